@@ -400,6 +400,20 @@ var txErrorsFailureReasonMap = map[error]TransactionFailureReason{
 	iotago.ErrTxCapabilitiesNFTDestructionNotAllowed:     TxFailureCapabilitiesNFTDestructionNotAllowed,
 }
 
+// ErrTransactionSemanticValidationFailed is the error which TxFailureSemanticValidationFailed maps to,
+// since it doesn't correspond to a specific error of the VM.
+var ErrTransactionSemanticValidationFailed = ierrors.New("transaction semantic validation failed")
+
+var txFailureReasonErrorsMap = func() map[TransactionFailureReason]error {
+	failureReasonErrorsMap := make(map[TransactionFailureReason]error, len(txErrorsFailureReasonMap)+1)
+	for err, txFailureReason := range txErrorsFailureReasonMap {
+		failureReasonErrorsMap[txFailureReason] = err
+	}
+	failureReasonErrorsMap[TxFailureSemanticValidationFailed] = ErrTransactionSemanticValidationFailed
+
+	return failureReasonErrorsMap
+}()
+
 func (t TransactionFailureReason) Bytes() ([]byte, error) {
 	return []byte{byte(t)}, nil
 }
@@ -458,6 +472,22 @@ func DetermineTransactionFailureReason(err error) TransactionFailureReason {
 	return TxFailureSemanticValidationFailed
 }
 
+// TransactionFailureReasonError returns the sentinel error the given TransactionFailureReason corresponds to,
+// so that failures reported by a node can be checked with ierrors.Is like the errors returned by the VM.
+// It returns nil for TxFailureNone and an error for unknown failure reasons.
+func TransactionFailureReasonError(txFailureReason TransactionFailureReason) error {
+	if txFailureReason == TxFailureNone {
+		return nil
+	}
+
+	err, exists := txFailureReasonErrorsMap[txFailureReason]
+	if !exists {
+		return ierrors.Errorf("unknown transaction failure reason: %d", txFailureReason)
+	}
+
+	return err
+}
+
 type (
 	// InfoResponse defines the response of a GET info REST API call.
 	InfoResponse struct {
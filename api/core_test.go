@@ -884,3 +884,22 @@ func TestTransactionFailureReasonDetermination(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionFailureReasonError(t *testing.T) {
+	require.NoError(t, api.TransactionFailureReasonError(api.TxFailureNone))
+
+	// every failure reason maps to an error that is mapped back to the same failure reason
+	for txFailureReason := api.TxFailureConflictRejected; txFailureReason <= api.TxFailureCapabilitiesNFTDestructionNotAllowed; txFailureReason++ {
+		err := api.TransactionFailureReasonError(txFailureReason)
+		require.Error(t, err, "failure reason %d", txFailureReason)
+		require.Equal(t, txFailureReason, api.DetermineTransactionFailureReason(ierrors.Wrap(err, "wrapped")), "failure reason %d", txFailureReason)
+	}
+
+	err := api.TransactionFailureReasonError(api.TxFailureSemanticValidationFailed)
+	require.ErrorIs(t, err, api.ErrTransactionSemanticValidationFailed)
+	require.Equal(t, api.TxFailureSemanticValidationFailed, api.DetermineTransactionFailureReason(err))
+
+	require.ErrorIs(t, api.TransactionFailureReasonError(api.TxFailureInputOutputBaseTokenMismatch), iotago.ErrInputOutputBaseTokenMismatch)
+
+	require.Error(t, api.TransactionFailureReasonError(api.TransactionFailureReason(200)))
+}
@@ -0,0 +1,350 @@
+package builder
+
+import (
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrBatchPayout gets returned if a payment of a batch payout is invalid.
+	ErrBatchPayout = ierrors.New("invalid batch payout")
+	// ErrBatchPayoutLimitExceeded gets returned if a transaction of a batch payout exceeds the size or work score of a block.
+	ErrBatchPayoutLimitExceeded = ierrors.New("batch payout transaction exceeds the block limits")
+)
+
+// Payment is a transfer to a single recipient of a batch payout.
+type Payment struct {
+	// The address of the recipient.
+	Address iotago.Address
+	// The base tokens the recipient receives.
+	// They need to cover the storage deposit of the output, unless the storage deposit is returned.
+	BaseTokens iotago.BaseToken
+	// The native tokens the recipient receives, or nil.
+	NativeToken *iotago.NativeTokenFeature
+	// Whether the storage deposit of the output is added on top of the base tokens
+	// and needs to be returned to the remainder address with a StorageDepositReturnUnlockCondition.
+	ReturnStorageDeposit bool
+	// The slot from which on the output can only be unlocked by the remainder address, or 0 if it doesn't expire.
+	ExpirationSlot iotago.SlotIndex
+}
+
+// output creates the BasicOutput of the payment, storage deposits and expired outputs are returned to the given address.
+func (p *Payment) output(storageScoreStructure *iotago.StorageScoreStructure, returnAddress iotago.Address) (*iotago.BasicOutput, error) {
+	output := &iotago.BasicOutput{
+		Amount: p.BaseTokens,
+		UnlockConditions: iotago.BasicOutputUnlockConditions{
+			&iotago.AddressUnlockCondition{Address: p.Address},
+		},
+		Features: iotago.BasicOutputFeatures{},
+	}
+
+	if p.NativeToken != nil {
+		output.Features.Upsert(p.NativeToken.Clone())
+	}
+
+	if p.ExpirationSlot != 0 {
+		output.UnlockConditions.Upsert(&iotago.ExpirationUnlockCondition{ReturnAddress: returnAddress, Slot: p.ExpirationSlot})
+	}
+
+	storageDepositReturn := &iotago.StorageDepositReturnUnlockCondition{ReturnAddress: returnAddress}
+	if p.ReturnStorageDeposit {
+		output.UnlockConditions.Upsert(storageDepositReturn)
+	}
+	output.UnlockConditions.Sort()
+
+	minDeposit, err := storageScoreStructure.MinDeposit(output)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to calculate the minimum storage deposit")
+	}
+
+	if p.ReturnStorageDeposit {
+		storageDepositReturn.Amount = minDeposit
+		if output.Amount, err = safemath.SafeAdd(output.Amount, minDeposit); err != nil {
+			return nil, ierrors.Wrap(err, "failed to add the storage deposit to the base tokens")
+		}
+
+		return output, nil
+	}
+
+	if output.Amount < minDeposit {
+		return nil, ierrors.WithMessagef(ErrBatchPayout, "%d base tokens don't cover the storage deposit of %d", output.Amount, minDeposit)
+	}
+
+	return output, nil
+}
+
+// PayoutBatch is a transaction of a batch payout.
+type PayoutBatch struct {
+	// The index of the first payment of the batch.
+	FirstPayment int
+	// The amount of payments in the batch, their outputs are the first outputs of the transaction in the same order.
+	PaymentCount int
+	// The signed transaction of the batch.
+	Transaction *iotago.SignedTransaction
+	// The remainder outputs of the transaction, which fund the next batch.
+	RemainderOutputs iotago.OutputSet
+	// The mana allotted to the block issuer to issue the transaction, zero if no block issuer is set.
+	ManaCost iotago.Mana
+}
+
+// NextPayment returns the index of the first payment after the batch.
+// If the issuance stops after this batch, the payout can be resumed with the payments from this index on,
+// funded by the remainder outputs of the batch.
+func (b *PayoutBatch) NextPayment() int {
+	return b.FirstPayment + b.PaymentCount
+}
+
+// NewBatchPayoutPlanner creates a new BatchPayoutPlanner that funds the payments with the given candidates,
+// which are unlockable by one of the given addresses. Remainders, returned storage deposits and expired payments
+// go to the remainder address.
+func NewBatchPayoutPlanner(api iotago.API, signer iotago.AddressSigner, candidates iotago.OutputSet, remainderAddress iotago.Address, addresses ...iotago.Address) *BatchPayoutPlanner {
+	return &BatchPayoutPlanner{
+		api:              api,
+		signer:           signer,
+		candidates:       candidates,
+		remainderAddress: remainderAddress,
+		addresses:        addresses,
+		strategy:         InputSelectionStrategyLargestFirst,
+		maxInputs:        iotago.MaxInputsCount,
+		// one output is left for the remainder
+		maxPayments: iotago.MaxOutputsCount - 1,
+	}
+}
+
+// BatchPayoutPlanner splits payments into a sequence of transactions that respect the protocol limits.
+// Every transaction is funded by the remainder of the previous one.
+type BatchPayoutPlanner struct {
+	api                  iotago.API
+	signer               iotago.AddressSigner
+	candidates           iotago.OutputSet
+	remainderAddress     iotago.Address
+	addresses            []iotago.Address
+	strategy             InputSelectionStrategy
+	maxInputs            int
+	maxPayments          int
+	blockIssuerAccountID iotago.AccountID
+	rmc                  iotago.Mana
+}
+
+// Strategy sets the strategy which is used to select the inputs of the transactions.
+func (p *BatchPayoutPlanner) Strategy(strategy InputSelectionStrategy) *BatchPayoutPlanner {
+	p.strategy = strategy
+
+	return p
+}
+
+// MaxInputs sets the maximum amount of inputs of a transaction.
+func (p *BatchPayoutPlanner) MaxInputs(maxInputs int) *BatchPayoutPlanner {
+	p.maxInputs = maxInputs
+
+	return p
+}
+
+// MaxPaymentsPerTransaction sets the maximum amount of payments of a transaction.
+func (p *BatchPayoutPlanner) MaxPaymentsPerTransaction(maxPayments int) *BatchPayoutPlanner {
+	p.maxPayments = maxPayments
+
+	return p
+}
+
+// BlockIssuer sets the account that issues the blocks of the transactions.
+// Every transaction allots the mana needed to issue its block at the given reference mana cost to the account.
+func (p *BatchPayoutPlanner) BlockIssuer(accountID iotago.AccountID, rmc iotago.Mana) *BatchPayoutPlanner {
+	p.blockIssuerAccountID = accountID
+	p.rmc = rmc
+
+	return p
+}
+
+// Plan builds the transactions of the payout at the given creation slot, which need to be issued in order.
+// A transaction holds as many payments as fit into the maximum inputs and outputs of a transaction and the size and work of a block.
+// If a batch can't be built, the batches built so far are returned together with the error,
+// so the payout can be resumed from the NextPayment of the last batch.
+func (p *BatchPayoutPlanner) Plan(creationSlot iotago.SlotIndex, payments []*Payment) ([]*PayoutBatch, error) {
+	outputs := make(iotago.TxEssenceOutputs, 0, len(payments))
+	for i, payment := range payments {
+		output, err := payment.output(p.api.StorageScoreStructure(), p.remainderAddress)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "payment %d is invalid", i)
+		}
+		outputs = append(outputs, output)
+	}
+
+	candidates := p.candidates.Clone()
+	batches := make([]*PayoutBatch, 0)
+
+	for nextPayment := 0; nextPayment < len(outputs); {
+		paymentCount := min(p.maxPayments, len(outputs)-nextPayment)
+
+		batch, err := p.buildLargestBatch(creationSlot, candidates, outputs[nextPayment:nextPayment+paymentCount])
+		if err != nil {
+			return batches, ierrors.Wrapf(err, "failed to build the batch starting at payment %d", nextPayment)
+		}
+		batch.FirstPayment = nextPayment
+		batches = append(batches, batch)
+
+		// the remainder of the batch funds the next batch
+		for _, input := range batch.Transaction.Transaction.Inputs() {
+			delete(candidates, input.OutputID())
+		}
+		for outputID, output := range batch.RemainderOutputs {
+			candidates[outputID] = output
+		}
+
+		nextPayment += batch.PaymentCount
+	}
+
+	return batches, nil
+}
+
+// buildLargestBatch builds the batch that pays out as many of the given outputs as fit into the limits of a transaction.
+// Since a transaction that exceeds the limits also exceeds them with more payments, the payment count is binary searched.
+func (p *BatchPayoutPlanner) buildLargestBatch(creationSlot iotago.SlotIndex, candidates iotago.OutputSet, outputs iotago.TxEssenceOutputs) (*PayoutBatch, error) {
+	batch, err := p.buildBatch(creationSlot, candidates, outputs)
+	if err == nil || !isBatchLimitError(err) {
+		return batch, err
+	}
+
+	// the batch with the most payments that fits and the smallest payment count that exceeds the limits
+	var fittingBatch *PayoutBatch
+	fittingCount, exceedingCount := 0, len(outputs)
+	for exceedingCount-fittingCount > 1 {
+		paymentCount := (fittingCount + exceedingCount) / 2
+
+		candidateBatch, candidateErr := p.buildBatch(creationSlot, candidates, outputs[:paymentCount])
+		switch {
+		case candidateErr == nil:
+			fittingBatch, fittingCount = candidateBatch, paymentCount
+		case isBatchLimitError(candidateErr):
+			err, exceedingCount = candidateErr, paymentCount
+		default:
+			return nil, candidateErr
+		}
+	}
+
+	if fittingBatch == nil {
+		return nil, err
+	}
+
+	return fittingBatch, nil
+}
+
+// isBatchLimitError returns whether the error was caused by a batch that exceeds the limits of a transaction.
+func isBatchLimitError(err error) bool {
+	return ierrors.Is(err, ErrInputSelectionMaxInputsExceeded) || ierrors.Is(err, ErrInputSelectionMaxOutputsExceeded) || ierrors.Is(err, ErrBatchPayoutLimitExceeded)
+}
+
+// buildBatch builds the transaction that pays out the given outputs.
+func (p *BatchPayoutPlanner) buildBatch(creationSlot iotago.SlotIndex, candidates iotago.OutputSet, outputs iotago.TxEssenceOutputs) (*PayoutBatch, error) {
+	selector := NewInputSelector(p.api, candidates, p.addresses...).
+		Strategy(p.strategy).
+		MaxInputs(p.maxInputs)
+
+	var (
+		txBuilder *TransactionBuilder
+		selection *InputSelection
+		manaCost  iotago.Mana
+	)
+
+	// the mana cost depends on the selected inputs, so the inputs are selected again until they cover the mana cost
+	for {
+		var err error
+		if selection, err = selector.AdditionalMana(manaCost).Select(creationSlot, outputs, p.remainderAddress); err != nil {
+			return nil, err
+		}
+
+		txBuilder = NewTransactionBuilder(p.api, p.signer).SetCreationSlot(creationSlot)
+		for _, output := range outputs {
+			txBuilder.AddOutput(output.Clone())
+		}
+		txBuilder.AddInputSelection(selection)
+
+		if p.blockIssuerAccountID.Empty() {
+			break
+		}
+
+		requiredMana, err := txBuilder.MinRequiredAllottedMana(p.rmc, p.blockIssuerAccountID)
+		if err != nil {
+			return nil, err
+		}
+
+		if requiredMana <= manaCost {
+			break
+		}
+		manaCost = requiredMana
+	}
+
+	// the mana is stored on the first remainder output, which follows the payments and the storage deposit returns
+	storedManaOutputIndex := len(outputs) + len(selection.StorageDepositReturnOutputs)
+
+	switch {
+	case len(selection.RemainderOutputs) == 0 && p.blockIssuerAccountID.Empty():
+		// the selection leaves no mana without a remainder output, only the account bound mana remains
+		txBuilder.AllotRemainingAccountBoundMana(creationSlot, nil)
+	case len(selection.RemainderOutputs) == 0:
+		// the leftover mana is allotted to the block issuer, so it doesn't end up on a payment or a storage deposit return
+		txBuilder.AllotRemainingAccountBoundMana(creationSlot, nil, p.blockIssuerAccountID)
+
+		requiredMana, err := txBuilder.MinRequiredAllottedMana(p.rmc, p.blockIssuerAccountID)
+		if err != nil {
+			return nil, err
+		}
+		txBuilder.AllotAllMana(creationSlot, p.blockIssuerAccountID, requiredMana)
+	case p.blockIssuerAccountID.Empty():
+		txBuilder.StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(creationSlot, storedManaOutputIndex)
+	default:
+		txBuilder.AllotMinRequiredManaAndStoreRemainingManaInOutput(creationSlot, p.rmc, p.blockIssuerAccountID, storedManaOutputIndex)
+	}
+
+	signedTx, err := txBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBlockLimits(p.api, signedTx, ErrBatchPayoutLimitExceeded); err != nil {
+		return nil, err
+	}
+
+	txID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to compute the transaction ID")
+	}
+
+	remainderOutputs := make(iotago.OutputSet, len(selection.RemainderOutputs))
+	for i := len(signedTx.Transaction.Outputs) - len(selection.RemainderOutputs); i < len(signedTx.Transaction.Outputs); i++ {
+		remainderOutputs[iotago.OutputIDFromTransactionIDAndIndex(txID, uint16(i))] = signedTx.Transaction.Outputs[i]
+	}
+
+	batch := &PayoutBatch{
+		PaymentCount:     len(outputs),
+		Transaction:      signedTx,
+		RemainderOutputs: remainderOutputs,
+	}
+
+	for _, allotment := range signedTx.Transaction.Allotments {
+		if allotment.AccountID == p.blockIssuerAccountID {
+			batch.ManaCost = allotment.Mana
+		}
+	}
+
+	return batch, nil
+}
+
+// checkBlockLimits checks that the signed transaction fits into the size and work score limits of a block.
+// The given error is returned with the exceeded limit as message otherwise.
+func checkBlockLimits(apiForSlot iotago.API, signedTx *iotago.SignedTransaction, limitErr error) error {
+	if size := signedTx.Size(); size > iotago.MaxPayloadSize {
+		return ierrors.WithMessagef(limitErr, "size %d > %d", size, iotago.MaxPayloadSize)
+	}
+
+	workScore, err := signedTx.WorkScore(apiForSlot.ProtocolParameters().WorkScoreParameters())
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate the work score of the transaction")
+	}
+	if maxBlockWork := apiForSlot.MaxBlockWork(); workScore > maxBlockWork {
+		return ierrors.WithMessagef(limitErr, "work score %d > %d", workScore, maxBlockWork)
+	}
+
+	return nil
+}
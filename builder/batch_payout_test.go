@@ -0,0 +1,234 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm/nova/ledger"
+)
+
+// batchPayoutLedger returns a ledger with the given funds of the owner and the signer of the owner.
+func batchPayoutLedger(t *testing.T, funds ...iotago.Output) (*ledger.Ledger, iotago.Address, iotago.AddressSigner) {
+	t.Helper()
+
+	l := newTestLedger(t)
+	for _, output := range funds {
+		switch output := output.(type) {
+		case *iotago.BasicOutput:
+			output.UnlockConditions = iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: l.owner}}
+		case *iotago.AccountOutput:
+			output.UnlockConditions = iotago.AccountOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: l.owner}}
+		}
+		l.AddGenesisOutput(output)
+	}
+	l.AdvanceSlots(l.API().ProtocolParameters().MaxCommittableAge())
+
+	return l.Ledger, l.owner, l.signer
+}
+
+// applyPayoutBatches applies the transactions of the batches in order and returns the created payment outputs.
+func applyPayoutBatches(t *testing.T, l *ledger.Ledger, batches []*builder.PayoutBatch) iotago.TxEssenceOutputs {
+	t.Helper()
+
+	var paymentOutputs iotago.TxEssenceOutputs
+	for i, batch := range batches {
+		if i > 0 {
+			require.Equal(t, batches[i-1].NextPayment(), batch.FirstPayment)
+		}
+		require.LessOrEqual(t, len(batch.Transaction.Transaction.Outputs), iotago.MaxOutputsCount)
+
+		createdOutputs, err := l.ApplyTransaction(batch.Transaction)
+		require.NoError(t, err)
+
+		for outputID, remainderOutput := range batch.RemainderOutputs {
+			require.Equal(t, remainderOutput, createdOutputs[outputID])
+		}
+
+		paymentOutputs = append(paymentOutputs, batch.Transaction.Transaction.Outputs[:batch.PaymentCount]...)
+	}
+
+	return paymentOutputs
+}
+
+func TestBatchPayoutPlanner(t *testing.T) {
+	nativeTokenID := tpkg.RandNativeTokenID()
+	l, owner, signer := batchPayoutLedger(t,
+		builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 1_000_000_000).MustBuild(),
+		builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 1_000_000).NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(1_000)}).MustBuild(),
+	)
+
+	payments := make([]*builder.Payment, 0, 300)
+	for range 297 {
+		payments = append(payments, &builder.Payment{Address: tpkg.RandEd25519Address(), BaseTokens: 1_000_000})
+	}
+	payments = append(payments,
+		&builder.Payment{Address: tpkg.RandEd25519Address(), NativeToken: &iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(600)}, ReturnStorageDeposit: true},
+		&builder.Payment{Address: tpkg.RandEd25519Address(), BaseTokens: 2_000_000, ExpirationSlot: l.CurrentSlot() + 100},
+		&builder.Payment{Address: tpkg.RandEd25519Address(), BaseTokens: 3_000_000},
+	)
+
+	batches, err := builder.NewBatchPayoutPlanner(l.API(), signer, l.UnspentOutputs(), owner, owner).Plan(l.CurrentSlot(), payments)
+	require.NoError(t, err)
+	require.Len(t, batches, 3)
+	require.Equal(t, len(payments), batches[2].NextPayment())
+
+	paymentOutputs := applyPayoutBatches(t, l, batches)
+	require.Len(t, paymentOutputs, len(payments))
+
+	for i, payment := range payments {
+		output := paymentOutputs[i].(*iotago.BasicOutput)
+		require.True(t, output.Owner().Equal(payment.Address))
+
+		unlockConditions := output.UnlockConditionSet()
+		switch {
+		case payment.ReturnStorageDeposit:
+			require.True(t, unlockConditions.StorageDepositReturn().ReturnAddress.Equal(owner))
+			require.Equal(t, output.Amount, unlockConditions.StorageDepositReturn().Amount)
+			require.Equal(t, payment.NativeToken.Amount, output.FeatureSet().NativeToken().Amount)
+		case payment.ExpirationSlot != 0:
+			require.True(t, unlockConditions.Expiration().ReturnAddress.Equal(owner))
+			require.Equal(t, payment.ExpirationSlot, unlockConditions.Expiration().Slot)
+			require.Equal(t, payment.BaseTokens, output.Amount)
+		default:
+			require.Equal(t, payment.BaseTokens, output.Amount)
+		}
+	}
+
+	// the remaining native tokens are sent back to the owner
+	var remainingNativeTokens *big.Int
+	for _, output := range l.UnspentOutputs() {
+		if nativeToken := output.FeatureSet().NativeToken(); nativeToken != nil && output.UnlockConditionSet().Address().Address.Equal(owner) {
+			remainingNativeTokens = nativeToken.Amount
+		}
+	}
+	require.Equal(t, big.NewInt(400), remainingNativeTokens)
+}
+
+func TestBatchPayoutPlannerLimits(t *testing.T) {
+	funds := make([]iotago.Output, 0, 100)
+	for range 100 {
+		funds = append(funds, builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 1_000_000).MustBuild())
+	}
+	l, owner, signer := batchPayoutLedger(t, funds...)
+
+	payments := make([]*builder.Payment, 0, 40)
+	for range 40 {
+		payments = append(payments, &builder.Payment{Address: tpkg.RandEd25519Address(), BaseTokens: 2_000_000})
+	}
+
+	// every payment needs two inputs and the remainder is below the storage deposit, so a transaction can only hold 4 payments
+	batches, err := builder.NewBatchPayoutPlanner(l.API(), signer, l.UnspentOutputs(), owner, owner).
+		MaxInputs(10).
+		Plan(l.CurrentSlot(), payments)
+	require.NoError(t, err)
+	require.Len(t, batches, 10)
+
+	for _, batch := range batches {
+		require.LessOrEqual(t, len(batch.Transaction.Transaction.Inputs()), 10)
+		require.Equal(t, 4, batch.PaymentCount)
+	}
+	require.Len(t, applyPayoutBatches(t, l, batches), len(payments))
+
+	t.Run("max payments per transaction", func(t *testing.T) {
+		l, owner, signer := batchPayoutLedger(t, builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 100_000_000).MustBuild())
+
+		batches, err := builder.NewBatchPayoutPlanner(l.API(), signer, l.UnspentOutputs(), owner, owner).
+			MaxPaymentsPerTransaction(7).
+			Plan(l.CurrentSlot(), payments[:20])
+		require.NoError(t, err)
+		require.Len(t, batches, 3)
+		require.Equal(t, 7, batches[0].PaymentCount)
+		require.Equal(t, 6, batches[2].PaymentCount)
+		require.Len(t, applyPayoutBatches(t, l, batches), 20)
+	})
+}
+
+func TestBatchPayoutPlannerManaCost(t *testing.T) {
+	accountID := tpkg.RandAccountID()
+	l, owner, signer := batchPayoutLedger(t,
+		builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 500_000_000).Mana(100_000_000).MustBuild(),
+		builder.NewAccountOutputBuilder(tpkg.RandEd25519Address(), 10_000_000).AccountID(accountID).MustBuild(),
+	)
+
+	payments := make([]*builder.Payment, 0, 200)
+	for range 200 {
+		payments = append(payments, &builder.Payment{Address: tpkg.RandEd25519Address(), BaseTokens: 1_000_000})
+	}
+
+	batches, err := builder.NewBatchPayoutPlanner(l.API(), signer, l.UnspentOutputs(), owner, owner).
+		BlockIssuer(accountID, 1).
+		Plan(l.CurrentSlot(), payments)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+
+	for _, batch := range batches {
+		require.NotZero(t, batch.ManaCost)
+		require.Len(t, batch.Transaction.Transaction.Allotments, 1)
+		require.Equal(t, accountID, batch.Transaction.Transaction.Allotments[0].AccountID)
+		require.Equal(t, batch.ManaCost, batch.Transaction.Transaction.Allotments[0].Mana)
+	}
+	// the bigger transaction costs more mana
+	require.Greater(t, batches[0].ManaCost, batches[1].ManaCost)
+
+	require.Len(t, applyPayoutBatches(t, l, batches), len(payments))
+}
+
+func TestBatchPayoutPlannerWithoutRemainder(t *testing.T) {
+	// the funds hold exactly the base tokens of the payments and generate no mana in the creation slot
+	l := newTestLedger(t)
+	l.AddGenesisOutput(builder.NewBasicOutputBuilder(l.owner, 3_000_000).MustBuild())
+
+	payments := []*builder.Payment{
+		{Address: tpkg.RandEd25519Address(), BaseTokens: 1_000_000},
+		{Address: tpkg.RandEd25519Address(), BaseTokens: 2_000_000},
+	}
+
+	batches, err := builder.NewBatchPayoutPlanner(l.API(), l.signer, l.UnspentOutputs(), l.owner, l.owner).Plan(l.CurrentSlot(), payments)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Empty(t, batches[0].RemainderOutputs)
+	require.Empty(t, batches[0].Transaction.Transaction.Allotments)
+
+	// no mana is stored on the payments
+	paymentOutputs := applyPayoutBatches(t, l.Ledger, batches)
+	require.Len(t, paymentOutputs, len(payments))
+	for _, output := range paymentOutputs {
+		require.Zero(t, output.StoredMana())
+	}
+	require.Len(t, l.UnspentOutputs(), len(payments))
+}
+
+func TestBatchPayoutPlannerResume(t *testing.T) {
+	l, owner, signer := batchPayoutLedger(t, builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 200_000_000).MustBuild())
+
+	payments := make([]*builder.Payment, 0, 300)
+	for range 300 {
+		payments = append(payments, &builder.Payment{Address: tpkg.RandEd25519Address(), BaseTokens: 1_000_000})
+	}
+
+	// the funds only cover the first batch
+	batches, err := builder.NewBatchPayoutPlanner(l.API(), signer, l.UnspentOutputs(), owner, owner).Plan(l.CurrentSlot(), payments)
+	require.ErrorIs(t, err, builder.ErrInputSelectionInsufficientBaseTokens)
+	require.Len(t, batches, 1)
+	require.Len(t, applyPayoutBatches(t, l, batches), batches[0].PaymentCount)
+
+	// the payout is resumed with new funds once they arrived
+	l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 500_000_000).MustBuild())
+	l.AdvanceSlots(1)
+
+	resumedBatches, err := builder.NewBatchPayoutPlanner(l.API(), signer, l.UnspentOutputs(), owner, owner).Plan(l.CurrentSlot(), payments[batches[0].NextPayment():])
+	require.NoError(t, err)
+	require.Len(t, applyPayoutBatches(t, l, resumedBatches), len(payments)-batches[0].PaymentCount)
+
+	t.Run("err - payment below the storage deposit", func(t *testing.T) {
+		_, err := builder.NewBatchPayoutPlanner(l.API(), signer, l.UnspentOutputs(), owner, owner).
+			Plan(l.CurrentSlot(), []*builder.Payment{{Address: tpkg.RandEd25519Address(), BaseTokens: 1}})
+		require.ErrorIs(t, err, builder.ErrBatchPayout)
+	})
+}
@@ -0,0 +1,381 @@
+package builder
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrConsolidation gets returned if outputs can't be consolidated.
+	ErrConsolidation = ierrors.New("invalid consolidation")
+	// ErrConsolidationLimitExceeded gets returned if a consolidation transaction exceeds the size or work score of a block.
+	ErrConsolidationLimitExceeded = ierrors.New("consolidation transaction exceeds the block limits")
+)
+
+// Consolidation is a transaction that merges outputs of the same addresses.
+type Consolidation struct {
+	// The signed transaction of the consolidation.
+	Transaction *iotago.SignedTransaction
+	// The outputs the inputs are merged into, one per address and native token.
+	ConsolidatedOutputs iotago.OutputSet
+	// The mana allotted to the block issuer to issue the transaction, zero if no block issuer is set.
+	ManaCost iotago.Mana
+}
+
+// NewConsolidationPlanner creates a new ConsolidationPlanner that consolidates the given candidates,
+// which are unlockable by one of the given addresses.
+// Only BasicOutputs are considered as candidates.
+func NewConsolidationPlanner(api iotago.API, signer iotago.AddressSigner, candidates iotago.OutputSet, addresses ...iotago.Address) *ConsolidationPlanner {
+	return &ConsolidationPlanner{
+		api:           api,
+		signer:        signer,
+		candidates:    candidates,
+		addresses:     addresses,
+		maxInputs:     iotago.MaxInputsCount,
+		dustThreshold: iotago.MaxBaseToken,
+	}
+}
+
+// ConsolidationPlanner merges the spendable outputs of every address into as few outputs as possible,
+// using the minimal amount of transactions. Outputs are grouped by the address that unlocks them,
+// timelocked outputs are skipped and storage deposits are returned where needed.
+// Expired outputs that return to one of the addresses are claimed and merged into the outputs of that address.
+// The mana of the inputs of an address is stored on its first consolidated output.
+type ConsolidationPlanner struct {
+	api                  iotago.API
+	signer               iotago.AddressSigner
+	candidates           iotago.OutputSet
+	addresses            []iotago.Address
+	maxInputs            int
+	dustThreshold        iotago.BaseToken
+	blockIssuerAccountID iotago.AccountID
+	rmc                  iotago.Mana
+}
+
+// MaxInputs sets the maximum amount of inputs of a transaction.
+func (p *ConsolidationPlanner) MaxInputs(maxInputs int) *ConsolidationPlanner {
+	p.maxInputs = maxInputs
+
+	return p
+}
+
+// DustThreshold sets the maximum base tokens an output may hold to be consolidated, so only dust is swept.
+// Expired outputs that are claimed are consolidated regardless of their base tokens.
+func (p *ConsolidationPlanner) DustThreshold(baseTokens iotago.BaseToken) *ConsolidationPlanner {
+	p.dustThreshold = baseTokens
+
+	return p
+}
+
+// BlockIssuer sets the account that issues the blocks of the transactions.
+// Every transaction allots the mana needed to issue its block at the given reference mana cost to the account,
+// which is paid by the addresses of the transaction in proportion to the mana of their inputs.
+func (p *ConsolidationPlanner) BlockIssuer(accountID iotago.AccountID, rmc iotago.Mana) *ConsolidationPlanner {
+	p.blockIssuerAccountID = accountID
+	p.rmc = rmc
+
+	return p
+}
+
+// Plan builds the consolidation transactions at the given creation slot. Timelocks and expirations are checked
+// against the given commitment, which is referenced by the transactions that need it.
+// The transactions spend distinct inputs, so they can be issued in any order.
+// Transactions that exceed the size or work score of a block are split until they fit.
+// If a transaction can't be built, the transactions built so far are returned together with the error.
+func (p *ConsolidationPlanner) Plan(creationSlot iotago.SlotIndex, commitmentID iotago.CommitmentID) ([]*Consolidation, error) {
+	selector := NewInputSelector(p.api, p.candidates, p.addresses...).CommitmentSlot(commitmentID.Slot())
+
+	candidates, err := selector.unlockableCandidates(creationSlot)
+	if err != nil {
+		return nil, err
+	}
+
+	// group the candidates by the address that unlocks them, the smallest outputs come first
+	groups := make(map[string]*consolidationPart)
+	addressKeys := make([]string, 0)
+	for _, candidate := range InputSelectionStrategyConsolidateDust(candidates, nil) {
+		if candidate.AvailableBaseTokens > p.dustThreshold && !isClaimedCandidate(candidate) {
+			continue
+		}
+
+		address := ResolveUnderlyingAddress(candidate.UnlockTarget)
+		group, exists := groups[address.Key()]
+		if !exists {
+			group = &consolidationPart{address: address}
+			groups[address.Key()] = group
+			addressKeys = append(addressKeys, address.Key())
+		}
+		group.candidates = append(group.candidates, candidate)
+	}
+	sort.Strings(addressKeys)
+
+	// fill every transaction up to the limits, a transaction holds the parts of several addresses if they fit
+	transactionsParts := make([][]*consolidationPart, 0)
+	var transactionParts []*consolidationPart
+	var inputsCount int
+	for _, addressKey := range addressKeys {
+		group := groups[addressKey]
+
+		var part *consolidationPart
+		for _, candidate := range group.candidates {
+			if part == nil {
+				part = &consolidationPart{address: group.address}
+				transactionParts = append(transactionParts, part)
+			}
+			part.candidates = append(part.candidates, candidate)
+
+			if inputsCount > 0 && (inputsCount >= p.maxInputs || consolidationOutputsCount(transactionParts) > iotago.MaxOutputsCount) {
+				// the candidate doesn't fit into the transaction anymore, so it opens a new one
+				part.candidates = part.candidates[:len(part.candidates)-1]
+				if len(part.candidates) == 0 {
+					transactionParts = transactionParts[:len(transactionParts)-1]
+				}
+				transactionsParts = append(transactionsParts, transactionParts)
+
+				part = &consolidationPart{address: group.address, candidates: []*InputCandidate{candidate}}
+				transactionParts, inputsCount = []*consolidationPart{part}, 0
+			}
+			inputsCount++
+		}
+	}
+	if inputsCount > 0 {
+		transactionsParts = append(transactionsParts, transactionParts)
+	}
+
+	consolidations := make([]*Consolidation, 0, len(transactionsParts))
+	for _, parts := range transactionsParts {
+		partsConsolidations, err := p.buildConsolidations(selector, creationSlot, commitmentID, parts)
+		consolidations = append(consolidations, partsConsolidations...)
+		if err != nil {
+			return consolidations, ierrors.Wrapf(err, "failed to build consolidation transaction %d", len(consolidations))
+		}
+	}
+
+	return consolidations, nil
+}
+
+// buildConsolidations builds the transactions that merge the candidates of the given parts.
+// If the transaction exceeds the limits of a block, the candidates are split into two transactions.
+func (p *ConsolidationPlanner) buildConsolidations(selector *InputSelector, creationSlot iotago.SlotIndex, commitmentID iotago.CommitmentID, parts []*consolidationPart) ([]*Consolidation, error) {
+	// a single output is only worth a transaction if it is claimed
+	worthwhileParts := make([]*consolidationPart, 0, len(parts))
+	for _, part := range parts {
+		if len(part.candidates) > 1 || isClaimedCandidate(part.candidates[0]) {
+			worthwhileParts = append(worthwhileParts, part)
+		}
+	}
+
+	if len(worthwhileParts) == 0 {
+		return nil, nil
+	}
+
+	consolidation, err := p.buildConsolidation(selector, creationSlot, commitmentID, worthwhileParts)
+	if err == nil {
+		return []*Consolidation{consolidation}, nil
+	}
+
+	firstParts, secondParts := splitConsolidationParts(worthwhileParts)
+	if !ierrors.Is(err, ErrConsolidationLimitExceeded) || len(secondParts) == 0 {
+		return nil, err
+	}
+
+	consolidations, err := p.buildConsolidations(selector, creationSlot, commitmentID, firstParts)
+	if err != nil {
+		return consolidations, err
+	}
+
+	secondConsolidations, err := p.buildConsolidations(selector, creationSlot, commitmentID, secondParts)
+
+	return append(consolidations, secondConsolidations...), err
+}
+
+// buildConsolidation builds the transaction that merges the candidates of every part into outputs of the address of the part.
+func (p *ConsolidationPlanner) buildConsolidation(selector *InputSelector, creationSlot iotago.SlotIndex, commitmentID iotago.CommitmentID, parts []*consolidationPart) (*Consolidation, error) {
+	selection := newInputSelectionState()
+	consolidatedOutputs := make([]*iotago.BasicOutput, 0, len(parts))
+	// the first output of every part stores the mana of the inputs of the part
+	manaOutputs := make([]*iotago.BasicOutput, 0, len(parts))
+	var needsCommitmentInput bool
+
+	for _, part := range parts {
+		partSelection := newInputSelectionState()
+		for _, candidate := range part.candidates {
+			if err := partSelection.add(candidate); err != nil {
+				return nil, err
+			}
+			if err := selection.add(candidate); err != nil {
+				return nil, err
+			}
+
+			unlockConditions := candidate.Output.UnlockConditionSet()
+			needsCommitmentInput = needsCommitmentInput || unlockConditions.Timelock() != nil || unlockConditions.Expiration() != nil
+		}
+
+		outputs, missingBaseTokens, err := newRemainderOutputs(p.api.StorageScoreStructure(), part.address, partSelection.baseTokens, partSelection.mana, partSelection.nativeTokens)
+		if err != nil {
+			return nil, err
+		}
+		if missingBaseTokens > 0 || len(outputs) == 0 {
+			return nil, ierrors.WithMessagef(ErrConsolidation, "outputs of address %s don't cover the storage deposit of the consolidated outputs after returning their storage deposits", part.address.Bech32(p.api.ProtocolParameters().Bech32HRP()))
+		}
+		consolidatedOutputs = append(consolidatedOutputs, outputs...)
+		manaOutputs = append(manaOutputs, outputs[0])
+	}
+
+	inputSelection, err := selector.inputSelection(selection, nil, consolidatedOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	txBuilder := NewTransactionBuilder(p.api, p.signer).
+		SetCreationSlot(creationSlot).
+		AddInputSelection(inputSelection)
+
+	if needsCommitmentInput {
+		if err := txBuilder.setCommitmentInput(commitmentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.blockIssuerAccountID.Empty() {
+		if err := p.allotManaCost(txBuilder, manaOutputs); err != nil {
+			return nil, err
+		}
+	}
+
+	signedTx, err := txBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBlockLimits(p.api, signedTx, ErrConsolidationLimitExceeded); err != nil {
+		return nil, err
+	}
+
+	txID, err := signedTx.Transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to compute the transaction ID")
+	}
+
+	consolidation := &Consolidation{
+		Transaction:         signedTx,
+		ConsolidatedOutputs: make(iotago.OutputSet, len(consolidatedOutputs)),
+	}
+
+	// the consolidated outputs follow the storage deposit returns
+	for i := len(inputSelection.StorageDepositReturnOutputs); i < len(signedTx.Transaction.Outputs); i++ {
+		consolidation.ConsolidatedOutputs[iotago.OutputIDFromTransactionIDAndIndex(txID, uint16(i))] = signedTx.Transaction.Outputs[i]
+	}
+
+	for _, allotment := range signedTx.Transaction.Allotments {
+		if allotment.AccountID == p.blockIssuerAccountID {
+			consolidation.ManaCost = allotment.Mana
+		}
+	}
+
+	return consolidation, nil
+}
+
+// allotManaCost allots the mana needed to issue the transaction to the block issuer.
+// Every part pays a share of the mana cost proportional to the mana of its inputs, which is taken from its output.
+func (p *ConsolidationPlanner) allotManaCost(txBuilder *TransactionBuilder, manaOutputs []*iotago.BasicOutput) error {
+	manaCost, err := txBuilder.MinRequiredAllottedMana(p.rmc, p.blockIssuerAccountID)
+	if err != nil {
+		return err
+	}
+
+	totalMana := new(big.Int)
+	for _, output := range manaOutputs {
+		totalMana.Add(totalMana, new(big.Int).SetUint64(uint64(output.Mana)))
+	}
+	if totalMana.Cmp(new(big.Int).SetUint64(uint64(manaCost))) < 0 {
+		return ierrors.WithMessagef(ErrConsolidation, "mana of the inputs doesn't cover the mana cost of the transaction: %s < %d", totalMana, manaCost)
+	}
+
+	// the shares are the differences of the rounded down costs of the cumulative mana of the parts,
+	// so they add up to the mana cost and none of them exceeds the mana of its part
+	if manaCost > 0 {
+		cumulativeMana, paidManaCost := new(big.Int), new(big.Int)
+		for _, output := range manaOutputs {
+			cumulativeMana.Add(cumulativeMana, new(big.Int).SetUint64(uint64(output.Mana)))
+
+			cumulativeManaCost := new(big.Int).Mul(cumulativeMana, new(big.Int).SetUint64(uint64(manaCost)))
+			cumulativeManaCost.Div(cumulativeManaCost, totalMana)
+
+			output.Mana -= iotago.Mana(new(big.Int).Sub(cumulativeManaCost, paidManaCost).Uint64())
+			paidManaCost = cumulativeManaCost
+		}
+	}
+
+	txBuilder.IncreaseAllotment(p.blockIssuerAccountID, manaCost)
+
+	return nil
+}
+
+// consolidationPart holds the candidates of a transaction that are merged into the outputs of an address.
+type consolidationPart struct {
+	address    iotago.Address
+	candidates []*InputCandidate
+}
+
+// outputsCount returns the amount of outputs the part creates at most,
+// one per native token or a single one without native tokens, plus one per storage deposit that needs to be returned.
+func (p *consolidationPart) outputsCount() int {
+	nativeTokenIDs := make(map[iotago.NativeTokenID]struct{})
+	var storageDepositReturns int
+
+	for _, candidate := range p.candidates {
+		if nativeTokenFeature := candidate.Output.FeatureSet().NativeToken(); nativeTokenFeature != nil {
+			nativeTokenIDs[nativeTokenFeature.ID] = struct{}{}
+		}
+		if candidate.StorageDepositReturn != nil {
+			storageDepositReturns++
+		}
+	}
+
+	return max(1, len(nativeTokenIDs)) + storageDepositReturns
+}
+
+// splitConsolidationParts splits the candidates of the parts into two halves, the parts keep their order.
+// The second half is empty if the parts hold a single candidate.
+func splitConsolidationParts(parts []*consolidationPart) ([]*consolidationPart, []*consolidationPart) {
+	var candidatesCount int
+	for _, part := range parts {
+		candidatesCount += len(part.candidates)
+	}
+
+	firstParts, secondParts := make([]*consolidationPart, 0, len(parts)), make([]*consolidationPart, 0, len(parts))
+	remaining := (candidatesCount + 1) / 2
+	for _, part := range parts {
+		switch {
+		case remaining >= len(part.candidates):
+			firstParts = append(firstParts, part)
+		case remaining > 0:
+			firstParts = append(firstParts, &consolidationPart{address: part.address, candidates: part.candidates[:remaining]})
+			secondParts = append(secondParts, &consolidationPart{address: part.address, candidates: part.candidates[remaining:]})
+		default:
+			secondParts = append(secondParts, part)
+		}
+		remaining = max(0, remaining-len(part.candidates))
+	}
+
+	return firstParts, secondParts
+}
+
+// consolidationOutputsCount returns the amount of outputs the parts of a transaction create at most.
+func consolidationOutputsCount(parts []*consolidationPart) int {
+	var outputsCount int
+	for _, part := range parts {
+		outputsCount += part.outputsCount()
+	}
+
+	return outputsCount
+}
+
+// isClaimedCandidate tells whether the candidate is unlocked by the return address of its expiration unlock condition.
+func isClaimedCandidate(candidate *InputCandidate) bool {
+	return !ResolveUnderlyingAddress(candidate.UnlockTarget).Equal(ResolveUnderlyingAddress(candidate.Output.Owner()))
+}
@@ -0,0 +1,231 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+// ownedOutputs returns the unspent outputs of the ledger that are owned by the given address.
+func (l *testLedger) ownedOutputs(owner iotago.Address) iotago.OutputSet {
+	outputs := make(iotago.OutputSet)
+	for outputID, output := range l.UnspentOutputs() {
+		if output.UnlockConditionSet().Address().Address.Equal(owner) {
+			outputs[outputID] = output
+		}
+	}
+
+	return outputs
+}
+
+func TestConsolidationPlanner(t *testing.T) {
+	l := newTestLedger(t)
+	owner, secondOwner := l.owner, l.addOwner()
+	other := tpkg.RandEd25519Address()
+	nativeTokenID := tpkg.RandNativeTokenID()
+
+	timelockSlot := l.API().ProtocolParameters().MaxCommittableAge() + 1_000
+
+	for range 150 {
+		l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 1_000_000).Mana(1_000).MustBuild())
+	}
+	for range 5 {
+		l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 1_000_000).NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(10)}).MustBuild())
+	}
+	for range 3 {
+		l.AddGenesisOutput(builder.NewBasicOutputBuilder(secondOwner, 2_000_000).MustBuild())
+	}
+
+	// the storage deposit is returned to the other address
+	l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 3_000_000).StorageDepositReturn(other, 1_000_000).MustBuild())
+	// the expired output of the other address is claimed without returning the storage deposit
+	claimedOutputID := l.AddGenesisOutput(builder.NewBasicOutputBuilder(other, 2_000_000).StorageDepositReturn(owner, 1_000_000).Expiration(owner, 1).MustBuild())
+	// the timelocked output can't be consolidated yet
+	timelockedOutputID := l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 1_000_000).Timelock(timelockSlot).MustBuild())
+	// a single output of an address is left alone
+	singleOutputID := l.AddGenesisOutput(builder.NewBasicOutputBuilder(tpkg.RandEd25519Address(), 1_000_000).MustBuild())
+
+	l.AdvanceSlots(l.API().ProtocolParameters().MaxCommittableAge())
+
+	consolidations, err := builder.NewConsolidationPlanner(l.API(), l.signer, l.UnspentOutputs(), owner, secondOwner).
+		Plan(l.CurrentSlot(), l.LatestCommitment().MustID())
+	require.NoError(t, err)
+
+	// 160 inputs fit into two transactions
+	require.Len(t, consolidations, 2)
+
+	var inputsCount int
+	spentOutputIDs := make(map[iotago.OutputID]struct{})
+	for _, consolidation := range consolidations {
+		require.LessOrEqual(t, len(consolidation.Transaction.Transaction.Inputs()), iotago.MaxInputsCount)
+		require.Zero(t, consolidation.ManaCost)
+		inputsCount += len(consolidation.Transaction.Transaction.Inputs())
+
+		for _, input := range consolidation.Transaction.Transaction.Inputs() {
+			spentOutputIDs[input.OutputID()] = struct{}{}
+		}
+
+		createdOutputs, err := l.ApplyTransaction(consolidation.Transaction)
+		require.NoError(t, err)
+
+		for outputID, consolidatedOutput := range consolidation.ConsolidatedOutputs {
+			require.Equal(t, consolidatedOutput, createdOutputs[outputID])
+		}
+	}
+	require.Equal(t, 160, inputsCount)
+	require.Contains(t, spentOutputIDs, claimedOutputID)
+	require.NotContains(t, spentOutputIDs, timelockedOutputID)
+	require.NotContains(t, spentOutputIDs, singleOutputID)
+
+	// the storage deposit was returned
+	returnOutputs := l.ownedOutputs(other)
+	require.Len(t, returnOutputs, 1)
+	for _, returnOutput := range returnOutputs {
+		require.EqualValues(t, 1_000_000, returnOutput.BaseTokenAmount())
+	}
+
+	// the second address holds a single output with all base tokens and the mana of its inputs
+	secondOwnerOutputs := l.ownedOutputs(secondOwner)
+	require.Len(t, secondOwnerOutputs, 1)
+	for _, output := range secondOwnerOutputs {
+		require.EqualValues(t, 6_000_000, output.BaseTokenAmount())
+		require.NotZero(t, output.StoredMana())
+	}
+
+	// the owner holds the timelocked output, the merged native tokens and the remaining base tokens and mana
+	var (
+		baseTokens   iotago.BaseToken
+		mana         iotago.Mana
+		nativeTokens = big.NewInt(0)
+	)
+	ownerOutputs := l.ownedOutputs(owner)
+	for _, output := range ownerOutputs {
+		baseTokens += output.BaseTokenAmount()
+		mana += output.StoredMana()
+		if nativeToken := output.FeatureSet().NativeToken(); nativeToken != nil {
+			nativeTokens.Add(nativeTokens, nativeToken.Amount)
+		}
+	}
+	require.LessOrEqual(t, len(ownerOutputs), 5)
+	require.EqualValues(t, 150_000_000+5_000_000+2_000_000+2_000_000+1_000_000, baseTokens)
+	require.Greater(t, mana, iotago.Mana(0))
+	require.Equal(t, big.NewInt(50), nativeTokens)
+
+	t.Run("dust threshold", func(t *testing.T) {
+		l := newTestLedger(t)
+		owner := l.owner
+		for range 10 {
+			l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 1_000_000).MustBuild())
+		}
+		largeOutputID := l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 100_000_000).MustBuild())
+		l.AdvanceSlots(l.API().ProtocolParameters().MaxCommittableAge())
+
+		consolidations, err := builder.NewConsolidationPlanner(l.API(), l.signer, l.UnspentOutputs(), owner).
+			DustThreshold(1_000_000).
+			MaxInputs(4).
+			Plan(l.CurrentSlot(), l.LatestCommitment().MustID())
+		require.NoError(t, err)
+		require.Len(t, consolidations, 3)
+
+		for _, consolidation := range consolidations {
+			for _, input := range consolidation.Transaction.Transaction.Inputs() {
+				require.NotEqual(t, largeOutputID, input.OutputID())
+			}
+
+			_, err := l.ApplyTransaction(consolidation.Transaction)
+			require.NoError(t, err)
+		}
+		require.Len(t, l.ownedOutputs(owner), 4)
+	})
+
+	t.Run("block issuer", func(t *testing.T) {
+		accountID := tpkg.RandAccountID()
+		l := newTestLedger(t)
+		owner, secondOwner := l.owner, l.addOwner()
+		for range 20 {
+			l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 1_000_000).Mana(1_000_000).MustBuild())
+		}
+		for range 5 {
+			l.AddGenesisOutput(builder.NewBasicOutputBuilder(secondOwner, 1_000_000).Mana(1_000_000).MustBuild())
+		}
+		l.AddGenesisOutput(builder.NewAccountOutputBuilder(owner, 10_000_000).AccountID(accountID).MustBuild())
+		l.AdvanceSlots(l.API().ProtocolParameters().MaxCommittableAge())
+
+		consolidations, err := builder.NewConsolidationPlanner(l.API(), l.signer, l.UnspentOutputs(), owner, secondOwner).
+			BlockIssuer(accountID, 1).
+			Plan(l.CurrentSlot(), l.LatestCommitment().MustID())
+		require.NoError(t, err)
+		require.Len(t, consolidations, 1)
+		require.NotZero(t, consolidations[0].ManaCost)
+		require.Equal(t, accountID, consolidations[0].Transaction.Transaction.Allotments[0].AccountID)
+
+		_, err = l.ApplyTransaction(consolidations[0].Transaction)
+		require.NoError(t, err)
+
+		// every address keeps the mana of its inputs after paying its share of the mana cost
+		var ownerMana, secondOwnerMana iotago.Mana
+		for _, output := range l.ownedOutputs(owner) {
+			ownerMana += output.StoredMana()
+		}
+		for _, output := range l.ownedOutputs(secondOwner) {
+			secondOwnerMana += output.StoredMana()
+		}
+		require.Greater(t, secondOwnerMana, iotago.Mana(4_000_000))
+		require.Greater(t, ownerMana, 3*secondOwnerMana)
+	})
+
+	t.Run("split at the block limits", func(t *testing.T) {
+		l := newTestLedger(t)
+
+		// the signatures of the multi addresses don't fit into the size of a single block
+		multiAddresses := make([]iotago.Address, 0, 40)
+		for range 40 {
+			multiAddress := &iotago.MultiAddress{Threshold: 10}
+			for range 10 {
+				prvKey, member, _ := tpkg.RandEd25519Identity()
+				l.prvKeys = append(l.prvKeys, prvKey)
+				multiAddress.Addresses = append(multiAddress.Addresses, &iotago.AddressWithWeight{Address: member, Weight: 1})
+			}
+			multiAddress.Addresses.Sort()
+			multiAddresses = append(multiAddresses, multiAddress)
+
+			for range 2 {
+				l.AddGenesisOutput(builder.NewBasicOutputBuilder(multiAddress, 1_000_000).MustBuild())
+			}
+		}
+		l.signer = iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(l.prvKeys...)
+		l.AdvanceSlots(l.API().ProtocolParameters().MaxCommittableAge())
+
+		consolidations, err := builder.NewConsolidationPlanner(l.API(), l.signer, l.UnspentOutputs(), multiAddresses...).
+			Plan(l.CurrentSlot(), l.LatestCommitment().MustID())
+		require.NoError(t, err)
+		require.Greater(t, len(consolidations), 1)
+
+		for _, consolidation := range consolidations {
+			_, err := l.ApplyTransaction(consolidation.Transaction)
+			require.NoError(t, err)
+		}
+		for _, multiAddress := range multiAddresses {
+			require.Len(t, l.ownedOutputs(multiAddress), 1)
+		}
+	})
+
+	t.Run("err - storage deposit return isn't covered", func(t *testing.T) {
+		l := newTestLedger(t)
+		owner := l.owner
+		for range 2 {
+			l.AddGenesisOutput(builder.NewBasicOutputBuilder(owner, 1_000_000).StorageDepositReturn(other, 1_000_000).MustBuild())
+		}
+		l.AdvanceSlots(l.API().ProtocolParameters().MaxCommittableAge())
+
+		_, err := builder.NewConsolidationPlanner(l.API(), l.signer, l.UnspentOutputs(), owner).
+			Plan(l.CurrentSlot(), l.LatestCommitment().MustID())
+		require.ErrorIs(t, err, builder.ErrConsolidation)
+	})
+}
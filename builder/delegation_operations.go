@@ -0,0 +1,147 @@
+package builder
+
+import (
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+)
+
+// ErrDelegationOperation gets returned if a delegation operation can't be applied to the transaction.
+var ErrDelegationOperation = ierrors.New("invalid delegation operation")
+
+// DelegationStartEpoch returns the start epoch a DelegationOutput needs to have
+// if it is created in a transaction that references the commitment of the given slot.
+// The delegation counts for the epoch after the past bounded slot if the registration for that epoch is still open,
+// otherwise it counts for the epoch after.
+func DelegationStartEpoch(apiForSlot iotago.API, commitmentSlot iotago.SlotIndex) iotago.EpochIndex {
+	pastBoundedSlot := commitmentSlot + apiForSlot.ProtocolParameters().MaxCommittableAge()
+	pastBoundedEpoch := apiForSlot.TimeProvider().EpochFromSlot(pastBoundedSlot)
+
+	if pastBoundedSlot <= delegationRegistrationSlot(apiForSlot, pastBoundedEpoch) {
+		return pastBoundedEpoch + 1
+	}
+
+	return pastBoundedEpoch + 2
+}
+
+// DelegationEndEpoch returns the end epoch a DelegationOutput needs to have
+// if it is transitioned to delayed claiming in a transaction that references the commitment of the given slot.
+// The delegation ends with the epoch of the future bounded slot if the registration for the next epoch is still open,
+// otherwise it also counts for the next epoch.
+func DelegationEndEpoch(apiForSlot iotago.API, commitmentSlot iotago.SlotIndex) iotago.EpochIndex {
+	futureBoundedSlot := commitmentSlot + apiForSlot.ProtocolParameters().MinCommittableAge()
+	futureBoundedEpoch := apiForSlot.TimeProvider().EpochFromSlot(futureBoundedSlot)
+
+	if futureBoundedSlot <= delegationRegistrationSlot(apiForSlot, futureBoundedEpoch) {
+		return futureBoundedEpoch
+	}
+
+	return futureBoundedEpoch + 1
+}
+
+// delegationRegistrationSlot returns the last slot of the given epoch in which delegations are registered for the next epoch.
+func delegationRegistrationSlot(apiForSlot iotago.API, epoch iotago.EpochIndex) iotago.SlotIndex {
+	return apiForSlot.TimeProvider().EpochEnd(epoch) - apiForSlot.ProtocolParameters().EpochNearingThreshold()
+}
+
+// CreateDelegation creates a new DelegationOutput that delegates the given amount of base tokens to the validator
+// and is owned by the given address. The start epoch is derived from the slot of the given commitment,
+// which is added as commitment input. The base tokens need to be provided by the inputs of the transaction.
+func (b *TransactionBuilder) CreateDelegation(validatorAddress *iotago.AccountAddress, ownerAddress iotago.Address, amount iotago.BaseToken, commitmentID iotago.CommitmentID) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	if err := b.setCommitmentInput(commitmentID); err != nil {
+		return b.setBuildError(ierrors.Chain(ErrDelegationOperation, err))
+	}
+
+	delegationOutput, err := NewDelegationOutputBuilder(validatorAddress, ownerAddress, amount).
+		DelegatedAmount(amount).
+		StartEpoch(DelegationStartEpoch(b.api, commitmentID.Slot())).
+		Build()
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	if _, err := b.api.StorageScoreStructure().CoversMinDeposit(delegationOutput, amount); err != nil {
+		return b.setBuildError(ierrors.Wrap(err, "the delegated amount doesn't cover the storage deposit of the delegation output"))
+	}
+
+	return b.AddOutput(delegationOutput)
+}
+
+// DelayDelegationClaiming transitions the DelegationOutput to delayed claiming, so the delegation stops
+// and its rewards can be claimed once the end epoch is over. The end epoch is derived from the slot of the given commitment,
+// which is added as commitment input. A delegation can only be transitioned to delayed claiming once.
+// The delegation input is added if it isn't part of the transaction yet.
+func (b *TransactionBuilder) DelayDelegationClaiming(delegation *TxInput, commitmentID iotago.CommitmentID) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	delegationInput, isDelegation := delegation.Input.(*iotago.DelegationOutput)
+	if !isDelegation {
+		return b.setBuildError(ierrors.WithMessagef(ErrDelegationOperation, "input %s is not a delegation output", delegation.InputID.ToHex()))
+	}
+
+	if !delegationInput.DelegationID.Empty() {
+		return b.setBuildError(ierrors.WithMessagef(ErrDelegationOperation, "delegation %s is already in delayed claiming", delegationInput.DelegationID.ToHex()))
+	}
+
+	if err := b.setCommitmentInput(commitmentID); err != nil {
+		return b.setBuildError(ierrors.Chain(ErrDelegationOperation, err))
+	}
+
+	delegationOutput, err := NewDelegationOutputBuilderFromPrevious(delegationInput).
+		DelegationID(iotago.DelegationIDFromOutputID(delegation.InputID)).
+		EndEpoch(DelegationEndEpoch(b.api, commitmentID.Slot())).
+		Build()
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	if _, exists := b.inputs[delegation.InputID]; !exists {
+		b.AddInput(delegation)
+	}
+
+	return b.AddOutput(delegationOutput)
+}
+
+// ClaimDelegationRewards destroys the DelegationOutput and claims its mana rewards with a reward input.
+// The rewards are the ones returned by the node for the delegation, the given commitment is added as commitment input
+// that the rewards are calculated against. The delegation input is added if it isn't part of the transaction yet.
+// The base tokens of the delegation and the claimed mana need to be handled by the remainder and mana helpers of the builder.
+func (b *TransactionBuilder) ClaimDelegationRewards(delegation *TxInput, rewards *api.ManaRewardsResponse, commitmentID iotago.CommitmentID) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	if _, isDelegation := delegation.Input.(*iotago.DelegationOutput); !isDelegation {
+		return b.setBuildError(ierrors.WithMessagef(ErrDelegationOperation, "input %s is not a delegation output", delegation.InputID.ToHex()))
+	}
+
+	if rewards == nil {
+		return b.setBuildError(ierrors.WithMessagef(ErrDelegationOperation, "no mana rewards given for input %s", delegation.InputID.ToHex()))
+	}
+
+	if err := b.setCommitmentInput(commitmentID); err != nil {
+		return b.setBuildError(ierrors.Chain(ErrDelegationOperation, err))
+	}
+
+	// the reward input references the index of the delegation input
+	inputIndex := len(b.transaction.TransactionEssence.Inputs)
+	for i, input := range b.transaction.TransactionEssence.Inputs {
+		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
+		if input.(*iotago.UTXOInput).OutputID() == delegation.InputID {
+			inputIndex = i
+			break
+		}
+	}
+
+	if inputIndex == len(b.transaction.TransactionEssence.Inputs) {
+		b.AddInput(delegation)
+	}
+
+	return b.AddRewardInput(&iotago.RewardInput{Index: uint16(inputIndex)}, rewards.Rewards)
+}
@@ -0,0 +1,195 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+// delegationTestLedger tracks the outputs used in the delegation operation tests.
+type delegationTestLedger struct {
+	*testLedger
+
+	funds      *builder.TxInput
+	delegation *builder.TxInput
+}
+
+func newDelegationTestLedger(t *testing.T) *delegationTestLedger {
+	t.Helper()
+
+	l := newTestLedger(t)
+
+	return &delegationTestLedger{
+		testLedger: l,
+		funds:      l.addGenesisInput(builder.NewBasicOutputBuilder(l.owner, 10_000_000).MustBuild()),
+	}
+}
+
+// transactionBuilder returns a builder that spends the funds of the owner.
+func (d *delegationTestLedger) transactionBuilder() *builder.TransactionBuilder {
+	return d.testLedger.transactionBuilder().AddInput(d.funds)
+}
+
+// apply sends the remaining base tokens and mana to a remainder output, applies the transaction to the ledger
+// and tracks the created delegation and remainder outputs.
+func (d *delegationTestLedger) apply(txBuilder *builder.TransactionBuilder, remainderOutputIndex int) {
+	d.t.Helper()
+
+	d.delegation = nil
+	for _, createdInput := range d.testLedger.apply(txBuilder.AddRemainderOutputs(d.owner), remainderOutputIndex) {
+		switch createdInput.Input.(type) {
+		case *iotago.DelegationOutput:
+			d.delegation = createdInput
+		case *iotago.BasicOutput:
+			d.funds = createdInput
+		}
+	}
+}
+
+func TestTransactionBuilderDelegationOperations(t *testing.T) {
+	d := newDelegationTestLedger(t)
+	d.AdvanceSlots(d.API().ProtocolParameters().MaxCommittableAge())
+	validatorAddress := tpkg.RandAccountID().ToAddress().(*iotago.AccountAddress)
+
+	// create
+	commitmentID := d.LatestCommitment().MustID()
+	d.apply(d.transactionBuilder().CreateDelegation(validatorAddress, d.owner, 4_000_000, commitmentID), 1)
+	require.NotNil(t, d.delegation)
+
+	delegation := d.delegation.Input.(*iotago.DelegationOutput)
+	require.True(t, delegation.DelegationID.Empty())
+	require.True(t, delegation.ValidatorAddress.Equal(validatorAddress))
+	require.EqualValues(t, 4_000_000, delegation.DelegatedAmount)
+	require.Equal(t, builder.DelegationStartEpoch(d.API(), commitmentID.Slot()), delegation.StartEpoch)
+	require.EqualValues(t, 6_000_000, d.funds.Input.BaseTokenAmount())
+
+	// delay claiming, the delegation is not added again if it is already an input of the transaction
+	commitmentID = d.LatestCommitment().MustID()
+	delegationOutputID := d.delegation.InputID
+	d.apply(d.transactionBuilder().AddInput(d.delegation).DelayDelegationClaiming(d.delegation, commitmentID), 1)
+	require.NotNil(t, d.delegation)
+
+	delayedDelegation := d.delegation.Input.(*iotago.DelegationOutput)
+	require.Equal(t, iotago.DelegationIDFromOutputID(delegationOutputID), delayedDelegation.DelegationID)
+	require.Equal(t, delegation.StartEpoch, delayedDelegation.StartEpoch)
+	require.Equal(t, builder.DelegationEndEpoch(d.API(), commitmentID.Slot()), delayedDelegation.EndEpoch)
+
+	t.Run("err - delay twice", func(t *testing.T) {
+		_, err := d.transactionBuilder().DelayDelegationClaiming(d.delegation, d.LatestCommitment().MustID()).Build()
+		require.ErrorIs(t, err, builder.ErrDelegationOperation)
+	})
+
+	t.Run("err - not a delegation", func(t *testing.T) {
+		_, err := builder.NewTransactionBuilder(d.API(), d.signer).DelayDelegationClaiming(d.funds, d.LatestCommitment().MustID()).Build()
+		require.ErrorIs(t, err, builder.ErrDelegationOperation)
+
+		_, err = builder.NewTransactionBuilder(d.API(), d.signer).ClaimDelegationRewards(d.funds, &api.ManaRewardsResponse{}, d.LatestCommitment().MustID()).Build()
+		require.ErrorIs(t, err, builder.ErrDelegationOperation)
+	})
+
+	t.Run("err - different commitment", func(t *testing.T) {
+		_, err := d.transactionBuilder().
+			AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: tpkg.Rand36ByteArray()}).
+			CreateDelegation(validatorAddress, d.owner, 1_000_000, d.LatestCommitment().MustID()).
+			Build()
+		require.ErrorIs(t, err, builder.ErrDelegationOperation)
+		require.ErrorIs(t, err, builder.ErrTransactionBuilder)
+	})
+
+	// claim the rewards once the delegation ended, the reward input references the delegation that is already an input
+	d.AdvanceToEpoch(delayedDelegation.EndEpoch + 1)
+	d.SetRewards(delayedDelegation.DelegationID, 1_000)
+	d.apply(d.transactionBuilder().AddInput(d.delegation).ClaimDelegationRewards(d.delegation, &api.ManaRewardsResponse{Rewards: 1_000}, d.LatestCommitment().MustID()), 0)
+	require.Nil(t, d.delegation)
+	require.EqualValues(t, 10_000_000, d.funds.Input.BaseTokenAmount())
+	require.GreaterOrEqual(t, d.funds.Input.StoredMana(), iotago.Mana(1_000))
+	require.Len(t, d.UnspentOutputs(), 1)
+}
+
+func TestDelegationEpochsAtEpochBoundaries(t *testing.T) {
+	testAPI := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+	protocolParameters := testAPI.ProtocolParameters()
+	validatorAddress := tpkg.RandAccountID().ToAddress().(*iotago.AccountAddress)
+
+	// the last slot of epoch 1 in which the registration for epoch 2 is open
+	registrationSlot := testAPI.TimeProvider().EpochEnd(1) - protocolParameters.EpochNearingThreshold()
+
+	tests := []struct {
+		name string
+		// the slot in which the transaction is issued, the commitment is the latest one that can be referenced in it
+		slot       iotago.SlotIndex
+		startEpoch iotago.EpochIndex
+		endEpoch   iotago.EpochIndex
+	}{
+		{
+			name:       "registration open",
+			slot:       registrationSlot - protocolParameters.MaxCommittableAge() + protocolParameters.MinCommittableAge(),
+			startEpoch: 2,
+			endEpoch:   1,
+		},
+		{
+			name:       "registration closed",
+			slot:       registrationSlot - protocolParameters.MaxCommittableAge() + protocolParameters.MinCommittableAge() + 1,
+			startEpoch: 3,
+			endEpoch:   1,
+		},
+		{
+			name:       "registration open for delayed claiming",
+			slot:       registrationSlot,
+			startEpoch: 3,
+			endEpoch:   1,
+		},
+		{
+			name:       "registration closed for delayed claiming",
+			slot:       registrationSlot + 1,
+			startEpoch: 3,
+			endEpoch:   2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newDelegationTestLedger(t)
+			d.AdvanceSlots(test.slot)
+
+			commitmentID := d.LatestCommitment().MustID()
+			require.Equal(t, test.startEpoch, builder.DelegationStartEpoch(testAPI, commitmentID.Slot()))
+			require.Equal(t, test.endEpoch, builder.DelegationEndEpoch(testAPI, commitmentID.Slot()))
+
+			// the ledger accepts the epochs of both transitions in the same slot
+			signedTx, err := d.transactionBuilder().
+				CreateDelegation(validatorAddress, d.owner, 4_000_000, commitmentID).
+				AddRemainderOutputs(d.owner).
+				StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(d.CurrentSlot(), 1).
+				Build()
+			require.NoError(t, err)
+
+			createdOutputs, err := d.ApplyTransaction(signedTx)
+			require.NoError(t, err)
+
+			for outputID, output := range createdOutputs {
+				if _, isDelegation := output.(*iotago.DelegationOutput); isDelegation {
+					d.delegation = &builder.TxInput{UnlockTarget: d.owner, InputID: outputID, Input: output}
+				} else {
+					d.funds = &builder.TxInput{UnlockTarget: d.owner, InputID: outputID, Input: output}
+				}
+			}
+
+			signedTx, err = d.transactionBuilder().
+				DelayDelegationClaiming(d.delegation, commitmentID).
+				AddRemainderOutputs(d.owner).
+				StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(d.CurrentSlot(), 1).
+				Build()
+			require.NoError(t, err)
+
+			_, err = d.ApplyTransaction(signedTx)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package builder
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+const (
+	// IRC30MetadataKey is the key of the IRC30 native token metadata in the immutable MetadataFeature of a foundry.
+	IRC30MetadataKey = "irc-30"
+	// IRC30Standard is the value of the standard field of IRC30 native token metadata.
+	IRC30Standard = "IRC30"
+)
+
+// ErrFoundryOperation gets returned if a foundry operation can't be applied to the transaction.
+var ErrFoundryOperation = ierrors.New("invalid foundry operation")
+
+// IRC30Metadata is the metadata of a native token as defined by the IRC30 standard.
+type IRC30Metadata struct {
+	Standard    string `json:"standard"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Symbol      string `json:"symbol"`
+	Decimals    uint32 `json:"decimals"`
+	URL         string `json:"url,omitempty"`
+	LogoURL     string `json:"logoUrl,omitempty"`
+	Logo        string `json:"logo,omitempty"`
+}
+
+// NewIRC30Metadata creates new IRC30 native token metadata with the mandatory fields.
+func NewIRC30Metadata(name string, symbol string, decimals uint32) *IRC30Metadata {
+	return &IRC30Metadata{
+		Standard: IRC30Standard,
+		Name:     name,
+		Symbol:   symbol,
+		Decimals: decimals,
+	}
+}
+
+// MetadataFeatureEntries returns the entries of the MetadataFeature holding the JSON encoded metadata.
+func (m *IRC30Metadata) MetadataFeatureEntries() (iotago.MetadataFeatureEntries, error) {
+	metadataJSON, err := json.Marshal(m)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to encode IRC30 metadata")
+	}
+
+	return iotago.MetadataFeatureEntries{IRC30MetadataKey: metadataJSON}, nil
+}
+
+// CreateFoundry creates a new foundry with a SimpleTokenScheme of the given maximum supply, controlled by the account of the given input.
+// The account is transitioned and its foundry counter is bumped, the new foundry gets the incremented foundry counter as serial number.
+// If metadata is given, it is stored in an immutable MetadataFeature of the foundry.
+// The storage deposit of the foundry is taken from the base tokens of the account.
+func (b *TransactionBuilder) CreateFoundry(account *TxInput, maxSupply *big.Int, metadata *IRC30Metadata) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	if maxSupply == nil || maxSupply.Sign() <= 0 {
+		return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "the maximum supply of the foundry must be positive, got %s", maxSupply))
+	}
+
+	accountOutput, err := b.accountTransition(account)
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	accountOutput.FoundryCounter++
+	accountAddress := iotago.AccountAddress(accountOutput.AccountID)
+	foundryBuilder := NewFoundryOutputBuilder(&accountAddress, 0, accountOutput.FoundryCounter, &iotago.SimpleTokenScheme{
+		MintedTokens:  big.NewInt(0),
+		MeltedTokens:  big.NewInt(0),
+		MaximumSupply: new(big.Int).Set(maxSupply),
+	})
+
+	if metadata != nil {
+		entries, err := metadata.MetadataFeatureEntries()
+		if err != nil {
+			return b.setBuildError(err)
+		}
+		foundryBuilder.ImmutableMetadata(entries)
+	}
+
+	foundryOutput, err := foundryBuilder.Build()
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	if err := b.depositFromAccount(accountOutput, foundryOutput); err != nil {
+		return b.setBuildError(err)
+	}
+
+	return b.AddOutput(foundryOutput)
+}
+
+// MintNativeTokens mints the given amount of native tokens of the foundry and sends them to the target address
+// in a new BasicOutput. The foundry and its controlling account are transitioned.
+// The storage deposit of the new output is taken from the base tokens of the account.
+func (b *TransactionBuilder) MintNativeTokens(account *TxInput, foundry *TxInput, amount *big.Int, targetAddress iotago.Address) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	if amount == nil || amount.Sign() <= 0 {
+		return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "the amount of native tokens to mint must be positive, got %s", amount))
+	}
+
+	accountOutput, foundryOutput, tokenScheme, err := b.foundryTransition(account, foundry)
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	circulatingSupply := new(big.Int).Sub(tokenScheme.MintedTokens, tokenScheme.MeltedTokens)
+	if circulatingSupply.Add(circulatingSupply, amount).Cmp(tokenScheme.MaximumSupply) > 0 {
+		return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "minting %s tokens exceeds the maximum supply %s of foundry %s", amount, tokenScheme.MaximumSupply, foundryOutput.MustFoundryID().ToHex()))
+	}
+	tokenScheme.MintedTokens.Add(tokenScheme.MintedTokens, amount)
+
+	tokenOutput := NewBasicOutputBuilder(targetAddress, 0).
+		NativeToken(&iotago.NativeTokenFeature{ID: foundryOutput.MustNativeTokenID(), Amount: new(big.Int).Set(amount)}).
+		MustBuild()
+
+	if err := b.depositFromAccount(accountOutput, tokenOutput); err != nil {
+		return b.setBuildError(err)
+	}
+
+	return b.AddOutput(tokenOutput)
+}
+
+// MeltNativeTokens melts the given amount of native tokens of the foundry, which are taken from the given token inputs.
+// The foundry and its controlling account are transitioned. The native tokens of the token inputs that are not melted
+// are sent to the unlock target of the first token input in a new BasicOutput.
+// The base tokens of the token inputs are moved to the account, their mana needs to be handled with the mana helpers of the builder.
+// Token inputs that are already part of the transaction are skipped.
+func (b *TransactionBuilder) MeltNativeTokens(account *TxInput, foundry *TxInput, amount *big.Int, tokenInputs ...*TxInput) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	if amount == nil || amount.Sign() <= 0 {
+		return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "the amount of native tokens to melt must be positive, got %s", amount))
+	}
+
+	if len(tokenInputs) == 0 {
+		return b.setBuildError(ierrors.WithMessage(ErrFoundryOperation, "no token inputs given to melt native tokens from"))
+	}
+
+	accountOutput, foundryOutput, tokenScheme, err := b.foundryTransition(account, foundry)
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	nativeTokenID := foundryOutput.MustNativeTokenID()
+	availableTokens := new(big.Int)
+	for _, tokenInput := range tokenInputs {
+		// inputs that are already part of the transaction are not consumed again
+		if _, exists := b.inputs[tokenInput.InputID]; exists {
+			continue
+		}
+
+		nativeToken := tokenInput.Input.FeatureSet().NativeToken()
+		if nativeToken == nil || nativeToken.ID != nativeTokenID {
+			return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "input %s doesn't hold native tokens of foundry %s", tokenInput.InputID.ToHex(), foundryOutput.MustFoundryID().ToHex()))
+		}
+		availableTokens.Add(availableTokens, nativeToken.Amount)
+
+		if accountOutput.Amount, err = safemath.SafeAdd(accountOutput.Amount, tokenInput.Input.BaseTokenAmount()); err != nil {
+			return b.setBuildError(ierrors.Wrap(err, "failed to move the base tokens of the token inputs to the account"))
+		}
+		b.AddInput(tokenInput)
+	}
+
+	remainingTokens := availableTokens.Sub(availableTokens, amount)
+	if remainingTokens.Sign() < 0 {
+		return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "the token inputs hold %s tokens less than the %s tokens to melt", new(big.Int).Neg(remainingTokens), amount))
+	}
+	tokenScheme.MeltedTokens.Add(tokenScheme.MeltedTokens, amount)
+
+	if remainingTokens.Sign() == 0 {
+		return b
+	}
+
+	remainderOutput := NewBasicOutputBuilder(tokenInputs[0].UnlockTarget, 0).
+		NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: remainingTokens}).
+		MustBuild()
+
+	if err := b.depositFromAccount(accountOutput, remainderOutput); err != nil {
+		return b.setBuildError(err)
+	}
+
+	return b.AddOutput(remainderOutput)
+}
+
+// DestroyFoundry destroys the foundry and moves its base tokens to its controlling account, which is transitioned.
+// The circulating supply of the foundry is burned, so the given token inputs need to hold exactly the native tokens
+// of the foundry that were minted but not melted yet. The base tokens of the token inputs are moved to the account,
+// their mana needs to be handled with the mana helpers of the builder.
+// Token inputs that are already part of the transaction are skipped.
+// The capabilities to destroy foundries and, if tokens are burned, to burn native tokens are added to the transaction.
+func (b *TransactionBuilder) DestroyFoundry(account *TxInput, foundry *TxInput, tokenInputs ...*TxInput) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	accountOutput, foundryOutput, tokenScheme, err := b.foundryTransition(account, foundry)
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	// the destruction is checked against the token scheme of the foundry input,
+	// so changes of the token scheme within the same transaction would get lost
+	if !foundryOutput.Equal(foundry.Input) {
+		return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "foundry %s can't be destroyed in the transaction that transitions it", foundryOutput.MustFoundryID().ToHex()))
+	}
+
+	nativeTokenID := foundryOutput.MustNativeTokenID()
+	burnedTokens := new(big.Int)
+	for _, tokenInput := range tokenInputs {
+		// inputs that are already part of the transaction are not consumed again
+		if _, exists := b.inputs[tokenInput.InputID]; exists {
+			continue
+		}
+
+		nativeToken := tokenInput.Input.FeatureSet().NativeToken()
+		if nativeToken == nil || nativeToken.ID != nativeTokenID {
+			return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "input %s doesn't hold native tokens of foundry %s", tokenInput.InputID.ToHex(), foundryOutput.MustFoundryID().ToHex()))
+		}
+		burnedTokens.Add(burnedTokens, nativeToken.Amount)
+
+		if accountOutput.Amount, err = safemath.SafeAdd(accountOutput.Amount, tokenInput.Input.BaseTokenAmount()); err != nil {
+			return b.setBuildError(ierrors.Wrap(err, "failed to move the base tokens of the token inputs to the account"))
+		}
+		b.AddInput(tokenInput)
+	}
+
+	// minted tokens + token diff (the burned tokens) must equal the melted tokens
+	if circulatingSupply := new(big.Int).Sub(tokenScheme.MintedTokens, tokenScheme.MeltedTokens); circulatingSupply.Cmp(burnedTokens) != 0 {
+		return b.setBuildError(ierrors.WithMessagef(ErrFoundryOperation, "foundry %s can't be destroyed with a circulating supply of %s tokens while burning %s tokens", foundryOutput.MustFoundryID().ToHex(), circulatingSupply, burnedTokens))
+	}
+
+	if accountOutput.Amount, err = safemath.SafeAdd(accountOutput.Amount, foundryOutput.Amount); err != nil {
+		return b.setBuildError(ierrors.Wrap(err, "failed to move the base tokens of the foundry to the account"))
+	}
+
+	for i, output := range b.transaction.Outputs {
+		if output == foundryOutput {
+			b.transaction.Outputs = append(b.transaction.Outputs[:i], b.transaction.Outputs[i+1:]...)
+			break
+		}
+	}
+
+	// keep the capabilities that were already set
+	capabilities := iotago.TransactionCapabilitiesBitMaskWithCapabilities(
+		iotago.WithTransactionCanDestroyFoundryOutputs(true),
+		iotago.WithTransactionCanBurnNativeTokens(burnedTokens.Sign() > 0),
+	)
+	for len(capabilities) < len(b.transaction.Capabilities) {
+		capabilities = append(capabilities, 0)
+	}
+	for i, capabilitiesByte := range b.transaction.Capabilities {
+		capabilities[i] |= capabilitiesByte
+	}
+
+	return b.WithTransactionCapabilities(capabilities)
+}
+
+// accountTransition returns the output of the account of the given input within the transaction.
+// If the account is not transitioned yet, the input is added and a copy of it is added as output.
+func (b *TransactionBuilder) accountTransition(account *TxInput) (*iotago.AccountOutput, error) {
+	accountInput, isAccount := account.Input.(*iotago.AccountOutput)
+	if !isAccount {
+		return nil, ierrors.WithMessagef(ErrFoundryOperation, "input %s is not an account output", account.InputID.ToHex())
+	}
+
+	accountID := accountInput.AccountID
+	if accountID.Empty() {
+		accountID = iotago.AccountIDFromOutputID(account.InputID)
+	}
+
+	for _, output := range b.transaction.Outputs {
+		if accountOutput, isAccountOutput := output.(*iotago.AccountOutput); isAccountOutput && accountOutput.AccountID == accountID {
+			return accountOutput, nil
+		}
+	}
+
+	if _, exists := b.inputs[account.InputID]; !exists {
+		b.AddInput(account)
+	}
+
+	//nolint:forcetypeassert // we can safely assume that this is an AccountOutput
+	accountOutput := accountInput.Clone().(*iotago.AccountOutput)
+	accountOutput.AccountID = accountID
+	b.AddOutput(accountOutput)
+
+	return accountOutput, nil
+}
+
+// foundryTransition returns the outputs of the foundry of the given input and of its controlling account within the transaction,
+// together with the token scheme of the foundry output.
+// If they are not transitioned yet, the inputs are added and copies of them are added as outputs.
+func (b *TransactionBuilder) foundryTransition(account *TxInput, foundry *TxInput) (*iotago.AccountOutput, *iotago.FoundryOutput, *iotago.SimpleTokenScheme, error) {
+	foundryInput, isFoundry := foundry.Input.(*iotago.FoundryOutput)
+	if !isFoundry {
+		return nil, nil, nil, ierrors.WithMessagef(ErrFoundryOperation, "input %s is not a foundry output", foundry.InputID.ToHex())
+	}
+
+	accountOutput, err := b.accountTransition(account)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	accountAddress := accountOutput.AccountID.ToAddress()
+	if !foundryInput.Owner().Equal(accountAddress) {
+		return nil, nil, nil, ierrors.WithMessagef(ErrFoundryOperation, "foundry %s is not controlled by account %s", foundryInput.MustFoundryID().ToHex(), accountOutput.AccountID.ToHex())
+	}
+
+	foundryID := foundryInput.MustFoundryID()
+
+	var foundryOutput *iotago.FoundryOutput
+	for _, output := range b.transaction.Outputs {
+		if otherFoundryOutput, isFoundryOutput := output.(*iotago.FoundryOutput); isFoundryOutput && otherFoundryOutput.MustFoundryID() == foundryID {
+			foundryOutput = otherFoundryOutput
+			break
+		}
+	}
+
+	if foundryOutput == nil {
+		if _, exists := b.inputs[foundry.InputID]; exists {
+			return nil, nil, nil, ierrors.WithMessagef(ErrFoundryOperation, "foundry %s is destroyed in the transaction", foundryID.ToHex())
+		}
+
+		// foundries are unlocked by their account
+		b.AddInput(&TxInput{UnlockTarget: accountAddress, InputID: foundry.InputID, Input: foundryInput})
+
+		//nolint:forcetypeassert // we can safely assume that this is a FoundryOutput
+		foundryOutput = foundryInput.Clone().(*iotago.FoundryOutput)
+		b.AddOutput(foundryOutput)
+	}
+
+	tokenScheme, isSimpleTokenScheme := foundryOutput.TokenScheme.(*iotago.SimpleTokenScheme)
+	if !isSimpleTokenScheme {
+		return nil, nil, nil, ierrors.WithMessagef(ErrFoundryOperation, "foundry %s doesn't use a simple token scheme", foundryID.ToHex())
+	}
+
+	return accountOutput, foundryOutput, tokenScheme, nil
+}
+
+// depositFromAccount sets the base tokens of the output to its minimum storage deposit, which is taken from the account.
+func (b *TransactionBuilder) depositFromAccount(accountOutput *iotago.AccountOutput, output iotago.Output) error {
+	minDeposit, err := b.api.StorageScoreStructure().MinDeposit(output)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate the minimum storage deposit")
+	}
+
+	accountMinDeposit, err := b.api.StorageScoreStructure().MinDeposit(accountOutput)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate the minimum storage deposit of the account")
+	}
+
+	// the account needs to keep its own storage deposit
+	if accountOutput.Amount < minDeposit || accountOutput.Amount-minDeposit < accountMinDeposit {
+		return ierrors.WithMessagef(ErrFoundryOperation, "account %s holds %d base tokens, but %d are needed for the storage deposit and %d for its own storage deposit", accountOutput.AccountID.ToHex(), accountOutput.Amount, minDeposit, accountMinDeposit)
+	}
+	accountOutput.Amount -= minDeposit
+
+	switch output := output.(type) {
+	case *iotago.BasicOutput:
+		output.Amount = minDeposit
+	case *iotago.FoundryOutput:
+		output.Amount = minDeposit
+	default:
+		return ierrors.Errorf("unsupported output type %s for the storage deposit", output.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/lo"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+// foundryTestLedger tracks the chains used in the foundry operation tests.
+type foundryTestLedger struct {
+	*testLedger
+
+	account *builder.TxInput
+	foundry *builder.TxInput
+	tokens  *builder.TxInput
+}
+
+func newFoundryTestLedger(t *testing.T) *foundryTestLedger {
+	t.Helper()
+
+	l := newTestLedger(t)
+	account := l.addGenesisInput(builder.NewAccountOutputBuilder(l.owner, 10_000_000).AccountID(tpkg.RandAccountID()).MustBuild())
+	l.AdvanceSlots(1)
+
+	return &foundryTestLedger{
+		testLedger: l,
+		account:    account,
+	}
+}
+
+// apply applies the transaction to the ledger and tracks the created account, foundry and token outputs.
+func (f *foundryTestLedger) apply(txBuilder *builder.TransactionBuilder) {
+	f.t.Helper()
+
+	f.foundry, f.tokens = nil, nil
+	for _, createdInput := range f.testLedger.apply(txBuilder, 0) {
+		switch createdInput.Input.(type) {
+		case *iotago.AccountOutput:
+			f.account = createdInput
+		case *iotago.FoundryOutput:
+			f.foundry = createdInput
+		case *iotago.BasicOutput:
+			f.tokens = createdInput
+		}
+	}
+}
+
+func TestTransactionBuilderFoundryOperations(t *testing.T) {
+	f := newFoundryTestLedger(t)
+	accountAmount := f.account.Input.BaseTokenAmount()
+
+	// create
+	f.apply(f.transactionBuilder().CreateFoundry(f.account, big.NewInt(1_000), builder.NewIRC30Metadata("Test Token", "TEST", 6)))
+	require.NotNil(t, f.foundry)
+
+	foundry := f.foundry.Input.(*iotago.FoundryOutput)
+	account := f.account.Input.(*iotago.AccountOutput)
+	require.EqualValues(t, 1, account.FoundryCounter)
+	require.EqualValues(t, 1, foundry.SerialNumber)
+	require.Equal(t, accountAmount, account.Amount+foundry.Amount)
+	require.True(t, foundry.Owner().Equal(account.AccountID.ToAddress()))
+
+	var metadata builder.IRC30Metadata
+	require.NoError(t, json.Unmarshal(foundry.ImmutableFeatureSet().Metadata().Entries[builder.IRC30MetadataKey], &metadata))
+	require.Equal(t, *builder.NewIRC30Metadata("Test Token", "TEST", 6), metadata)
+
+	nativeTokenID := foundry.MustNativeTokenID()
+
+	// mint
+	f.apply(f.transactionBuilder().MintNativeTokens(f.account, f.foundry, big.NewInt(600), f.owner))
+	require.NotNil(t, f.tokens)
+	require.Equal(t, big.NewInt(600), f.foundry.Input.(*iotago.FoundryOutput).TokenScheme.(*iotago.SimpleTokenScheme).MintedTokens)
+	require.Equal(t, nativeTokenID, f.tokens.Input.FeatureSet().NativeToken().ID)
+	require.Equal(t, big.NewInt(600), f.tokens.Input.FeatureSet().NativeToken().Amount)
+	require.True(t, f.tokens.Input.UnlockConditionSet().Address().Address.Equal(f.owner))
+
+	// melt part of the tokens, the rest is sent back to the owner
+	f.apply(f.transactionBuilder().MeltNativeTokens(f.account, f.foundry, big.NewInt(200), f.tokens))
+	require.Equal(t, big.NewInt(200), f.foundry.Input.(*iotago.FoundryOutput).TokenScheme.(*iotago.SimpleTokenScheme).MeltedTokens)
+	require.Equal(t, big.NewInt(400), f.tokens.Input.FeatureSet().NativeToken().Amount)
+
+	t.Run("err - circulating supply", func(t *testing.T) {
+		_, err := f.transactionBuilder().DestroyFoundry(f.account, f.foundry).Build()
+		require.ErrorIs(t, err, builder.ErrFoundryOperation)
+	})
+
+	t.Run("err - exceeds maximum supply", func(t *testing.T) {
+		_, err := f.transactionBuilder().MintNativeTokens(f.account, f.foundry, big.NewInt(601), f.owner).Build()
+		require.ErrorIs(t, err, builder.ErrFoundryOperation)
+	})
+
+	t.Run("err - melt more than the inputs hold", func(t *testing.T) {
+		_, err := f.transactionBuilder().MeltNativeTokens(f.account, f.foundry, big.NewInt(401), f.tokens).Build()
+		require.ErrorIs(t, err, builder.ErrFoundryOperation)
+	})
+
+	t.Run("err - not a foundry", func(t *testing.T) {
+		_, err := f.transactionBuilder().MintNativeTokens(f.account, f.tokens, big.NewInt(1), f.owner).Build()
+		require.ErrorIs(t, err, builder.ErrFoundryOperation)
+	})
+
+	t.Run("err - foundry of another account", func(t *testing.T) {
+		otherAccount := &builder.TxInput{
+			UnlockTarget: f.owner,
+			InputID:      tpkg.RandOutputID(0),
+			Input:        builder.NewAccountOutputBuilder(f.owner, 10_000_000).AccountID(tpkg.RandAccountID()).MustBuild(),
+		}
+
+		_, err := f.transactionBuilder().MintNativeTokens(otherAccount, f.foundry, big.NewInt(1), f.owner).Build()
+		require.ErrorIs(t, err, builder.ErrFoundryOperation)
+	})
+
+	t.Run("err - melt and destroy in the same transaction", func(t *testing.T) {
+		_, err := f.transactionBuilder().
+			MeltNativeTokens(f.account, f.foundry, big.NewInt(400), f.tokens).
+			DestroyFoundry(f.account, f.foundry).
+			Build()
+		require.ErrorIs(t, err, builder.ErrFoundryOperation)
+	})
+
+	t.Run("err - burned tokens don't match the circulating supply", func(t *testing.T) {
+		tokens := &builder.TxInput{
+			UnlockTarget: f.owner,
+			InputID:      tpkg.RandOutputID(0),
+			Input:        builder.NewBasicOutputBuilder(f.owner, 1_000_000).NativeToken(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(100)}).MustBuild(),
+		}
+
+		_, err := f.transactionBuilder().DestroyFoundry(f.account, f.foundry, tokens).Build()
+		require.ErrorIs(t, err, builder.ErrFoundryOperation)
+	})
+
+	t.Run("err - account can't keep its storage deposit", func(t *testing.T) {
+		accountOutput := builder.NewAccountOutputBuilder(f.owner, 0).AccountID(tpkg.RandAccountID()).MustBuild()
+		accountOutput.Amount = lo.PanicOnErr(f.API().StorageScoreStructure().MinDeposit(accountOutput))
+		poorAccount := &builder.TxInput{UnlockTarget: f.owner, InputID: tpkg.RandOutputID(0), Input: accountOutput}
+
+		_, err := f.transactionBuilder().CreateFoundry(poorAccount, big.NewInt(1_000), nil).Build()
+		require.ErrorIs(t, err, builder.ErrFoundryOperation)
+	})
+
+	t.Run("err - maximum supply not positive", func(t *testing.T) {
+		for _, maxSupply := range []*big.Int{nil, big.NewInt(0), big.NewInt(-1)} {
+			_, err := f.transactionBuilder().CreateFoundry(f.account, maxSupply, nil).Build()
+			require.ErrorIs(t, err, builder.ErrFoundryOperation)
+		}
+	})
+
+	t.Run("err - amount not positive", func(t *testing.T) {
+		for _, amount := range []*big.Int{nil, big.NewInt(0), big.NewInt(-1)} {
+			_, err := f.transactionBuilder().MintNativeTokens(f.account, f.foundry, amount, f.owner).Build()
+			require.ErrorIs(t, err, builder.ErrFoundryOperation)
+
+			_, err = f.transactionBuilder().MeltNativeTokens(f.account, f.foundry, amount, f.tokens).Build()
+			require.ErrorIs(t, err, builder.ErrFoundryOperation)
+		}
+	})
+
+	// melt more of the tokens, the rest is burned when the foundry is destroyed, token inputs are only consumed once
+	f.apply(f.transactionBuilder().MeltNativeTokens(f.account, f.foundry, big.NewInt(100), f.tokens, f.tokens))
+	require.Equal(t, big.NewInt(300), f.tokens.Input.FeatureSet().NativeToken().Amount)
+
+	// destroying the foundry returns all base tokens to the account
+	f.apply(f.transactionBuilder().DestroyFoundry(f.account, f.foundry, f.tokens))
+	require.Nil(t, f.foundry)
+	require.Nil(t, f.tokens)
+
+	account = f.account.Input.(*iotago.AccountOutput)
+	require.EqualValues(t, 1, account.FoundryCounter)
+	require.Equal(t, accountAmount, account.Amount)
+
+	_, err := f.Output(f.account.InputID)
+	require.NoError(t, err)
+	require.Len(t, f.UnspentOutputs(), 1)
+}
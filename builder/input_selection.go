@@ -0,0 +1,580 @@
+package builder
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrInputSelectionInsufficientBaseTokens gets returned if the candidates don't hold enough base tokens.
+	ErrInputSelectionInsufficientBaseTokens = ierrors.New("not enough base tokens available")
+	// ErrInputSelectionInsufficientMana gets returned if the candidates don't hold enough mana.
+	ErrInputSelectionInsufficientMana = ierrors.New("not enough mana available")
+	// ErrInputSelectionInsufficientNativeTokens gets returned if the candidates don't hold enough native tokens.
+	ErrInputSelectionInsufficientNativeTokens = ierrors.New("not enough native tokens available")
+	// ErrInputSelectionMaxInputsExceeded gets returned if more inputs than allowed would be needed.
+	ErrInputSelectionMaxInputsExceeded = ierrors.New("max inputs count exceeded")
+	// ErrInputSelectionMaxOutputsExceeded gets returned if the selection would result in more outputs than allowed.
+	ErrInputSelectionMaxOutputsExceeded = ierrors.New("max outputs count exceeded")
+)
+
+// InputCandidate is an output that can be selected as an input of a transaction.
+type InputCandidate struct {
+	// The address which needs to be unlocked to spend this input.
+	UnlockTarget iotago.Address
+	// The ID of the output.
+	OutputID iotago.OutputID
+	// The output itself.
+	Output *iotago.BasicOutput
+	// The base tokens that are available after the storage deposit was returned.
+	AvailableBaseTokens iotago.BaseToken
+	// The stored and potential mana of the output at the target slot.
+	AvailableMana iotago.Mana
+	// The StorageDepositReturnUnlockCondition that needs to be fulfilled when spending this input, or nil.
+	StorageDepositReturn *iotago.StorageDepositReturnUnlockCondition
+}
+
+// InputSelectionRequirements holds the balances that need to be covered by the selected inputs.
+type InputSelectionRequirements struct {
+	// The base tokens needed for the target outputs.
+	BaseTokens iotago.BaseToken
+	// The mana needed for the target outputs and allotments.
+	Mana iotago.Mana
+	// The native tokens needed for the target outputs.
+	NativeTokens iotago.NativeTokenSum
+}
+
+// InputSelectionStrategy returns the candidates in the order in which they should be selected.
+// The InputSelector selects candidates in that order until the requirements are covered.
+type InputSelectionStrategy func(candidates []*InputCandidate, requirements *InputSelectionRequirements) []*InputCandidate
+
+// InputSelectionStrategyLargestFirst selects the candidates with the most base tokens first.
+func InputSelectionStrategyLargestFirst(candidates []*InputCandidate, _ *InputSelectionRequirements) []*InputCandidate {
+	ordered := sortedCandidates(candidates, func(a *InputCandidate, b *InputCandidate) bool {
+		return a.AvailableBaseTokens > b.AvailableBaseTokens
+	})
+
+	return ordered
+}
+
+// InputSelectionStrategyMinimizeInputs selects the smallest candidate that covers the required base tokens on its own first.
+// If there is no such candidate, the candidates with the most base tokens are selected first.
+func InputSelectionStrategyMinimizeInputs(candidates []*InputCandidate, requirements *InputSelectionRequirements) []*InputCandidate {
+	ordered := InputSelectionStrategyLargestFirst(candidates, requirements)
+
+	// the candidates are sorted in descending order, so the last candidate covering the requirement is the smallest one
+	smallestCoveringIndex := -1
+	for i, candidate := range ordered {
+		if candidate.AvailableBaseTokens < requirements.BaseTokens {
+			break
+		}
+		smallestCoveringIndex = i
+	}
+
+	if smallestCoveringIndex <= 0 {
+		return ordered
+	}
+
+	smallestCovering := ordered[smallestCoveringIndex]
+	copy(ordered[1:smallestCoveringIndex+1], ordered[:smallestCoveringIndex])
+	ordered[0] = smallestCovering
+
+	return ordered
+}
+
+// InputSelectionStrategyConsolidateDust selects the candidates with the least base tokens first,
+// so small outputs get consumed and merged into the remainder.
+func InputSelectionStrategyConsolidateDust(candidates []*InputCandidate, _ *InputSelectionRequirements) []*InputCandidate {
+	return sortedCandidates(candidates, func(a *InputCandidate, b *InputCandidate) bool {
+		return a.AvailableBaseTokens < b.AvailableBaseTokens
+	})
+}
+
+// sortedCandidates returns a copy of the candidates sorted by the given less function.
+// Candidates that are equal are sorted by their OutputID to get a deterministic order.
+func sortedCandidates(candidates []*InputCandidate, less func(a *InputCandidate, b *InputCandidate) bool) []*InputCandidate {
+	ordered := make([]*InputCandidate, len(candidates))
+	copy(ordered, candidates)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if less(ordered[i], ordered[j]) {
+			return true
+		}
+		if less(ordered[j], ordered[i]) {
+			return false
+		}
+
+		return bytes.Compare(ordered[i].OutputID[:], ordered[j].OutputID[:]) < 0
+	})
+
+	return ordered
+}
+
+// InputSelection is the result of an InputSelector.
+type InputSelection struct {
+	// The selected inputs.
+	Inputs []*TxInput
+	// The outputs that return the storage deposits of the selected inputs.
+	StorageDepositReturnOutputs []*iotago.BasicOutput
+	// The outputs that hold the remaining base tokens, mana and native tokens.
+	RemainderOutputs []*iotago.BasicOutput
+}
+
+// NewInputSelector creates a new InputSelector that selects inputs out of the given candidates,
+// which are unlockable by one of the given addresses.
+// Only BasicOutputs are considered as candidates.
+func NewInputSelector(api iotago.API, candidates iotago.OutputSet, addresses ...iotago.Address) *InputSelector {
+	return &InputSelector{
+		api:        api,
+		candidates: candidates,
+		addresses:  addresses,
+		strategy:   InputSelectionStrategyLargestFirst,
+		maxInputs:  iotago.MaxInputsCount,
+	}
+}
+
+// InputSelector selects the inputs needed to fund a set of target outputs.
+type InputSelector struct {
+	api            iotago.API
+	candidates     iotago.OutputSet
+	addresses      []iotago.Address
+	strategy       InputSelectionStrategy
+	maxInputs      int
+	additionalMana iotago.Mana
+	commitmentSlot *iotago.SlotIndex
+}
+
+// Strategy sets the strategy which is used to order the candidates.
+func (s *InputSelector) Strategy(strategy InputSelectionStrategy) *InputSelector {
+	s.strategy = strategy
+
+	return s
+}
+
+// MaxInputs sets the maximum amount of inputs that can be selected.
+func (s *InputSelector) MaxInputs(maxInputs int) *InputSelector {
+	s.maxInputs = maxInputs
+
+	return s
+}
+
+// AdditionalMana sets the mana that is needed on top of the stored mana of the target outputs, e.g. for allotments.
+func (s *InputSelector) AdditionalMana(mana iotago.Mana) *InputSelector {
+	s.additionalMana = mana
+
+	return s
+}
+
+// CommitmentSlot sets the slot of the commitment input that will be referenced by the transaction.
+// It is used to check timelock and expiration unlock conditions the same way the VM does.
+// If it is not set, the most recent commitment that can be referenced at the target slot is assumed.
+func (s *InputSelector) CommitmentSlot(slot iotago.SlotIndex) *InputSelector {
+	s.commitmentSlot = &slot
+
+	return s
+}
+
+// Select selects the inputs needed to fund the given outputs at the given target slot.
+// Remaining base tokens, mana and native tokens are sent to the change address.
+func (s *InputSelector) Select(targetSlot iotago.SlotIndex, outputs iotago.TxEssenceOutputs, changeAddress iotago.Address) (*InputSelection, error) {
+	requirements, err := s.requirements(outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.unlockableCandidates(targetSlot)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := s.strategy(candidates, requirements)
+
+	selection := newInputSelectionState()
+
+	// select the candidates holding the required native tokens first
+	nativeTokenIDs := sortedNativeTokenIDs(requirements.NativeTokens)
+	for _, nativeTokenID := range nativeTokenIDs {
+		required := requirements.NativeTokens[nativeTokenID]
+
+		for _, candidate := range ordered {
+			if selection.nativeTokens.ValueOrBigInt0(nativeTokenID).Cmp(required) >= 0 {
+				break
+			}
+
+			if nativeTokenFeature := candidate.Output.FeatureSet().NativeToken(); nativeTokenFeature == nil || nativeTokenFeature.ID != nativeTokenID {
+				continue
+			}
+
+			if err := selection.add(candidate); err != nil {
+				return nil, err
+			}
+		}
+
+		if available := selection.nativeTokens.ValueOrBigInt0(nativeTokenID); available.Cmp(required) < 0 {
+			return nil, ierrors.WithMessagef(ErrInputSelectionInsufficientNativeTokens, "native token %s: %s < %s", nativeTokenID.ToHex(), available, required)
+		}
+	}
+
+	// select further candidates until the base tokens, the mana and the storage deposit of the remainder are covered
+	candidateIndex := 0
+	for {
+		if len(selection.candidates) > s.maxInputs {
+			return nil, ierrors.WithMessagef(ErrInputSelectionMaxInputsExceeded, "%d > %d", len(selection.candidates), s.maxInputs)
+		}
+
+		remainderOutputs, err := s.tryRemainder(selection, requirements, changeAddress)
+		if err == nil {
+			return s.inputSelection(selection, outputs, remainderOutputs)
+		}
+
+		// find the next candidate that was not selected yet
+		for candidateIndex < len(ordered) && selection.contains(ordered[candidateIndex]) {
+			candidateIndex++
+		}
+
+		if candidateIndex >= len(ordered) {
+			return nil, err
+		}
+
+		if err := selection.add(ordered[candidateIndex]); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// requirements calculates the balances needed to fund the given outputs.
+func (s *InputSelector) requirements(outputs iotago.TxEssenceOutputs) (*InputSelectionRequirements, error) {
+	requirements := &InputSelectionRequirements{
+		Mana: s.additionalMana,
+	}
+
+	for _, output := range outputs {
+		var err error
+
+		requirements.BaseTokens, err = safemath.SafeAdd(requirements.BaseTokens, output.BaseTokenAmount())
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to sum up the base tokens of the outputs")
+		}
+
+		requirements.Mana, err = safemath.SafeAdd(requirements.Mana, output.StoredMana())
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to sum up the mana of the outputs")
+		}
+	}
+
+	nativeTokens, err := outputs.NativeTokenSum()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to sum up the native tokens of the outputs")
+	}
+	requirements.NativeTokens = nativeTokens
+
+	return requirements, nil
+}
+
+// unlockableCandidates returns the candidates that are unlockable by one of the addresses at the target slot.
+func (s *InputSelector) unlockableCandidates(targetSlot iotago.SlotIndex) ([]*InputCandidate, error) {
+	protocolParams := s.api.ProtocolParameters()
+
+	commitmentSlot := targetSlot - protocolParams.MinCommittableAge()
+	if targetSlot < protocolParams.MinCommittableAge() {
+		commitmentSlot = 0
+	}
+	if s.commitmentSlot != nil {
+		commitmentSlot = *s.commitmentSlot
+	}
+	pastBoundedSlot := commitmentSlot + protocolParams.MaxCommittableAge()
+	futureBoundedSlot := commitmentSlot + protocolParams.MinCommittableAge()
+
+	candidates := make([]*InputCandidate, 0, len(s.candidates))
+	for outputID, output := range s.candidates {
+		basicOutput, isBasicOutput := output.(*iotago.BasicOutput)
+		if !isBasicOutput {
+			continue
+		}
+
+		if outputID.CreationSlot() > targetSlot {
+			continue
+		}
+
+		unlockConditions := basicOutput.UnlockConditionSet()
+		if err := unlockConditions.TimelocksExpired(futureBoundedSlot); err != nil {
+			continue
+		}
+
+		unlockTarget := basicOutput.Owner()
+		if unlockTarget.Type() == iotago.AddressImplicitAccountCreation {
+			// implicit accounts can only be transitioned to accounts
+			continue
+		}
+
+		expirationReturnAddr, err := unlockConditions.CheckExpirationCondition(futureBoundedSlot, pastBoundedSlot)
+		if err != nil {
+			// neither the owner nor the return address can unlock the output at the moment
+			continue
+		}
+		if expirationReturnAddr != nil {
+			unlockTarget = expirationReturnAddr
+		}
+
+		if !s.isOwnAddress(unlockTarget) {
+			continue
+		}
+
+		candidate := &InputCandidate{
+			UnlockTarget:        unlockTarget,
+			OutputID:            outputID,
+			Output:              basicOutput,
+			AvailableBaseTokens: basicOutput.Amount,
+		}
+
+		// the storage deposit only needs to be returned if the input is not unlocked by the return address
+		if storageDepositReturn := unlockConditions.StorageDepositReturn(); storageDepositReturn != nil && ResolveUnderlyingAddress(unlockTarget).Key() != storageDepositReturn.ReturnAddress.Key() {
+			candidate.StorageDepositReturn = storageDepositReturn
+			candidate.AvailableBaseTokens -= storageDepositReturn.Amount
+		}
+
+		potentialMana, err := iotago.PotentialMana(s.api.ManaDecayProvider(), s.api.StorageScoreStructure(), basicOutput, outputID.CreationSlot(), targetSlot)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to calculate potential mana of output %s", outputID.ToHex())
+		}
+
+		storedMana, err := s.api.ManaDecayProvider().DecayManaBySlots(basicOutput.StoredMana(), outputID.CreationSlot(), targetSlot)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to calculate stored mana decay of output %s", outputID.ToHex())
+		}
+
+		candidate.AvailableMana, err = safemath.SafeAdd(potentialMana, storedMana)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to calculate mana of output %s", outputID.ToHex())
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// isOwnAddress checks whether the given address, or its underlying address in case of a restricted address,
+// is one of the addresses of the InputSelector.
+func (s *InputSelector) isOwnAddress(addr iotago.Address) bool {
+	addr = ResolveUnderlyingAddress(addr)
+
+	for _, ownAddr := range s.addresses {
+		if ResolveUnderlyingAddress(ownAddr).Equal(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tryRemainder returns the remainder outputs if the selected candidates cover the requirements, otherwise an error.
+func (s *InputSelector) tryRemainder(selection *inputSelectionState, requirements *InputSelectionRequirements, changeAddress iotago.Address) ([]*iotago.BasicOutput, error) {
+	if selection.baseTokens < requirements.BaseTokens {
+		return nil, ierrors.WithMessagef(ErrInputSelectionInsufficientBaseTokens, "%d < %d", selection.baseTokens, requirements.BaseTokens)
+	}
+
+	if selection.mana < requirements.Mana {
+		return nil, ierrors.WithMessagef(ErrInputSelectionInsufficientMana, "%d < %d", selection.mana, requirements.Mana)
+	}
+
+	remainingNativeTokens := make(iotago.NativeTokenSum)
+	for nativeTokenID, amount := range selection.nativeTokens {
+		remaining := new(big.Int).Sub(amount, requirements.NativeTokens.ValueOrBigInt0(nativeTokenID))
+		if remaining.Sign() > 0 {
+			remainingNativeTokens[nativeTokenID] = remaining
+		}
+	}
+
+	remainingBaseTokens := selection.baseTokens - requirements.BaseTokens
+	remainderOutputs, missingBaseTokens, err := newRemainderOutputs(s.api.StorageScoreStructure(), changeAddress, remainingBaseTokens, selection.mana-requirements.Mana, remainingNativeTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	if missingBaseTokens > 0 {
+		return nil, ierrors.WithMessagef(ErrInputSelectionInsufficientBaseTokens, "remaining base tokens don't cover the storage deposit of the remainder: %d < %d", remainingBaseTokens, remainingBaseTokens+missingBaseTokens)
+	}
+
+	return remainderOutputs, nil
+}
+
+// inputSelection creates the InputSelection of the selected candidates.
+func (s *InputSelector) inputSelection(selection *inputSelectionState, outputs iotago.TxEssenceOutputs, remainderOutputs []*iotago.BasicOutput) (*InputSelection, error) {
+	result := &InputSelection{
+		Inputs:                      make([]*TxInput, 0, len(selection.candidates)),
+		StorageDepositReturnOutputs: make([]*iotago.BasicOutput, 0),
+		RemainderOutputs:            remainderOutputs,
+	}
+
+	// the return amounts are summed up per return address, since the VM checks the sum of simple transfers per address
+	returnOutputsByAddr := make(map[string]*iotago.BasicOutput)
+	for _, candidate := range selection.candidates {
+		result.Inputs = append(result.Inputs, &TxInput{
+			UnlockTarget: candidate.UnlockTarget,
+			InputID:      candidate.OutputID,
+			Input:        candidate.Output,
+		})
+
+		if candidate.StorageDepositReturn == nil {
+			continue
+		}
+
+		returnAddrKey := candidate.StorageDepositReturn.ReturnAddress.Key()
+		if returnOutput, exists := returnOutputsByAddr[returnAddrKey]; exists {
+			returnOutput.Amount += candidate.StorageDepositReturn.Amount
+			continue
+		}
+
+		returnOutput := &iotago.BasicOutput{
+			Amount: candidate.StorageDepositReturn.Amount,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{
+				&iotago.AddressUnlockCondition{Address: candidate.StorageDepositReturn.ReturnAddress},
+			},
+			Features: iotago.BasicOutputFeatures{},
+		}
+		returnOutputsByAddr[returnAddrKey] = returnOutput
+		result.StorageDepositReturnOutputs = append(result.StorageDepositReturnOutputs, returnOutput)
+	}
+
+	if outputsCount := len(outputs) + len(result.StorageDepositReturnOutputs) + len(result.RemainderOutputs); outputsCount > iotago.MaxOutputsCount {
+		return nil, ierrors.WithMessagef(ErrInputSelectionMaxOutputsExceeded, "%d > %d", outputsCount, iotago.MaxOutputsCount)
+	}
+
+	return result, nil
+}
+
+// AddInputSelection adds the inputs, the storage deposit return outputs and the remainder outputs of the given InputSelection to the builder.
+// A commitment input needs to be added separately if any of the inputs has a timelock or expiration unlock condition.
+func (b *TransactionBuilder) AddInputSelection(selection *InputSelection) *TransactionBuilder {
+	for _, input := range selection.Inputs {
+		b.AddInput(input)
+	}
+
+	for _, output := range selection.StorageDepositReturnOutputs {
+		b.AddOutput(output)
+	}
+
+	for _, output := range selection.RemainderOutputs {
+		b.AddOutput(output)
+	}
+
+	return b
+}
+
+// inputSelectionState keeps track of the selected candidates and their balances.
+type inputSelectionState struct {
+	candidates   []*InputCandidate
+	selectedIDs  map[iotago.OutputID]struct{}
+	baseTokens   iotago.BaseToken
+	mana         iotago.Mana
+	nativeTokens iotago.NativeTokenSum
+}
+
+func newInputSelectionState() *inputSelectionState {
+	return &inputSelectionState{
+		candidates:   make([]*InputCandidate, 0),
+		selectedIDs:  make(map[iotago.OutputID]struct{}),
+		nativeTokens: make(iotago.NativeTokenSum),
+	}
+}
+
+func (s *inputSelectionState) contains(candidate *InputCandidate) bool {
+	_, contains := s.selectedIDs[candidate.OutputID]
+	return contains
+}
+
+func (s *inputSelectionState) add(candidate *InputCandidate) error {
+	var err error
+
+	s.baseTokens, err = safemath.SafeAdd(s.baseTokens, candidate.AvailableBaseTokens)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to add base tokens of the input")
+	}
+
+	s.mana, err = safemath.SafeAdd(s.mana, candidate.AvailableMana)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to add mana of the input")
+	}
+
+	if nativeTokenFeature := candidate.Output.FeatureSet().NativeToken(); nativeTokenFeature != nil {
+		s.nativeTokens[nativeTokenFeature.ID] = new(big.Int).Add(s.nativeTokens.ValueOrBigInt0(nativeTokenFeature.ID), nativeTokenFeature.Amount)
+	}
+
+	s.candidates = append(s.candidates, candidate)
+	s.selectedIDs[candidate.OutputID] = struct{}{}
+
+	return nil
+}
+
+// newRemainderOutputs creates the outputs that hold the given remaining base tokens, mana and native tokens on the given address.
+// Since a NativeTokenFeature holds a single native token, one output is created per native token.
+// The excess base tokens and the mana are added to the first output.
+// If the base tokens don't cover the minimum storage deposit of the outputs, the missing amount is returned.
+func newRemainderOutputs(storageScoreStructure *iotago.StorageScoreStructure, addr iotago.Address, baseTokens iotago.BaseToken, mana iotago.Mana, nativeTokens iotago.NativeTokenSum) ([]*iotago.BasicOutput, iotago.BaseToken, error) {
+	newOutput := func() *iotago.BasicOutput {
+		return &iotago.BasicOutput{
+			UnlockConditions: iotago.BasicOutputUnlockConditions{
+				&iotago.AddressUnlockCondition{Address: addr},
+			},
+			Features: iotago.BasicOutputFeatures{},
+		}
+	}
+
+	remainderOutputs := make([]*iotago.BasicOutput, 0, len(nativeTokens))
+	for _, nativeTokenID := range sortedNativeTokenIDs(nativeTokens) {
+		output := newOutput()
+		output.Features.Upsert(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: new(big.Int).Set(nativeTokens[nativeTokenID])})
+		remainderOutputs = append(remainderOutputs, output)
+	}
+
+	if len(remainderOutputs) == 0 {
+		if baseTokens == 0 && mana == 0 {
+			// nothing remains, so no remainder output is needed
+			return remainderOutputs, 0, nil
+		}
+
+		remainderOutputs = append(remainderOutputs, newOutput())
+	}
+
+	var minDepositSum iotago.BaseToken
+	for _, output := range remainderOutputs {
+		minDeposit, err := storageScoreStructure.MinDeposit(output)
+		if err != nil {
+			return nil, 0, ierrors.Wrap(err, "failed to calculate the min deposit of the remainder output")
+		}
+		output.Amount = minDeposit
+
+		if minDepositSum, err = safemath.SafeAdd(minDepositSum, minDeposit); err != nil {
+			return nil, 0, ierrors.Wrap(err, "failed to sum up the min deposits of the remainder outputs")
+		}
+	}
+
+	if baseTokens < minDepositSum {
+		return nil, minDepositSum - baseTokens, nil
+	}
+
+	remainderOutputs[0].Amount += baseTokens - minDepositSum
+	remainderOutputs[0].Mana = mana
+
+	return remainderOutputs, 0, nil
+}
+
+// sortedNativeTokenIDs returns the IDs of the given native tokens in lexical order.
+func sortedNativeTokenIDs(nativeTokens iotago.NativeTokenSum) []iotago.NativeTokenID {
+	nativeTokenIDs := make([]iotago.NativeTokenID, 0, len(nativeTokens))
+	for nativeTokenID := range nativeTokens {
+		nativeTokenIDs = append(nativeTokenIDs, nativeTokenID)
+	}
+
+	sort.Slice(nativeTokenIDs, func(i, j int) bool {
+		return bytes.Compare(nativeTokenIDs[i][:], nativeTokenIDs[j][:]) < 0
+	})
+
+	return nativeTokenIDs
+}
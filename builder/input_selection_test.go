@@ -0,0 +1,245 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestInputSelector(t *testing.T) {
+	api := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+
+	const targetSlot = iotago.SlotIndex(100)
+
+	prvKey, ownAddr, _ := tpkg.RandEd25519Identity()
+	otherAddr := tpkg.RandEd25519Address()
+	changeAddr := tpkg.RandEd25519Address()
+	nativeTokenID := tpkg.RandNativeTokenID()
+
+	minDeposit, err := api.StorageScoreStructure().MinDeposit(&iotago.BasicOutput{
+		UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: changeAddr}},
+	})
+	require.NoError(t, err)
+
+	newBasicOutput := func(amount iotago.BaseToken, unlockConditions ...iotago.BasicOutputUnlockCondition) *iotago.BasicOutput {
+		return &iotago.BasicOutput{
+			Amount:           amount,
+			UnlockConditions: append(iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: ownAddr}}, unlockConditions...),
+			Features:         iotago.BasicOutputFeatures{},
+		}
+	}
+
+	targetOutput := func(amount iotago.BaseToken) iotago.TxEssenceOutputs {
+		return iotago.TxEssenceOutputs{
+			&iotago.BasicOutput{
+				Amount:           amount,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: otherAddr}},
+			},
+		}
+	}
+
+	var (
+		smallID  = tpkg.RandOutputIDWithCreationSlot(10, 0)
+		mediumID = tpkg.RandOutputIDWithCreationSlot(10, 1)
+		largeID  = tpkg.RandOutputIDWithCreationSlot(10, 2)
+
+		small  = newBasicOutput(minDeposit)
+		medium = newBasicOutput(10 * minDeposit)
+		large  = newBasicOutput(100 * minDeposit)
+
+		candidates = iotago.OutputSet{smallID: small, mediumID: medium, largeID: large}
+	)
+
+	type test struct {
+		name                  string
+		selector              *builder.InputSelector
+		outputs               iotago.TxEssenceOutputs
+		expectedInputs        []iotago.OutputID
+		expectedReturnOutputs int
+		expectedRemainders    int
+		selectErr             error
+	}
+
+	tests := []*test{
+		{
+			name:               "ok - largest first",
+			selector:           builder.NewInputSelector(api, candidates, ownAddr),
+			outputs:            targetOutput(5 * minDeposit),
+			expectedInputs:     []iotago.OutputID{largeID},
+			expectedRemainders: 1,
+		},
+		{
+			name:               "ok - minimize inputs picks the smallest sufficient input",
+			selector:           builder.NewInputSelector(api, candidates, ownAddr).Strategy(builder.InputSelectionStrategyMinimizeInputs),
+			outputs:            targetOutput(5 * minDeposit),
+			expectedInputs:     []iotago.OutputID{mediumID},
+			expectedRemainders: 1,
+		},
+		{
+			name:               "ok - consolidate dust picks the smallest inputs first",
+			selector:           builder.NewInputSelector(api, candidates, ownAddr).Strategy(builder.InputSelectionStrategyConsolidateDust),
+			outputs:            targetOutput(5 * minDeposit),
+			expectedInputs:     []iotago.OutputID{smallID, mediumID},
+			expectedRemainders: 1,
+		},
+		{
+			name:           "ok - exact amount without remainder",
+			selector:       builder.NewInputSelector(api, iotago.OutputSet{smallID: newBasicOutput(minDeposit)}, ownAddr),
+			outputs:        targetOutput(minDeposit),
+			expectedInputs: []iotago.OutputID{smallID},
+		},
+		func() *test {
+			nativeTokenOutputID := tpkg.RandOutputIDWithCreationSlot(10, 3)
+			nativeTokenOutput := newBasicOutput(2 * minDeposit)
+			nativeTokenOutput.Features.Upsert(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(100)})
+
+			outputs := targetOutput(2 * minDeposit)
+			outputs[0].(*iotago.BasicOutput).Features.Upsert(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(40)})
+
+			return &test{
+				name:           "ok - native tokens are selected and the rest is sent to the remainder",
+				selector:       builder.NewInputSelector(api, iotago.OutputSet{nativeTokenOutputID: nativeTokenOutput, largeID: large}, ownAddr),
+				outputs:        outputs,
+				expectedInputs: []iotago.OutputID{nativeTokenOutputID, largeID},
+				// one remainder for the native tokens
+				expectedRemainders: 1,
+			}
+		}(),
+		func() *test {
+			returnOutputID := tpkg.RandOutputIDWithCreationSlot(10, 3)
+			returnOutput := newBasicOutput(10*minDeposit, &iotago.StorageDepositReturnUnlockCondition{ReturnAddress: otherAddr, Amount: minDeposit})
+
+			return &test{
+				name:                  "ok - storage deposit is returned",
+				selector:              builder.NewInputSelector(api, iotago.OutputSet{returnOutputID: returnOutput}, ownAddr),
+				outputs:               targetOutput(5 * minDeposit),
+				expectedInputs:        []iotago.OutputID{returnOutputID},
+				expectedReturnOutputs: 1,
+				expectedRemainders:    1,
+			}
+		}(),
+		func() *test {
+			expiredOutputID := tpkg.RandOutputIDWithCreationSlot(10, 3)
+			expiredOutput := &iotago.BasicOutput{
+				Amount: 10 * minDeposit,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{
+					&iotago.AddressUnlockCondition{Address: otherAddr},
+					&iotago.StorageDepositReturnUnlockCondition{ReturnAddress: ownAddr, Amount: minDeposit},
+					&iotago.ExpirationUnlockCondition{ReturnAddress: ownAddr, Slot: 20},
+				},
+			}
+
+			return &test{
+				name:               "ok - expired output can be claimed by the return address",
+				selector:           builder.NewInputSelector(api, iotago.OutputSet{expiredOutputID: expiredOutput}, ownAddr),
+				outputs:            targetOutput(5 * minDeposit),
+				expectedInputs:     []iotago.OutputID{expiredOutputID},
+				expectedRemainders: 1,
+			}
+		}(),
+		func() *test {
+			timelockedOutputID := tpkg.RandOutputIDWithCreationSlot(10, 3)
+			timelockedOutput := newBasicOutput(100*minDeposit, &iotago.TimelockUnlockCondition{Slot: 1000})
+
+			return &test{
+				name:      "err - timelocked output is not selected",
+				selector:  builder.NewInputSelector(api, iotago.OutputSet{timelockedOutputID: timelockedOutput}, ownAddr),
+				outputs:   targetOutput(5 * minDeposit),
+				selectErr: builder.ErrInputSelectionInsufficientBaseTokens,
+			}
+		}(),
+		{
+			name:      "err - remainder below min deposit",
+			selector:  builder.NewInputSelector(api, iotago.OutputSet{mediumID: medium}, ownAddr),
+			outputs:   targetOutput(10*minDeposit - 1),
+			selectErr: builder.ErrInputSelectionInsufficientBaseTokens,
+		},
+		{
+			name:      "err - not enough mana",
+			selector:  builder.NewInputSelector(api, candidates, ownAddr).AdditionalMana(iotago.MaxMana),
+			outputs:   targetOutput(5 * minDeposit),
+			selectErr: builder.ErrInputSelectionInsufficientMana,
+		},
+		func() *test {
+			outputs := targetOutput(minDeposit)
+			outputs[0].(*iotago.BasicOutput).Features.Upsert(&iotago.NativeTokenFeature{ID: nativeTokenID, Amount: big.NewInt(1)})
+
+			return &test{
+				name:      "err - not enough native tokens",
+				selector:  builder.NewInputSelector(api, candidates, ownAddr),
+				outputs:   outputs,
+				selectErr: builder.ErrInputSelectionInsufficientNativeTokens,
+			}
+		}(),
+		{
+			name:      "err - max inputs exceeded",
+			selector:  builder.NewInputSelector(api, candidates, ownAddr).Strategy(builder.InputSelectionStrategyConsolidateDust).MaxInputs(1),
+			outputs:   targetOutput(5 * minDeposit),
+			selectErr: builder.ErrInputSelectionMaxInputsExceeded,
+		},
+		{
+			name:      "err - max inputs exceeded before the candidates run out",
+			selector:  builder.NewInputSelector(api, candidates, ownAddr).MaxInputs(1),
+			outputs:   targetOutput(iotago.MaxBaseToken),
+			selectErr: builder.ErrInputSelectionMaxInputsExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selection, err := test.selector.Select(targetSlot, test.outputs, changeAddr)
+			if test.selectErr != nil {
+				require.ErrorIs(t, err, test.selectErr)
+
+				return
+			}
+			require.NoError(t, err)
+
+			selectedInputIDs := make([]iotago.OutputID, 0, len(selection.Inputs))
+			inputSet := vm.InputSet{}
+			for _, input := range selection.Inputs {
+				selectedInputIDs = append(selectedInputIDs, input.InputID)
+				inputSet[input.InputID] = input.Input
+			}
+			require.ElementsMatch(t, test.expectedInputs, selectedInputIDs)
+			require.Len(t, selection.StorageDepositReturnOutputs, test.expectedReturnOutputs)
+			require.Len(t, selection.RemainderOutputs, test.expectedRemainders)
+
+			for _, remainder := range selection.RemainderOutputs {
+				_, err := api.StorageScoreStructure().CoversMinDeposit(remainder, remainder.Amount)
+				require.NoError(t, err)
+			}
+
+			// the resulting transaction must be balanced
+			signedTx, err := builder.NewTransactionBuilder(api, iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(prvKey)).
+				SetCreationSlot(targetSlot).
+				AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: iotago.NewCommitmentID(targetSlot-api.ProtocolParameters().MinCommittableAge(), tpkg.Rand32ByteArray())}).
+				AddInputSelection(selection).
+				AddOutput(test.outputs[0]).
+				Build()
+			require.NoError(t, err)
+
+			resolvedInputs := vm.ResolvedInputs{
+				InputSet: inputSet,
+				CommitmentInput: &iotago.Commitment{
+					Slot: targetSlot - api.ProtocolParameters().MinCommittableAge(),
+				},
+			}
+
+			novaVM := nova.NewVirtualMachine()
+			unlockedAddrs, err := novaVM.ValidateUnlocks(signedTx, resolvedInputs)
+			require.NoError(t, err)
+
+			_, err = novaVM.Execute(signedTx.Transaction, resolvedInputs, unlockedAddrs)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package builder_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm/nova/ledger"
+)
+
+// testLedger is the ledger the builder tests apply their transactions to,
+// together with the owner of the outputs and a signer for the keys of all owners.
+type testLedger struct {
+	*ledger.Ledger
+
+	t       *testing.T
+	prvKeys []ed25519.PrivateKey
+	signer  iotago.AddressSigner
+	owner   iotago.Address
+}
+
+func newTestLedger(t *testing.T) *testLedger {
+	t.Helper()
+
+	l := &testLedger{
+		Ledger: ledger.New(iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)),
+		t:      t,
+	}
+	l.owner = l.addOwner()
+
+	return l
+}
+
+// addOwner creates a new owner whose key is added to the signer of the ledger.
+func (l *testLedger) addOwner() iotago.Address {
+	prvKey, owner, _ := tpkg.RandEd25519Identity()
+	l.prvKeys = append(l.prvKeys, prvKey)
+	l.signer = iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(l.prvKeys...)
+
+	return owner
+}
+
+// addGenesisInput adds the output owned by the owner of the ledger to the genesis of the ledger.
+func (l *testLedger) addGenesisInput(output iotago.Output) *builder.TxInput {
+	return &builder.TxInput{UnlockTarget: l.owner, InputID: l.AddGenesisOutput(output), Input: output}
+}
+
+// transactionBuilder returns a builder for a transaction in the current slot.
+func (l *testLedger) transactionBuilder() *builder.TransactionBuilder {
+	return builder.NewTransactionBuilder(l.API(), l.signer).SetCreationSlot(l.CurrentSlot())
+}
+
+// apply stores the remaining mana in the output with the given index, applies the transaction to the ledger
+// and advances the ledger by a slot. The created outputs are returned as inputs owned by the owner of the ledger.
+func (l *testLedger) apply(txBuilder *builder.TransactionBuilder, storedManaOutputIndex int) []*builder.TxInput {
+	l.t.Helper()
+
+	signedTx, err := txBuilder.StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(l.CurrentSlot(), storedManaOutputIndex).Build()
+	require.NoError(l.t, err)
+
+	createdOutputs, err := l.ApplyTransaction(signedTx)
+	require.NoError(l.t, err)
+
+	createdInputs := make([]*builder.TxInput, 0, len(createdOutputs))
+	for outputID, output := range createdOutputs {
+		createdInputs = append(createdInputs, &builder.TxInput{UnlockTarget: l.owner, InputID: outputID, Input: output})
+	}
+
+	l.AdvanceSlots(1)
+
+	return createdInputs
+}
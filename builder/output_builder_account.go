@@ -31,8 +31,9 @@ func NewAccountOutputBuilderFromPrevious(previous *iotago.AccountOutput) *Accoun
 
 // AccountOutputBuilder builds an iotago.AccountOutput.
 type AccountOutputBuilder struct {
-	prev   *iotago.AccountOutput
-	output *iotago.AccountOutput
+	prev             *iotago.AccountOutput
+	output           *iotago.AccountOutput
+	occurredBuildErr error
 }
 
 // Amount sets the base token amount of the output.
@@ -130,6 +131,10 @@ func (builder *AccountOutputBuilder) ImmutableMetadata(entries iotago.MetadataFe
 
 // Build builds the iotago.AccountOutput.
 func (builder *AccountOutputBuilder) Build() (*iotago.AccountOutput, error) {
+	if builder.occurredBuildErr != nil {
+		return nil, builder.occurredBuildErr
+	}
+
 	if builder.prev != nil {
 		if !builder.prev.ImmutableFeatures.Equal(builder.output.ImmutableFeatures) {
 			return nil, ierrors.New("immutable features are not allowed to be changed")
@@ -0,0 +1,308 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrPartiallySignedTransactionMismatch gets returned if partially signed transactions of different transactions are merged.
+	ErrPartiallySignedTransactionMismatch = ierrors.New("partially signed transactions do not belong to the same transaction")
+	// ErrSignatureNotRequired gets returned if a signature is added that doesn't match any address that needs to be unlocked.
+	ErrSignatureNotRequired = ierrors.New("signature does not match any address that needs to be unlocked")
+)
+
+// PartiallySignedTransactionInput defines an input of a PartiallySignedTransaction with the address to unlock.
+type PartiallySignedTransactionInput struct {
+	// The address which needs to be unlocked to spend this input.
+	UnlockTarget iotago.Address `serix:""`
+	// The ID of the referenced input.
+	InputID iotago.OutputID `serix:""`
+	// The output which is used as an input.
+	Input iotago.TxEssenceOutput `serix:""`
+}
+
+// PartiallySignedTransaction is an unsigned Transaction together with its resolved inputs and the signatures
+// that were collected so far. It can be passed between co-signers, merged and finally turned into a SignedTransaction.
+type PartiallySignedTransaction struct {
+	API iotago.API
+	// The unsigned transaction.
+	Transaction *iotago.Transaction `serix:""`
+	// The inputs of the transaction in the same order as in the transaction essence.
+	Inputs []*PartiallySignedTransactionInput `serix:",lenPrefix=uint16"`
+	// The signatures collected so far, sorted by signer UID.
+	Signatures []iotago.Signature `serix:",lenPrefix=uint16"`
+}
+
+// BuildPartiallySignedTransaction builds the transaction without signing it
+// and returns it as a PartiallySignedTransaction.
+func (b *TransactionBuilder) BuildPartiallySignedTransaction() (*PartiallySignedTransaction, error) {
+	if b.occurredBuildErr != nil {
+		return nil, b.occurredBuildErr
+	}
+
+	// the transaction needs to be in its final form, so the signing message doesn't change afterwards
+	b.transaction.Allotments.Sort()
+	b.transaction.TransactionEssence.ContextInputs.Sort()
+
+	inputs := make([]*PartiallySignedTransactionInput, 0, len(b.transaction.TransactionEssence.Inputs))
+	for _, inputRef := range b.transaction.TransactionEssence.Inputs {
+		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
+		inputID := inputRef.(*iotago.UTXOInput).OutputID()
+
+		input, exists := b.inputs[inputID]
+		if !exists {
+			return nil, ierrors.Errorf("input %s is not known to the builder", inputID.ToHex())
+		}
+
+		txEssenceOutput, isTxEssenceOutput := input.(iotago.TxEssenceOutput)
+		if !isTxEssenceOutput {
+			return nil, ierrors.Errorf("input %s of type %s can't be serialized", inputID.ToHex(), input.Type())
+		}
+
+		inputs = append(inputs, &PartiallySignedTransactionInput{
+			UnlockTarget: b.inputOwner[inputID],
+			InputID:      inputID,
+			Input:        txEssenceOutput,
+		})
+	}
+
+	return &PartiallySignedTransaction{
+		API:         b.api,
+		Transaction: b.transaction.Clone(),
+		Inputs:      inputs,
+		Signatures:  []iotago.Signature{},
+	}, nil
+}
+
+func (p *PartiallySignedTransaction) SetDeserializationContext(ctx context.Context) {
+	p.API = iotago.APIFromContext(ctx)
+}
+
+// Clone returns a deep copy of the PartiallySignedTransaction.
+func (p *PartiallySignedTransaction) Clone() *PartiallySignedTransaction {
+	inputs := make([]*PartiallySignedTransactionInput, 0, len(p.Inputs))
+	for _, input := range p.Inputs {
+		//nolint:forcetypeassert // we can safely assume that this is a TxEssenceOutput
+		inputs = append(inputs, &PartiallySignedTransactionInput{
+			UnlockTarget: input.UnlockTarget.Clone(),
+			InputID:      input.InputID,
+			Input:        input.Input.Clone().(iotago.TxEssenceOutput),
+		})
+	}
+
+	signatures := make([]iotago.Signature, 0, len(p.Signatures))
+	for _, signature := range p.Signatures {
+		signatures = append(signatures, signature.Clone())
+	}
+
+	return &PartiallySignedTransaction{
+		API:         p.API,
+		Transaction: p.Transaction.Clone(),
+		Inputs:      inputs,
+		Signatures:  signatures,
+	}
+}
+
+// RequiredSigners returns all directly unlockable addresses that are able to contribute to unlocking the inputs.
+// Addresses inside of MultiAddresses are included, even if not all of them are needed to reach the threshold.
+func (p *PartiallySignedTransaction) RequiredSigners() []iotago.DirectUnlockableAddress {
+	seenAddrs := make(map[string]struct{})
+	requiredSigners := make([]iotago.DirectUnlockableAddress, 0)
+
+	addRequiredSigner := func(addr iotago.Address) {
+		directUnlockableAddr, isDirectUnlockable := addr.(iotago.DirectUnlockableAddress)
+		if !isDirectUnlockable {
+			return
+		}
+
+		if _, seen := seenAddrs[addr.Key()]; seen {
+			return
+		}
+		seenAddrs[addr.Key()] = struct{}{}
+
+		requiredSigners = append(requiredSigners, directUnlockableAddr)
+	}
+
+	for _, input := range p.Inputs {
+		owner := ResolveUnderlyingAddress(input.UnlockTarget)
+
+		if multiAddr, isMultiAddress := owner.(*iotago.MultiAddress); isMultiAddress {
+			for _, addrWithWeight := range multiAddr.Addresses {
+				addRequiredSigner(addrWithWeight.Address)
+			}
+
+			continue
+		}
+
+		addRequiredSigner(owner)
+	}
+
+	return requiredSigners
+}
+
+// Sign adds the signatures for all required signers the given signer holds the keys for.
+// It returns an error if the signer holds none of the keys.
+func (p *PartiallySignedTransaction) Sign(signer iotago.AddressSigner) error {
+	signingMessage, err := p.Transaction.SigningMessage()
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate tx transaction for signing message")
+	}
+
+	var signed bool
+	for _, addr := range p.RequiredSigners() {
+		signerUID, err := signer.SignerUIDForAddress(addr)
+		if err != nil {
+			if ierrors.Is(err, iotago.ErrAddressKeysNotMapped) {
+				// the signer doesn't hold the keys for this address
+				continue
+			}
+
+			return ierrors.Wrapf(err, "failed to get signer UID for address %s", addr.Bech32(p.API.ProtocolParameters().Bech32HRP()))
+		}
+		signed = true
+
+		if p.hasSignatureOfSigner(signerUID) {
+			continue
+		}
+
+		signature, err := signer.Sign(addr, signingMessage)
+		if err != nil {
+			return ierrors.Wrap(err, "failed to sign transaction")
+		}
+
+		if err := p.AddSignature(signature); err != nil {
+			return err
+		}
+	}
+
+	if !signed {
+		return ierrors.WithMessage(iotago.ErrAddressKeysNotMapped, "signer holds none of the keys of the required signers")
+	}
+
+	return nil
+}
+
+// AddSignature verifies the given signature against the addresses that need to be unlocked and adds it.
+// Signatures of signers that already signed the transaction are ignored.
+func (p *PartiallySignedTransaction) AddSignature(signature iotago.Signature) error {
+	if p.hasSignatureOfSigner(signature.SignerUID()) {
+		return nil
+	}
+
+	signingMessage, err := p.Transaction.SigningMessage()
+	if err != nil {
+		return ierrors.Wrap(err, "failed to calculate tx transaction for signing message")
+	}
+
+	for _, addr := range p.RequiredSigners() {
+		if !signature.MatchesAddress(addr) {
+			continue
+		}
+
+		if err := addr.Unlock(signingMessage, signature); err != nil {
+			return ierrors.Wrapf(err, "invalid signature for address %s", addr.Bech32(p.API.ProtocolParameters().Bech32HRP()))
+		}
+
+		p.Signatures = append(p.Signatures, signature)
+		sort.Slice(p.Signatures, func(i, j int) bool {
+			signerUIDI, signerUIDJ := p.Signatures[i].SignerUID(), p.Signatures[j].SignerUID()
+
+			return bytes.Compare(signerUIDI[:], signerUIDJ[:]) < 0
+		})
+
+		return nil
+	}
+
+	return ierrors.WithMessagef(ErrSignatureNotRequired, "signer UID %s", signature.SignerUID().ToHex())
+}
+
+// Merge adds the signatures of the other PartiallySignedTransaction, which must belong to the same transaction.
+func (p *PartiallySignedTransaction) Merge(other *PartiallySignedTransaction) error {
+	txID, err := p.Transaction.ID()
+	if err != nil {
+		return ierrors.Wrap(err, "failed to compute transaction ID")
+	}
+
+	otherTxID, err := other.Transaction.ID()
+	if err != nil {
+		return ierrors.Wrap(err, "failed to compute transaction ID of the other partially signed transaction")
+	}
+
+	if txID != otherTxID {
+		return ierrors.WithMessagef(ErrPartiallySignedTransactionMismatch, "%s != %s", txID.ToHex(), otherTxID.ToHex())
+	}
+
+	for _, signature := range other.Signatures {
+		if err := p.AddSignature(signature); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Finalize creates the SignedTransaction with the collected signatures.
+// It returns an error if the collected signatures are not sufficient to unlock all inputs.
+func (p *PartiallySignedTransaction) Finalize() (*iotago.SignedTransaction, error) {
+	txBuilder := NewTransactionBuilder(p.API, &collectedSignaturesSigner{signatures: p.Signatures})
+	txBuilder.transaction = p.Transaction.Clone()
+
+	for _, input := range p.Inputs {
+		txBuilder.inputOwner[input.InputID] = input.UnlockTarget
+		txBuilder.inputs[input.InputID] = input.Input
+	}
+
+	return txBuilder.Build()
+}
+
+func (p *PartiallySignedTransaction) hasSignatureOfSigner(signerUID iotago.Identifier) bool {
+	for _, signature := range p.Signatures {
+		if signature.SignerUID() == signerUID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectedSignaturesSigner implements iotago.AddressSigner by handing out already collected signatures.
+type collectedSignaturesSigner struct {
+	signatures []iotago.Signature
+}
+
+func (s *collectedSignaturesSigner) signatureForAddress(addr iotago.Address) (iotago.Signature, error) {
+	addr = ResolveUnderlyingAddress(addr)
+	for _, signature := range s.signatures {
+		if signature.MatchesAddress(addr) {
+			return signature, nil
+		}
+	}
+
+	return nil, ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "no signature collected for address %s", addr)
+}
+
+func (s *collectedSignaturesSigner) SignerUIDForAddress(addr iotago.Address) (iotago.Identifier, error) {
+	signature, err := s.signatureForAddress(addr)
+	if err != nil {
+		return iotago.EmptyIdentifier, err
+	}
+
+	return signature.SignerUID(), nil
+}
+
+func (s *collectedSignaturesSigner) Sign(addr iotago.Address, _ []byte) (iotago.Signature, error) {
+	return s.signatureForAddress(addr)
+}
+
+func (s *collectedSignaturesSigner) EmptySignatureForAddress(addr iotago.Address) (iotago.Signature, error) {
+	if _, err := s.signatureForAddress(addr); err != nil {
+		return nil, err
+	}
+
+	return &iotago.Ed25519Signature{}, nil
+}
@@ -0,0 +1,123 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+)
+
+func TestPartiallySignedTransaction(t *testing.T) {
+	addresses, addressKeys := tpkg.RandEd25519IdentitiesSortedByAddress(3)
+
+	// 2-of-3 multi address with equal weights
+	multiAddr := iotago.NewMultiAddress(iotago.AddressesWithWeight{
+		{Address: addresses[0], Weight: 1},
+		{Address: addresses[1], Weight: 1},
+		{Address: addresses[2], Weight: 1},
+	}, 2)
+
+	inputID1 := tpkg.RandOutputID(0)
+	input1 := &iotago.BasicOutput{
+		Amount:           1000,
+		UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addresses[0]}},
+	}
+	inputID2 := tpkg.RandOutputID(1)
+	input2 := &iotago.BasicOutput{
+		Amount:           1000,
+		UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: multiAddr}},
+	}
+
+	output := &iotago.BasicOutput{
+		Amount: 2000,
+		UnlockConditions: iotago.BasicOutputUnlockConditions{
+			&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()},
+		},
+	}
+
+	newPartiallySignedTransaction := func() *builder.PartiallySignedTransaction {
+		partiallySignedTx, err := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, nil).
+			AddInput(&builder.TxInput{UnlockTarget: addresses[0], InputID: inputID1, Input: input1}).
+			AddInput(&builder.TxInput{UnlockTarget: multiAddr, InputID: inputID2, Input: input2}).
+			AddOutput(output).
+			BuildPartiallySignedTransaction()
+		require.NoError(t, err)
+
+		return partiallySignedTx
+	}
+
+	// serializes the partially signed transaction and deserializes it again, like it would be passed between co-signers
+	roundTrip := func(partiallySignedTx *builder.PartiallySignedTransaction) *builder.PartiallySignedTransaction {
+		txBytes, err := tpkg.ZeroCostTestAPI.Encode(partiallySignedTx)
+		require.NoError(t, err)
+
+		decoded := &builder.PartiallySignedTransaction{}
+		_, err = tpkg.ZeroCostTestAPI.Decode(txBytes, decoded)
+		require.NoError(t, err)
+
+		return decoded
+	}
+
+	t.Run("ok - two co-signers", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTransaction()
+		require.Len(t, partiallySignedTx.RequiredSigners(), 3)
+
+		cosigner1 := roundTrip(partiallySignedTx)
+		require.NoError(t, cosigner1.Sign(iotago.NewInMemoryAddressSigner(addressKeys[0])))
+
+		cosigner2 := roundTrip(partiallySignedTx)
+		require.NoError(t, cosigner2.Sign(iotago.NewInMemoryAddressSigner(addressKeys[2])))
+
+		// the first signature alone is not sufficient to reach the threshold of the multi address
+		_, err := cosigner1.Finalize()
+		require.ErrorIs(t, err, iotago.ErrMultiAddressUnlockThresholdNotReached)
+
+		require.NoError(t, partiallySignedTx.Merge(roundTrip(cosigner1)))
+		require.NoError(t, partiallySignedTx.Merge(roundTrip(cosigner2)))
+		require.Len(t, partiallySignedTx.Signatures, 2)
+
+		// merging the same signatures twice is a no-op
+		require.NoError(t, partiallySignedTx.Merge(cosigner2))
+		require.Len(t, partiallySignedTx.Signatures, 2)
+
+		signedTx, err := partiallySignedTx.Finalize()
+		require.NoError(t, err)
+
+		require.IsType(t, &iotago.SignatureUnlock{}, signedTx.Unlocks[0])
+		require.IsType(t, &iotago.MultiUnlock{}, signedTx.Unlocks[1])
+		multiUnlock := signedTx.Unlocks[1].(*iotago.MultiUnlock)
+		require.IsType(t, &iotago.ReferenceUnlock{}, multiUnlock.Unlocks[0])
+		require.IsType(t, &iotago.EmptyUnlock{}, multiUnlock.Unlocks[1])
+		require.IsType(t, &iotago.SignatureUnlock{}, multiUnlock.Unlocks[2])
+
+		_, err = vm.ValidateUnlocks(signedTx, vm.ResolvedInputs{InputSet: vm.InputSet{inputID1: input1, inputID2: input2}})
+		require.NoError(t, err)
+	})
+
+	t.Run("err - signer holds no keys", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTransaction()
+
+		_, _, unrelatedKeys := tpkg.RandEd25519Identity()
+		require.ErrorIs(t, partiallySignedTx.Sign(iotago.NewInMemoryAddressSigner(unrelatedKeys)), iotago.ErrAddressKeysNotMapped)
+	})
+
+	t.Run("err - signature not required", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTransaction()
+
+		require.ErrorIs(t, partiallySignedTx.AddSignature(tpkg.RandEd25519Signature()), builder.ErrSignatureNotRequired)
+	})
+
+	t.Run("err - merge different transactions", func(t *testing.T) {
+		partiallySignedTx := newPartiallySignedTransaction()
+
+		otherPartiallySignedTx := newPartiallySignedTransaction()
+		otherPartiallySignedTx.Transaction.CreationSlot++
+
+		require.ErrorIs(t, partiallySignedTx.Merge(otherPartiallySignedTx), builder.ErrPartiallySignedTransactionMismatch)
+	})
+}
@@ -0,0 +1,148 @@
+package builder
+
+import (
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+)
+
+// ErrStakingOperation gets returned if a staking operation can't be applied to an account.
+var ErrStakingOperation = ierrors.New("invalid staking operation")
+
+// StakingStartEpoch returns the start epoch a new StakingFeature needs to have
+// if it is added in a transaction that references the commitment of the given slot.
+func StakingStartEpoch(apiForSlot iotago.API, commitmentSlot iotago.SlotIndex) iotago.EpochIndex {
+	return apiForSlot.TimeProvider().EpochFromSlot(commitmentSlot + apiForSlot.ProtocolParameters().MaxCommittableAge())
+}
+
+// StakingEarliestEndEpoch returns the earliest end epoch a StakingFeature can be set to
+// in a transaction that references the commitment of the given slot, which is the start epoch plus the unbonding period.
+func StakingEarliestEndEpoch(apiForSlot iotago.API, commitmentSlot iotago.SlotIndex) iotago.EpochIndex {
+	return StakingStartEpoch(apiForSlot, commitmentSlot) + apiForSlot.ProtocolParameters().StakingUnbondingPeriod()
+}
+
+// StakingFeatureExpired tells whether the end epoch of the StakingFeature is over for a transaction that references
+// the commitment of the given slot. Only then the feature can be removed and its rewards can be claimed.
+func StakingFeatureExpired(apiForSlot iotago.API, stakingFeature *iotago.StakingFeature, commitmentSlot iotago.SlotIndex) bool {
+	futureBoundedEpoch := apiForSlot.TimeProvider().EpochFromSlot(commitmentSlot + apiForSlot.ProtocolParameters().MinCommittableAge())
+
+	return futureBoundedEpoch > stakingFeature.EndEpoch
+}
+
+// RegisterValidator adds a StakingFeature with the given staked amount and fixed cost to the account,
+// so it becomes a validator candidate. The start epoch is derived from the slot of the commitment the transaction references.
+// The end epoch needs to be at least StakingEarliestEndEpoch, iotago.MaxEpochIndex stakes without end.
+// The account needs to hold a BlockIssuerFeature and at least the staked amount of base tokens.
+func (builder *AccountOutputBuilder) RegisterValidator(apiForSlot iotago.API, commitmentSlot iotago.SlotIndex, stakedAmount iotago.BaseToken, fixedCost iotago.Mana, endEpoch iotago.EpochIndex) *AccountOutputBuilder {
+	if builder.occurredBuildErr != nil {
+		return builder
+	}
+
+	if stakingFeature := builder.output.FeatureSet().Staking(); stakingFeature != nil && !StakingFeatureExpired(apiForSlot, stakingFeature, commitmentSlot) {
+		builder.occurredBuildErr = ierrors.WithMessagef(ErrStakingOperation, "account is already staking until epoch %d", stakingFeature.EndEpoch)
+		return builder
+	}
+
+	if builder.output.FeatureSet().BlockIssuer() == nil {
+		builder.occurredBuildErr = ierrors.Chain(ErrStakingOperation, iotago.ErrStakingBlockIssuerFeatureMissing)
+		return builder
+	}
+
+	if builder.output.Amount < stakedAmount {
+		builder.occurredBuildErr = ierrors.WithMessagef(ErrStakingOperation, "account holds %d base tokens, but %d are staked", builder.output.Amount, stakedAmount)
+		return builder
+	}
+
+	if earliestEndEpoch := StakingEarliestEndEpoch(apiForSlot, commitmentSlot); endEpoch < earliestEndEpoch {
+		builder.occurredBuildErr = ierrors.WithMessagef(ErrStakingOperation, "end epoch %d is before the end of the unbonding period in epoch %d", endEpoch, earliestEndEpoch)
+		return builder
+	}
+
+	return builder.Staking(stakedAmount, fixedCost, StakingStartEpoch(apiForSlot, commitmentSlot), endEpoch)
+}
+
+// ExtendStaking sets the end epoch of the StakingFeature of the account, which is not expired yet.
+// The new end epoch needs to be at least StakingEarliestEndEpoch, so a validator can't leave before the unbonding period is over.
+func (builder *AccountOutputBuilder) ExtendStaking(apiForSlot iotago.API, commitmentSlot iotago.SlotIndex, endEpoch iotago.EpochIndex) *AccountOutputBuilder {
+	if builder.occurredBuildErr != nil {
+		return builder
+	}
+
+	stakingFeature := builder.output.FeatureSet().Staking()
+	if stakingFeature == nil {
+		builder.occurredBuildErr = ierrors.WithMessage(ErrStakingOperation, "account is not staking")
+		return builder
+	}
+
+	if StakingFeatureExpired(apiForSlot, stakingFeature, commitmentSlot) {
+		builder.occurredBuildErr = ierrors.WithMessagef(ErrStakingOperation, "staking ended in epoch %d, the account needs to register again", stakingFeature.EndEpoch)
+		return builder
+	}
+
+	if earliestEndEpoch := StakingEarliestEndEpoch(apiForSlot, commitmentSlot); endEpoch < earliestEndEpoch {
+		builder.occurredBuildErr = ierrors.WithMessagef(ErrStakingOperation, "end epoch %d is before the end of the unbonding period in epoch %d", endEpoch, earliestEndEpoch)
+		return builder
+	}
+
+	return builder.Staking(stakingFeature.StakedAmount, stakingFeature.FixedCost, stakingFeature.StartEpoch, endEpoch)
+}
+
+// LeaveValidator removes the StakingFeature of the account once its end epoch is over.
+// The rewards of the account need to be claimed in the same transaction, see TransactionBuilder.ClaimStakingRewards.
+func (builder *AccountOutputBuilder) LeaveValidator(apiForSlot iotago.API, commitmentSlot iotago.SlotIndex) *AccountOutputBuilder {
+	if builder.occurredBuildErr != nil {
+		return builder
+	}
+
+	stakingFeature := builder.output.FeatureSet().Staking()
+	if stakingFeature == nil {
+		builder.occurredBuildErr = ierrors.WithMessage(ErrStakingOperation, "account is not staking")
+		return builder
+	}
+
+	if !StakingFeatureExpired(apiForSlot, stakingFeature, commitmentSlot) {
+		builder.occurredBuildErr = ierrors.WithMessagef(ErrStakingOperation, "staking can't be left before the end epoch %d is over", stakingFeature.EndEpoch)
+		return builder
+	}
+
+	return builder.RemoveFeature(iotago.FeatureStaking)
+}
+
+// ClaimStakingRewards claims the mana rewards of the staking account with a reward input.
+// The rewards are the ones returned by the node for the account, the given commitment is added as commitment input
+// that the rewards are calculated against. The account input is added if it isn't part of the transaction yet,
+// its StakingFeature needs to be removed or registered again on the output side, see AccountOutputBuilder.LeaveValidator.
+func (b *TransactionBuilder) ClaimStakingRewards(account *TxInput, rewards *api.ManaRewardsResponse, commitmentID iotago.CommitmentID) *TransactionBuilder {
+	if b.occurredBuildErr != nil {
+		return b
+	}
+
+	accountInput, isAccount := account.Input.(*iotago.AccountOutput)
+	if !isAccount || accountInput.FeatureSet().Staking() == nil {
+		return b.setBuildError(ierrors.WithMessagef(ErrStakingOperation, "input %s is not a staking account", account.InputID.ToHex()))
+	}
+
+	if rewards == nil {
+		return b.setBuildError(ierrors.WithMessagef(ErrStakingOperation, "no mana rewards given for input %s", account.InputID.ToHex()))
+	}
+
+	if err := b.setCommitmentInput(commitmentID); err != nil {
+		return b.setBuildError(ierrors.Chain(ErrStakingOperation, err))
+	}
+
+	// the reward input references the index of the account input
+	inputIndex := len(b.transaction.TransactionEssence.Inputs)
+	for i, input := range b.transaction.TransactionEssence.Inputs {
+		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
+		if input.(*iotago.UTXOInput).OutputID() == account.InputID {
+			inputIndex = i
+			break
+		}
+	}
+
+	if inputIndex == len(b.transaction.TransactionEssence.Inputs) {
+		b.AddInput(account)
+	}
+
+	return b.AddRewardInput(&iotago.RewardInput{Index: uint16(inputIndex)}, rewards.Rewards)
+}
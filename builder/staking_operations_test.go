@@ -0,0 +1,149 @@
+//nolint:forcetypeassert
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+)
+
+func TestAccountOutputBuilderStakingOperations(t *testing.T) {
+	l := newTestLedger(t)
+	owner, testAPI := l.owner, l.API()
+	unbondingPeriod := testAPI.ProtocolParameters().StakingUnbondingPeriod()
+
+	accountOutput := builder.NewAccountOutputBuilder(owner, 10_000_000).
+		AccountID(tpkg.RandAccountID()).
+		BlockIssuer(iotago.NewBlockIssuerKeys(tpkg.RandBlockIssuerKey()), iotago.MaxSlotIndex).
+		MustBuild()
+	account := l.addGenesisInput(accountOutput)
+	l.AdvanceSlots(testAPI.ProtocolParameters().MaxCommittableAge())
+
+	// transition applies the transition of the account input of the transaction to the ledger and tracks the new account output
+	transition := func(txBuilder *builder.TransactionBuilder, output *iotago.AccountOutput) {
+		t.Helper()
+
+		createdInputs := l.apply(txBuilder.
+			AddBlockIssuanceCreditInput(&iotago.BlockIssuanceCreditInput{AccountID: output.AccountID}).
+			AddOutput(output), 0)
+		require.Len(t, createdInputs, 1)
+
+		account = createdInputs[0]
+	}
+
+	// register
+	commitment := l.LatestCommitment()
+	output, err := builder.NewAccountOutputBuilderFromPrevious(accountOutput).
+		RegisterValidator(testAPI, commitment.Slot, 5_000_000, 100, builder.StakingEarliestEndEpoch(testAPI, commitment.Slot)).
+		Build()
+	require.NoError(t, err)
+
+	transition(l.transactionBuilder().AddInput(account).AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: commitment.MustID()}), output)
+
+	stakingFeature := account.Input.FeatureSet().Staking()
+	require.NotNil(t, stakingFeature)
+	require.EqualValues(t, 5_000_000, stakingFeature.StakedAmount)
+	require.EqualValues(t, 100, stakingFeature.FixedCost)
+	require.Equal(t, builder.StakingStartEpoch(testAPI, commitment.Slot), stakingFeature.StartEpoch)
+	require.Equal(t, stakingFeature.StartEpoch+unbondingPeriod, stakingFeature.EndEpoch)
+
+	// extend
+	commitment = l.LatestCommitment()
+	output, err = builder.NewAccountOutputBuilderFromPrevious(account.Input.(*iotago.AccountOutput)).
+		ExtendStaking(testAPI, commitment.Slot, stakingFeature.EndEpoch+1).
+		Build()
+	require.NoError(t, err)
+
+	transition(l.transactionBuilder().AddInput(account).AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: commitment.MustID()}), output)
+
+	extendedStakingFeature := account.Input.FeatureSet().Staking()
+	require.Equal(t, stakingFeature.StartEpoch, extendedStakingFeature.StartEpoch)
+	require.Equal(t, stakingFeature.EndEpoch+1, extendedStakingFeature.EndEpoch)
+
+	tests := []struct {
+		name    string
+		builder *builder.AccountOutputBuilder
+		err     error
+	}{
+		{
+			name:    "err - register twice",
+			builder: builder.NewAccountOutputBuilderFromPrevious(account.Input.(*iotago.AccountOutput)).RegisterValidator(testAPI, l.LatestCommitment().Slot, 1_000_000, 0, iotago.MaxEpochIndex),
+			err:     builder.ErrStakingOperation,
+		},
+		{
+			name:    "err - register without block issuer feature",
+			builder: builder.NewAccountOutputBuilder(owner, 10_000_000).RegisterValidator(testAPI, l.LatestCommitment().Slot, 1_000_000, 0, iotago.MaxEpochIndex),
+			err:     iotago.ErrStakingBlockIssuerFeatureMissing,
+		},
+		{
+			name:    "err - staked amount exceeds amount",
+			builder: builder.NewAccountOutputBuilderFromPrevious(accountOutput).RegisterValidator(testAPI, l.LatestCommitment().Slot, 10_000_001, 0, iotago.MaxEpochIndex),
+			err:     builder.ErrStakingOperation,
+		},
+		{
+			name:    "err - register with end epoch in unbonding period",
+			builder: builder.NewAccountOutputBuilderFromPrevious(accountOutput).RegisterValidator(testAPI, l.LatestCommitment().Slot, 1_000_000, 0, builder.StakingEarliestEndEpoch(testAPI, l.LatestCommitment().Slot)-1),
+			err:     builder.ErrStakingOperation,
+		},
+		{
+			name:    "err - extend with end epoch in unbonding period",
+			builder: builder.NewAccountOutputBuilderFromPrevious(account.Input.(*iotago.AccountOutput)).ExtendStaking(testAPI, l.LatestCommitment().Slot, builder.StakingEarliestEndEpoch(testAPI, l.LatestCommitment().Slot)-1),
+			err:     builder.ErrStakingOperation,
+		},
+		{
+			name:    "err - extend without staking feature",
+			builder: builder.NewAccountOutputBuilderFromPrevious(accountOutput).ExtendStaking(testAPI, l.LatestCommitment().Slot, iotago.MaxEpochIndex),
+			err:     builder.ErrStakingOperation,
+		},
+		{
+			name:    "err - leave before end epoch",
+			builder: builder.NewAccountOutputBuilderFromPrevious(account.Input.(*iotago.AccountOutput)).LeaveValidator(testAPI, l.LatestCommitment().Slot),
+			err:     builder.ErrStakingOperation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.builder.Build()
+			require.ErrorIs(t, err, test.err)
+		})
+	}
+
+	t.Run("err - claim rewards of an account that isn't staking", func(t *testing.T) {
+		_, err := l.transactionBuilder().
+			ClaimStakingRewards(&builder.TxInput{UnlockTarget: owner, InputID: tpkg.RandOutputID(0), Input: accountOutput}, &api.ManaRewardsResponse{}, l.LatestCommitment().MustID()).
+			Build()
+		require.ErrorIs(t, err, builder.ErrStakingOperation)
+	})
+
+	t.Run("err - different commitment", func(t *testing.T) {
+		_, err := l.transactionBuilder().
+			AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: tpkg.Rand36ByteArray()}).
+			ClaimStakingRewards(account, &api.ManaRewardsResponse{}, l.LatestCommitment().MustID()).
+			Build()
+		require.ErrorIs(t, err, builder.ErrStakingOperation)
+		require.ErrorIs(t, err, builder.ErrTransactionBuilder)
+	})
+
+	// leave once the staking ended and claim the rewards
+	l.AdvanceToEpoch(extendedStakingFeature.EndEpoch + 1)
+	commitment = l.LatestCommitment()
+	require.True(t, builder.StakingFeatureExpired(testAPI, extendedStakingFeature, commitment.Slot))
+
+	output, err = builder.NewAccountOutputBuilderFromPrevious(account.Input.(*iotago.AccountOutput)).
+		LeaveValidator(testAPI, commitment.Slot).
+		Build()
+	require.NoError(t, err)
+
+	l.SetRewards(output.AccountID, 1_000)
+	manaBefore := account.Input.StoredMana()
+	transition(l.transactionBuilder().ClaimStakingRewards(account, &api.ManaRewardsResponse{Rewards: 1_000}, commitment.MustID()), output)
+
+	require.Nil(t, account.Input.FeatureSet().Staking())
+	require.GreaterOrEqual(t, account.Input.StoredMana(), manaBefore+1_000)
+}
@@ -1,13 +1,21 @@
 package builder
 
 import (
+	"math/big"
+
 	"github.com/iotaledger/hive.go/core/safemath"
 	"github.com/iotaledger/hive.go/ierrors"
 	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
 )
 
-// ErrTransactionBuilder defines a generic error occurring within the TransactionBuilder.
-var ErrTransactionBuilder = ierrors.New("transaction builder error")
+var (
+	// ErrTransactionBuilder defines a generic error occurring within the TransactionBuilder.
+	ErrTransactionBuilder = ierrors.New("transaction builder error")
+	// ErrRemainderBelowMinDeposit gets returned if the remaining base tokens don't cover the minimum storage deposit of the remainder outputs.
+	ErrRemainderBelowMinDeposit = ierrors.New("remaining base tokens don't cover the minimum storage deposit of the remainder outputs")
+)
 
 // NewTransactionBuilder creates a new TransactionBuilder.
 func NewTransactionBuilder(api iotago.API, signer iotago.AddressSigner) *TransactionBuilder {
@@ -90,6 +98,23 @@ func (b *TransactionBuilder) AddCommitmentInput(commitmentInput *iotago.Commitme
 	return b
 }
 
+// setCommitmentInput adds the commitment input with the given ID.
+// If the transaction already references a commitment, it needs to be the same one.
+func (b *TransactionBuilder) setCommitmentInput(commitmentID iotago.CommitmentID) error {
+	commitmentInput := b.transaction.CommitmentInput()
+	if commitmentInput == nil {
+		b.AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: commitmentID})
+
+		return nil
+	}
+
+	if commitmentInput.CommitmentID != commitmentID {
+		return ierrors.WithMessagef(ErrTransactionBuilder, "the transaction already references commitment %s instead of %s", commitmentInput.CommitmentID.ToHex(), commitmentID.ToHex())
+	}
+
+	return nil
+}
+
 // AddBlockIssuanceCreditInput adds the given block issuance credit input to the builder.
 func (b *TransactionBuilder) AddBlockIssuanceCreditInput(blockIssuanceCreditInput *iotago.BlockIssuanceCreditInput) *TransactionBuilder {
 	b.transaction.TransactionEssence.ContextInputs = append(b.transaction.TransactionEssence.ContextInputs, blockIssuanceCreditInput)
@@ -362,6 +387,134 @@ func (b *TransactionBuilder) AllotMinRequiredManaAndStoreRemainingManaInOutput(t
 	return b
 }
 
+// AddRemainderOutputs adds the base tokens and native tokens of the inputs that are not deposited into outputs yet
+// as remainder BasicOutputs on the given address. Since a NativeTokenFeature holds a single native token,
+// one remainder output is created per native token, and the remaining base tokens are put on the first one.
+// Mana is not moved to the remainder outputs, use the mana helpers of the builder for that.
+// It fails with ErrRemainderBelowMinDeposit if the remaining base tokens don't cover the minimum storage deposit.
+func (b *TransactionBuilder) AddRemainderOutputs(remainderAddress iotago.Address) *TransactionBuilder {
+	remainingBaseTokens, remainingNativeTokens, err := b.CalculateRemainingBaseTokensAndNativeTokens()
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	remainderOutputs, missingBaseTokens, err := newRemainderOutputs(b.api.StorageScoreStructure(), remainderAddress, remainingBaseTokens, 0, remainingNativeTokens)
+	if err != nil {
+		return b.setBuildError(err)
+	}
+
+	if missingBaseTokens > 0 {
+		return b.setBuildError(ierrors.WithMessagef(ErrRemainderBelowMinDeposit, "remainder of %d base tokens and %d native tokens requires %d base tokens, %d are missing", remainingBaseTokens, len(remainingNativeTokens), remainingBaseTokens+missingBaseTokens, missingBaseTokens))
+	}
+
+	for _, remainderOutput := range remainderOutputs {
+		b.AddOutput(remainderOutput)
+	}
+
+	return b
+}
+
+// CalculateRemainingBaseTokensAndNativeTokens calculates the base tokens and native tokens on the input side
+// that are not deposited into outputs yet. Native tokens that are melted by a foundry in the transaction
+// are not part of the remainder.
+// It will return an error if the outputs hold more base tokens than the inputs.
+func (b *TransactionBuilder) CalculateRemainingBaseTokensAndNativeTokens() (iotago.BaseToken, iotago.NativeTokenSum, error) {
+	inputs := make(iotago.Outputs[iotago.Output], 0, len(b.inputs))
+	for _, input := range b.inputs {
+		inputs = append(inputs, input)
+	}
+
+	var err error
+	var inputBaseTokens, outputBaseTokens iotago.BaseToken
+	for _, input := range inputs {
+		if inputBaseTokens, err = safemath.SafeAdd(inputBaseTokens, input.BaseTokenAmount()); err != nil {
+			return 0, nil, ierrors.Wrap(err, "failed to sum up the base tokens of the inputs")
+		}
+	}
+	for _, output := range b.transaction.Outputs {
+		if outputBaseTokens, err = safemath.SafeAdd(outputBaseTokens, output.BaseTokenAmount()); err != nil {
+			return 0, nil, ierrors.Wrap(err, "failed to sum up the base tokens of the outputs")
+		}
+	}
+
+	if outputBaseTokens > inputBaseTokens {
+		return 0, nil, ierrors.WithMessagef(iotago.ErrInputOutputBaseTokenMismatch, "outputs hold more base tokens than the inputs: %d > %d", outputBaseTokens, inputBaseTokens)
+	}
+
+	inputNativeTokens, err := inputs.NativeTokenSum()
+	if err != nil {
+		return 0, nil, ierrors.Wrap(err, "failed to sum up the native tokens of the inputs")
+	}
+
+	outputNativeTokens, err := b.transaction.Outputs.NativeTokenSum()
+	if err != nil {
+		return 0, nil, ierrors.Wrap(err, "failed to sum up the native tokens of the outputs")
+	}
+
+	meltedNativeTokens, err := b.meltedNativeTokens()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	remainingNativeTokens := make(iotago.NativeTokenSum)
+	for nativeTokenID, inputAmount := range inputNativeTokens {
+		remaining := new(big.Int).Sub(inputAmount, outputNativeTokens.ValueOrBigInt0(nativeTokenID))
+		remaining.Sub(remaining, meltedNativeTokens.ValueOrBigInt0(nativeTokenID))
+
+		// native tokens that are minted in this transaction result in a negative balance, they are not part of the remainder
+		if remaining.Sign() > 0 {
+			remainingNativeTokens[nativeTokenID] = remaining
+		}
+	}
+
+	return inputBaseTokens - outputBaseTokens, remainingNativeTokens, nil
+}
+
+// meltedNativeTokens returns the native tokens that are melted by the foundries transitioned in the transaction.
+func (b *TransactionBuilder) meltedNativeTokens() (iotago.NativeTokenSum, error) {
+	inputFoundries := make(map[iotago.FoundryID]*iotago.FoundryOutput)
+	for _, input := range b.inputs {
+		if foundryInput, isFoundry := input.(*iotago.FoundryOutput); isFoundry {
+			foundryID, err := foundryInput.FoundryID()
+			if err != nil {
+				return nil, ierrors.Wrap(err, "failed to compute foundry ID of input")
+			}
+			inputFoundries[foundryID] = foundryInput
+		}
+	}
+
+	meltedNativeTokens := make(iotago.NativeTokenSum)
+	for _, output := range b.transaction.Outputs {
+		foundryOutput, isFoundry := output.(*iotago.FoundryOutput)
+		if !isFoundry {
+			continue
+		}
+
+		foundryID, err := foundryOutput.FoundryID()
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to compute foundry ID of output")
+		}
+
+		foundryInput, exists := inputFoundries[foundryID]
+		if !exists {
+			// newly created foundries didn't melt anything yet
+			continue
+		}
+
+		currentTokenScheme, isCurrentSimple := foundryInput.TokenScheme.(*iotago.SimpleTokenScheme)
+		nextTokenScheme, isNextSimple := foundryOutput.TokenScheme.(*iotago.SimpleTokenScheme)
+		if !isCurrentSimple || !isNextSimple {
+			continue
+		}
+
+		if melted := new(big.Int).Sub(nextTokenScheme.MeltedTokens, currentTokenScheme.MeltedTokens); melted.Sign() > 0 {
+			meltedNativeTokens[foundryID] = melted
+		}
+	}
+
+	return meltedNativeTokens, nil
+}
+
 // CalculateAvailableManaRemaining calculates the available mana on the input side, subtracts all the mana on the output side
 // and on the allotments and returns the remaining mana. It takes the account bound mana into consideration.
 // It will return an error if there is not enough mana available.
@@ -483,6 +636,34 @@ func (b *TransactionBuilder) BuildAndSwapToBlockBuilder(txFunc TransactionFunc)
 	return blockBuilder.Payload(tx)
 }
 
+// BuildAndSimulate builds the transaction and simulates its execution with the Nova VM locally,
+// so semantic errors are detected before the transaction is sent to a node.
+// The inputs of the builder are used as the resolved UTXO inputs, the commitment, block issuance credit
+// and rewards inputs need to be given by the caller. The returned SimulationResult tells which step failed.
+func (b *TransactionBuilder) BuildAndSimulate(commitmentInput vm.VMCommitmentInput, bicInputSet vm.BlockIssuanceCreditInputSet, rewardsInputSet vm.RewardsInputSet) (*iotago.SignedTransaction, *nova.SimulationResult, error) {
+	tx, err := b.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inputSet := make(vm.InputSet, len(b.inputs))
+	for inputID, input := range b.inputs {
+		inputSet[inputID] = input
+	}
+
+	simulationResult, err := nova.Simulate(tx, vm.ResolvedInputs{
+		InputSet:                    inputSet,
+		BlockIssuanceCreditInputSet: bicInputSet,
+		CommitmentInput:             commitmentInput,
+		RewardsInputSet:             rewardsInputSet,
+	})
+	if err != nil {
+		return tx, simulationResult, ierrors.Wrap(err, "transaction simulation failed")
+	}
+
+	return tx, simulationResult, nil
+}
+
 type AvailableManaResult struct {
 	TotalMana            iotago.Mana
 	UnboundMana          iotago.Mana
@@ -670,6 +851,13 @@ func (b *TransactionBuilder) Build() (*iotago.SignedTransaction, error) {
 	return b.build(true)
 }
 
+// BuildUnsigned returns the built payload with the empty signatures of the signer instead of actual signatures.
+// The unlocks have the same layout as the ones of a signed payload, so it can be used to hand the transaction
+// over to the holder of the keys, e.g. if the signer is watch-only.
+func (b *TransactionBuilder) BuildUnsigned() (*iotago.SignedTransaction, error) {
+	return b.build(false)
+}
+
 // build adds a signature and returns the built payload.
 // Depending on the value of "signEssence" it either signs the essence or adds empty signatures.
 func (b *TransactionBuilder) build(signEssence bool) (*iotago.SignedTransaction, error) {
@@ -698,26 +886,19 @@ func (b *TransactionBuilder) build(signEssence bool) (*iotago.SignedTransaction,
 	}
 
 	unlockedSet := &txBuilderUnlockedSet{
-		unlocks:        iotago.Unlocks{},
-		signerUIDs:     map[iotago.Identifier]int{},
-		unlockedChains: map[string]int{},
-	}
-
-	// resolveUnderlyingAddress returns the underlying address in case of a restricted address.
-	// this way we handle restricted addresses like normal addresses in the unlock logic.
-	resolveUnderlyingAddress := func(addr iotago.Address) iotago.Address {
-		switch addr := addr.(type) {
-		case *iotago.RestrictedAddress:
-			return addr.Address
-		default:
-			return addr
-		}
+		unlocks:               iotago.Unlocks{},
+		signerUIDs:            map[iotago.Identifier]int{},
+		multiUnlockSignerUIDs: map[iotago.Identifier]int{},
+		unlockedChains:        map[string]int{},
+		unlockedMultiAddrs:    map[string]int{},
 	}
 
 	for inputIndex, inputRef := range b.transaction.TransactionEssence.Inputs {
 		//nolint:forcetypeassert // we can safely assume that this is an UTXOInput
 		owner := b.inputOwner[inputRef.(*iotago.UTXOInput).OutputID()]
-		owner = resolveUnderlyingAddress(owner)
+
+		// we handle restricted addresses like normal addresses in the unlock logic.
+		owner = ResolveUnderlyingAddress(owner)
 
 		chainAddr, isChainAddress := owner.(iotago.ChainAddress)
 		if isChainAddress {
@@ -738,9 +919,20 @@ func (b *TransactionBuilder) build(signEssence bool) (*iotago.SignedTransaction,
 			continue
 		}
 
+		if multiAddr, isMultiAddress := owner.(*iotago.MultiAddress); isMultiAddress {
+			if err := b.unlockMultiAddress(unlockedSet, multiAddr, inputIndex, txEssenceData, signEssence); err != nil {
+				return nil, err
+			}
+
+			// always mark the chain as unlocked in case the output is a chain output
+			// e.g. "an NFT owned by a multi address".
+			unlockedSet.addChainAsUnlocked(inputs[inputIndex], inputIndex)
+
+			continue
+		}
+
 		if _, isDirectUnlockable := owner.(iotago.DirectUnlockableAddress); !isDirectUnlockable {
-			// we only support directly unlockable addresses in the transaction builder for now
-			return nil, ierrors.Errorf("input %d's owning address is not directly unlockable, address %s, type %s", inputIndex, owner.Bech32(b.api.ProtocolParameters().Bech32HRP()), owner.Type())
+			return nil, ierrors.Errorf("input %d's owning address is not unlockable, address %s, type %s", inputIndex, owner.Bech32(b.api.ProtocolParameters().Bech32HRP()), owner.Type())
 		}
 
 		// get the signer UID for the directly unlockable address
@@ -751,18 +943,14 @@ func (b *TransactionBuilder) build(signEssence bool) (*iotago.SignedTransaction,
 
 		unlockedAtIndex, alreadyUnlocked := unlockedSet.signerUIDs[signerUID]
 		if !alreadyUnlocked {
-			var err error
-			var signature iotago.Signature
-			if signEssence {
-				// sign the tx essence data
-				signature, err = b.signer.Sign(owner, txEssenceData)
-			} else {
-				// sign with empty signature.
-				// this is used for example to calculate the workscore of the transaction before the actual signing.
-				signature, err = b.signer.EmptySignatureForAddress(owner)
+			// signatures inside of a MultiUnlock can't be referenced and must not be reused in a SignatureUnlock.
+			if multiUnlockIndex, usedInMultiUnlock := unlockedSet.multiUnlockSignerUIDs[signerUID]; usedInMultiUnlock {
+				return nil, ierrors.Errorf("input %d's owning address %s was already signed inside the multi unlock at index %d, the input needs to be placed before the multi address input", inputIndex, owner.Bech32(b.api.ProtocolParameters().Bech32HRP()), multiUnlockIndex)
 			}
+
+			signature, err := b.sign(owner, txEssenceData, signEssence)
 			if err != nil {
-				return nil, ierrors.Wrapf(err, "failed to sign transaction")
+				return nil, err
 			}
 
 			// add the new signature to the unlocks
@@ -789,14 +977,160 @@ func (b *TransactionBuilder) build(signEssence bool) (*iotago.SignedTransaction,
 	return sigTxPayload, nil
 }
 
+// ResolveUnderlyingAddress returns the underlying address in case of a restricted address, otherwise the address itself.
+func ResolveUnderlyingAddress(addr iotago.Address) iotago.Address {
+	switch addr := addr.(type) {
+	case *iotago.RestrictedAddress:
+		return addr.Address
+	default:
+		return addr
+	}
+}
+
+// sign signs the tx essence data for the given address.
+// Depending on the value of "signEssence" it either signs the essence or returns an empty signature.
+func (b *TransactionBuilder) sign(addr iotago.Address, txEssenceData []byte, signEssence bool) (iotago.Signature, error) {
+	if !signEssence {
+		// sign with empty signature.
+		// this is used for example to calculate the workscore of the transaction before the actual signing.
+		signature, err := b.signer.EmptySignatureForAddress(addr)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to sign transaction")
+		}
+
+		return signature, nil
+	}
+
+	// sign the tx essence data
+	signature, err := b.signer.Sign(addr, txEssenceData)
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "failed to sign transaction")
+	}
+
+	return signature, nil
+}
+
+// unlockMultiAddress adds the unlock for the input at "inputIndex" which is owned by the given MultiAddress.
+// If the MultiAddress was already unlocked by a former input, a ReferenceUnlock to that input is added.
+// Otherwise a MultiUnlock is added, which contains referential unlocks for the addresses that were already unlocked
+// by former inputs, SignatureUnlocks for the addresses the signer holds keys for until the threshold is reached,
+// and EmptyUnlocks for all remaining addresses.
+func (b *TransactionBuilder) unlockMultiAddress(unlockedSet *txBuilderUnlockedSet, multiAddr *iotago.MultiAddress, inputIndex int, txEssenceData []byte, signEssence bool) error {
+	if unlockedAtIndex, isUnlocked := unlockedSet.unlockedMultiAddrs[multiAddr.Key()]; isUnlocked {
+		// add a referential unlock to the former unlock position
+		unlockedSet.addReferentialUnlock(multiAddr, unlockedAtIndex)
+
+		return nil
+	}
+
+	unlocks := make([]iotago.Unlock, len(multiAddr.Addresses))
+	signerUIDs := make(map[int]iotago.Identifier)
+	var cumulativeWeight uint16
+
+	// add referential unlocks for all addresses that were already unlocked by former inputs,
+	// and remember the addresses that could still be signed by the signer.
+	for addrIndex, addrWithWeight := range multiAddr.Addresses {
+		switch addr := addrWithWeight.Address.(type) {
+		case iotago.ChainAddress:
+			unlockedAtIndex, isUnlocked := unlockedSet.isChainUnlocked(addr)
+			if !isUnlocked {
+				// chains can't be unlocked by a signature
+				continue
+			}
+			unlocks[addrIndex] = newReferentialUnlock(addr, unlockedAtIndex)
+			cumulativeWeight += uint16(addrWithWeight.Weight)
+
+		case iotago.DirectUnlockableAddress:
+			signerUID, err := b.signer.SignerUIDForAddress(addr)
+			if err != nil {
+				if ierrors.Is(err, iotago.ErrAddressKeysNotMapped) {
+					// the signer doesn't hold the keys for this address, so another signer needs to reach the threshold
+					continue
+				}
+
+				return ierrors.Wrapf(err, "failed to get signer UID for address %s", addr.Bech32(b.api.ProtocolParameters().Bech32HRP()))
+			}
+
+			unlockedAtIndex, isUnlocked := unlockedSet.signerUIDs[signerUID]
+			if !isUnlocked {
+				signerUIDs[addrIndex] = signerUID
+				continue
+			}
+			unlocks[addrIndex] = newReferentialUnlock(addr, unlockedAtIndex)
+			cumulativeWeight += uint16(addrWithWeight.Weight)
+
+		default:
+			return ierrors.Errorf("input %d's owning multi address contains an unsupported address, address %s, type %s", inputIndex, addr.Bech32(b.api.ProtocolParameters().Bech32HRP()), addr.Type())
+		}
+	}
+
+	// sign with the remaining addresses until the threshold is reached
+	for addrIndex, addrWithWeight := range multiAddr.Addresses {
+		if cumulativeWeight >= multiAddr.Threshold {
+			break
+		}
+
+		signerUID, isSignable := signerUIDs[addrIndex]
+		if !isSignable {
+			continue
+		}
+
+		signature, err := b.sign(addrWithWeight.Address, txEssenceData, signEssence)
+		if err != nil {
+			return err
+		}
+		unlocks[addrIndex] = &iotago.SignatureUnlock{Signature: signature}
+		cumulativeWeight += uint16(addrWithWeight.Weight)
+
+		// remember the first multi unlock the signer UID was used in
+		if _, exists := unlockedSet.multiUnlockSignerUIDs[signerUID]; !exists {
+			unlockedSet.multiUnlockSignerUIDs[signerUID] = inputIndex
+		}
+	}
+
+	if cumulativeWeight < multiAddr.Threshold {
+		return ierrors.WithMessagef(iotago.ErrMultiAddressUnlockThresholdNotReached, "input %d's owning multi address %s can't be unlocked, the weight of the unlockable addresses is below the threshold %d < %d", inputIndex, multiAddr.Bech32(b.api.ProtocolParameters().Bech32HRP()), cumulativeWeight, multiAddr.Threshold)
+	}
+
+	// the remaining addresses are not needed to reach the threshold
+	for addrIndex, unlock := range unlocks {
+		if unlock == nil {
+			unlocks[addrIndex] = &iotago.EmptyUnlock{}
+		}
+	}
+
+	unlockedSet.addUnlock(&iotago.MultiUnlock{Unlocks: unlocks})
+	unlockedSet.unlockedMultiAddrs[multiAddr.Key()] = inputIndex
+
+	return nil
+}
+
+// newReferentialUnlock returns the referential unlock for the given address that references "referencedInputIndex".
+func newReferentialUnlock(addr iotago.Address, referencedInputIndex int) iotago.Unlock {
+	switch addr.(type) {
+	case *iotago.AccountAddress:
+		return &iotago.AccountUnlock{Reference: uint16(referencedInputIndex)}
+	case *iotago.AnchorAddress:
+		return &iotago.AnchorUnlock{Reference: uint16(referencedInputIndex)}
+	case *iotago.NFTAddress:
+		return &iotago.NFTUnlock{Reference: uint16(referencedInputIndex)}
+	default:
+		return &iotago.ReferenceUnlock{Reference: uint16(referencedInputIndex)}
+	}
+}
+
 // txBuilderUnlockedSet is a helper struct to keep track of the unlocked inputs and their positions.
 type txBuilderUnlockedSet struct {
 	// unlocks holds the unlocks for the tx inputs.
 	unlocks iotago.Unlocks
 	// signerUIDs maps unique signer UIDs to the position of the unlock in the unlocks slice.
 	signerUIDs map[iotago.Identifier]int
+	// multiUnlockSignerUIDs maps signer UIDs used inside of multi unlocks to the position of the first multi unlock.
+	multiUnlockSignerUIDs map[iotago.Identifier]int
 	// unlockedChains maps the chain address key to the position of the unlock in the unlocks slice.
 	unlockedChains map[string]int
+	// unlockedMultiAddrs maps the multi address key to the position of the unlock in the unlocks slice.
+	unlockedMultiAddrs map[string]int
 }
 
 // addUnlock adds the given unlock to the set.
@@ -806,16 +1140,7 @@ func (u *txBuilderUnlockedSet) addUnlock(unlock iotago.Unlock) {
 
 // addReferentialUnlock adds a referential unlock to the set.
 func (u *txBuilderUnlockedSet) addReferentialUnlock(addr iotago.Address, referencedInputIndex int) {
-	switch addr.(type) {
-	case *iotago.AccountAddress:
-		u.addUnlock(&iotago.AccountUnlock{Reference: uint16(referencedInputIndex)})
-	case *iotago.AnchorAddress:
-		u.addUnlock(&iotago.AnchorUnlock{Reference: uint16(referencedInputIndex)})
-	case *iotago.NFTAddress:
-		u.addUnlock(&iotago.NFTUnlock{Reference: uint16(referencedInputIndex)})
-	default:
-		u.addUnlock(&iotago.ReferenceUnlock{Reference: uint16(referencedInputIndex)})
-	}
+	u.addUnlock(newReferentialUnlock(addr, referencedInputIndex))
 }
 
 // addSignerUID marks the given signer UID as unlocked at "unlockedAtIndex".
@@ -3,14 +3,19 @@ package builder_test
 
 import (
 	"crypto/ed25519"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/serializer/v2/serix"
 	iotago "github.com/iotaledger/iota.go/v4"
 	"github.com/iotaledger/iota.go/v4/builder"
 	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
 )
 
 func TestTransactionBuilder(t *testing.T) {
@@ -278,3 +283,381 @@ func TestTransactionBuilder(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionBuilderMultiAddress(t *testing.T) {
+	addresses, addressKeys := tpkg.RandEd25519IdentitiesSortedByAddress(3)
+
+	// 2-of-3 multi address with equal weights
+	multiAddr := iotago.NewMultiAddress(iotago.AddressesWithWeight{
+		{Address: addresses[0], Weight: 1},
+		{Address: addresses[1], Weight: 1},
+		{Address: addresses[2], Weight: 1},
+	}, 2)
+
+	output := &iotago.BasicOutput{
+		Amount: 50,
+		UnlockConditions: iotago.BasicOutputUnlockConditions{
+			&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()},
+		},
+	}
+
+	type test struct {
+		name            string
+		builder         *builder.TransactionBuilder
+		inputs          vm.InputSet
+		expectedUnlocks iotago.Unlocks
+		buildErr        error
+	}
+
+	newInput := func(addr iotago.Address) (iotago.OutputID, iotago.Output) {
+		return tpkg.RandOutputID(0), &iotago.BasicOutput{
+			Amount:           1000,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr}},
+		}
+	}
+
+	tests := []*test{
+		// ok - threshold reached with two signatures, the third address is skipped
+		func() *test {
+			inputID, input := newInput(multiAddr)
+
+			bdl := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, iotago.NewInMemoryAddressSigner(addressKeys...)).
+				AddInput(&builder.TxInput{UnlockTarget: multiAddr, InputID: inputID, Input: input}).
+				AddOutput(output)
+
+			return &test{
+				name:    "ok - threshold reached with two signatures",
+				builder: bdl,
+				inputs:  vm.InputSet{inputID: input},
+				expectedUnlocks: iotago.Unlocks{
+					&iotago.MultiUnlock{Unlocks: []iotago.Unlock{&iotago.SignatureUnlock{}, &iotago.SignatureUnlock{}, &iotago.EmptyUnlock{}}},
+				},
+			}
+		}(),
+
+		// ok - signer only holds the keys of two addresses
+		func() *test {
+			inputID, input := newInput(multiAddr)
+
+			bdl := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, iotago.NewInMemoryAddressSigner(addressKeys[1], addressKeys[2])).
+				AddInput(&builder.TxInput{UnlockTarget: multiAddr, InputID: inputID, Input: input}).
+				AddOutput(output)
+
+			return &test{
+				name:    "ok - signer only holds the keys of two addresses",
+				builder: bdl,
+				inputs:  vm.InputSet{inputID: input},
+				expectedUnlocks: iotago.Unlocks{
+					&iotago.MultiUnlock{Unlocks: []iotago.Unlock{&iotago.EmptyUnlock{}, &iotago.SignatureUnlock{}, &iotago.SignatureUnlock{}}},
+				},
+			}
+		}(),
+
+		// ok - signature unlock before the multi unlock is referenced, and the multi unlock is referenced by the second multi input
+		func() *test {
+			inputID1, input1 := newInput(addresses[0])
+			inputID2, input2 := newInput(multiAddr)
+			inputID3, input3 := newInput(multiAddr)
+
+			bdl := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, iotago.NewInMemoryAddressSigner(addressKeys...)).
+				AddInput(&builder.TxInput{UnlockTarget: addresses[0], InputID: inputID1, Input: input1}).
+				AddInput(&builder.TxInput{UnlockTarget: multiAddr, InputID: inputID2, Input: input2}).
+				AddInput(&builder.TxInput{UnlockTarget: multiAddr, InputID: inputID3, Input: input3}).
+				AddOutput(output)
+
+			return &test{
+				name:    "ok - signature and multi unlocks are referenced",
+				builder: bdl,
+				inputs:  vm.InputSet{inputID1: input1, inputID2: input2, inputID3: input3},
+				expectedUnlocks: iotago.Unlocks{
+					&iotago.SignatureUnlock{},
+					&iotago.MultiUnlock{Unlocks: []iotago.Unlock{&iotago.ReferenceUnlock{Reference: 0}, &iotago.SignatureUnlock{}, &iotago.EmptyUnlock{}}},
+					&iotago.ReferenceUnlock{Reference: 1},
+				},
+			}
+		}(),
+
+		// ok - account address inside the multi address is unlocked by a former input
+		func() *test {
+			inputID1 := tpkg.RandOutputID(0)
+			accountInput := &iotago.AccountOutput{
+				Amount:    1000,
+				AccountID: tpkg.RandAccountID(),
+				UnlockConditions: iotago.AccountOutputUnlockConditions{
+					&iotago.AddressUnlockCondition{Address: addresses[0]},
+				},
+			}
+
+			accountMultiAddr := iotago.NewMultiAddress(iotago.AddressesWithWeight{
+				{Address: addresses[1], Weight: 1},
+				{Address: accountInput.AccountID.ToAddress(), Weight: 1},
+			}, 2)
+			accountMultiAddr.Addresses.Sort()
+
+			inputID2, input2 := newInput(accountMultiAddr)
+
+			bdl := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, iotago.NewInMemoryAddressSigner(addressKeys...)).
+				AddInput(&builder.TxInput{UnlockTarget: addresses[0], InputID: inputID1, Input: accountInput}).
+				AddInput(&builder.TxInput{UnlockTarget: accountMultiAddr, InputID: inputID2, Input: input2}).
+				AddOutput(accountInput.Clone()).
+				AddOutput(output)
+
+			return &test{
+				name:    "ok - account address inside the multi address",
+				builder: bdl,
+				inputs:  vm.InputSet{inputID1: accountInput, inputID2: input2},
+			}
+		}(),
+
+		// err - threshold not reached
+		func() *test {
+			inputID, input := newInput(multiAddr)
+
+			bdl := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, iotago.NewInMemoryAddressSigner(addressKeys[0])).
+				AddInput(&builder.TxInput{UnlockTarget: multiAddr, InputID: inputID, Input: input}).
+				AddOutput(output)
+
+			return &test{
+				name:     "err - threshold not reached",
+				builder:  bdl,
+				buildErr: iotago.ErrMultiAddressUnlockThresholdNotReached,
+			}
+		}(),
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx, err := test.builder.Build()
+			if test.buildErr != nil {
+				require.ErrorIs(t, err, test.buildErr)
+
+				return
+			}
+			require.NoError(t, err)
+
+			if test.expectedUnlocks != nil {
+				require.Len(t, tx.Unlocks, len(test.expectedUnlocks))
+				for i, expectedUnlock := range test.expectedUnlocks {
+					require.Equal(t, expectedUnlock.Type(), tx.Unlocks[i].Type())
+
+					if expectedMultiUnlock, isMultiUnlock := expectedUnlock.(*iotago.MultiUnlock); isMultiUnlock {
+						//nolint:forcetypeassert // we checked the type above
+						multiUnlock := tx.Unlocks[i].(*iotago.MultiUnlock)
+						require.Len(t, multiUnlock.Unlocks, len(expectedMultiUnlock.Unlocks))
+						for j, expectedSubUnlock := range expectedMultiUnlock.Unlocks {
+							require.Equal(t, expectedSubUnlock.Type(), multiUnlock.Unlocks[j].Type())
+						}
+					}
+				}
+			}
+
+			_, err = tpkg.ZeroCostTestAPI.Encode(tx, serix.WithValidation())
+			require.NoError(t, err)
+
+			_, err = vm.ValidateUnlocks(tx, vm.ResolvedInputs{InputSet: test.inputs})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTransactionBuilderRemainderOutputs(t *testing.T) {
+	api := iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+
+	inputAddr, addressKeys := tpkg.RandEd25519IdentitiesSortedByAddress(1)
+	remainderAddr := tpkg.RandEd25519Address()
+	nativeTokenID1 := tpkg.RandNativeTokenID()
+	nativeTokenID2 := tpkg.RandNativeTokenID()
+
+	minDeposit, err := api.StorageScoreStructure().MinDeposit(&iotago.BasicOutput{
+		UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: remainderAddr}},
+	})
+	require.NoError(t, err)
+
+	newInput := func(index uint16, amount iotago.BaseToken, nativeToken *iotago.NativeTokenFeature) (iotago.OutputID, *iotago.BasicOutput) {
+		input := &iotago.BasicOutput{
+			Amount:           amount,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: inputAddr[0]}},
+			Features:         iotago.BasicOutputFeatures{},
+		}
+		if nativeToken != nil {
+			input.Features.Upsert(nativeToken)
+		}
+
+		return tpkg.RandOutputIDWithCreationSlot(0, index), input
+	}
+
+	newOutput := func(amount iotago.BaseToken) *iotago.BasicOutput {
+		return &iotago.BasicOutput{
+			Amount:           amount,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()}},
+		}
+	}
+
+	type test struct {
+		name               string
+		inputs             vm.InputSet
+		outputs            iotago.TxEssenceOutputs
+		expectedRemainders []*iotago.BasicOutput
+		buildErr           error
+	}
+
+	tests := []*test{
+		func() *test {
+			inputID, input := newInput(0, 10*minDeposit, nil)
+
+			return &test{
+				name:    "ok - base tokens only",
+				inputs:  vm.InputSet{inputID: input},
+				outputs: iotago.TxEssenceOutputs{newOutput(4 * minDeposit)},
+				expectedRemainders: []*iotago.BasicOutput{
+					{Amount: 6 * minDeposit},
+				},
+			}
+		}(),
+		func() *test {
+			inputID, input := newInput(0, 10*minDeposit, nil)
+
+			return &test{
+				name:               "ok - nothing remains",
+				inputs:             vm.InputSet{inputID: input},
+				outputs:            iotago.TxEssenceOutputs{newOutput(10 * minDeposit)},
+				expectedRemainders: []*iotago.BasicOutput{},
+			}
+		}(),
+		func() *test {
+			inputID1, input1 := newInput(0, 10*minDeposit, &iotago.NativeTokenFeature{ID: nativeTokenID1, Amount: big.NewInt(100)})
+			inputID2, input2 := newInput(1, 10*minDeposit, &iotago.NativeTokenFeature{ID: nativeTokenID2, Amount: big.NewInt(50)})
+
+			output := newOutput(5 * minDeposit)
+			output.Features = iotago.BasicOutputFeatures{&iotago.NativeTokenFeature{ID: nativeTokenID1, Amount: big.NewInt(30)}}
+
+			return &test{
+				name:    "ok - native tokens are split across remainder outputs",
+				inputs:  vm.InputSet{inputID1: input1, inputID2: input2},
+				outputs: iotago.TxEssenceOutputs{output},
+				// the amounts are checked to sum up correctly, so only the native tokens are set here
+				expectedRemainders: []*iotago.BasicOutput{
+					{Features: iotago.BasicOutputFeatures{&iotago.NativeTokenFeature{ID: nativeTokenID1, Amount: big.NewInt(70)}}},
+					{Features: iotago.BasicOutputFeatures{&iotago.NativeTokenFeature{ID: nativeTokenID2, Amount: big.NewInt(50)}}},
+				},
+			}
+		}(),
+		func() *test {
+			inputID, input := newInput(0, 10*minDeposit, nil)
+
+			return &test{
+				name:     "err - remainder below min deposit",
+				inputs:   vm.InputSet{inputID: input},
+				outputs:  iotago.TxEssenceOutputs{newOutput(10*minDeposit - 1)},
+				buildErr: builder.ErrRemainderBelowMinDeposit,
+			}
+		}(),
+		func() *test {
+			inputID, input := newInput(0, minDeposit, &iotago.NativeTokenFeature{ID: nativeTokenID1, Amount: big.NewInt(100)})
+
+			return &test{
+				name:     "err - native token remainder below min deposit",
+				inputs:   vm.InputSet{inputID: input},
+				outputs:  iotago.TxEssenceOutputs{newOutput(minDeposit)},
+				buildErr: builder.ErrRemainderBelowMinDeposit,
+			}
+		}(),
+		func() *test {
+			inputID, input := newInput(0, minDeposit, nil)
+
+			return &test{
+				name:     "err - outputs exceed inputs",
+				inputs:   vm.InputSet{inputID: input},
+				outputs:  iotago.TxEssenceOutputs{newOutput(2 * minDeposit)},
+				buildErr: iotago.ErrInputOutputBaseTokenMismatch,
+			}
+		}(),
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bdl := builder.NewTransactionBuilder(api, iotago.NewInMemoryAddressSigner(addressKeys...))
+			for inputID, input := range test.inputs {
+				bdl.AddInput(&builder.TxInput{UnlockTarget: inputAddr[0], InputID: inputID, Input: input})
+			}
+			for _, output := range test.outputs {
+				bdl.AddOutput(output)
+			}
+
+			tx, err := bdl.AddRemainderOutputs(remainderAddr).Build()
+			if test.buildErr != nil {
+				require.ErrorIs(t, err, test.buildErr)
+
+				return
+			}
+			require.NoError(t, err)
+
+			remainders := tx.Transaction.Outputs[len(test.outputs):]
+			require.Len(t, remainders, len(test.expectedRemainders))
+
+			expectedNativeTokens := make([]*iotago.NativeTokenFeature, 0)
+			remainderNativeTokens := make([]*iotago.NativeTokenFeature, 0)
+			for i, expectedRemainder := range test.expectedRemainders {
+				remainder := remainders[i].(*iotago.BasicOutput)
+				require.True(t, remainder.UnlockConditionSet().Address().Address.Equal(remainderAddr))
+				if expectedRemainder.Amount != 0 {
+					require.Equal(t, expectedRemainder.Amount, remainder.Amount)
+				}
+
+				if nativeToken := expectedRemainder.FeatureSet().NativeToken(); nativeToken != nil {
+					expectedNativeTokens = append(expectedNativeTokens, nativeToken)
+				}
+				if nativeToken := remainder.FeatureSet().NativeToken(); nativeToken != nil {
+					remainderNativeTokens = append(remainderNativeTokens, nativeToken)
+				}
+
+				_, err := api.StorageScoreStructure().CoversMinDeposit(remainder, remainder.Amount)
+				require.NoError(t, err)
+			}
+			// the remainder outputs are sorted by native token ID, which is random in this test
+			require.ElementsMatch(t, expectedNativeTokens, remainderNativeTokens)
+
+			resolvedInputs := vm.ResolvedInputs{InputSet: test.inputs}
+			unlockedAddrs, err := nova.NewVirtualMachine().ValidateUnlocks(tx, resolvedInputs)
+			require.NoError(t, err)
+
+			_, err = nova.NewVirtualMachine().Execute(tx.Transaction, resolvedInputs, unlockedAddrs)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTransactionBuilderBuildAndSimulate(t *testing.T) {
+	_, inputAddr, addressKeys := tpkg.RandEd25519Identity()
+
+	inputID := tpkg.RandOutputIDWithCreationSlot(0, 0)
+	input := &iotago.BasicOutput{
+		Amount:           1000,
+		UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: inputAddr}},
+	}
+
+	newBuilder := func(outputAmount iotago.BaseToken) *builder.TransactionBuilder {
+		return builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, iotago.NewInMemoryAddressSigner(addressKeys)).
+			AddInput(&builder.TxInput{UnlockTarget: inputAddr, InputID: inputID, Input: input}).
+			AddOutput(&iotago.BasicOutput{
+				Amount:           outputAmount,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()}},
+			})
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		tx, result, err := newBuilder(1000).BuildAndSimulate(nil, nil, nil)
+		require.NoError(t, err)
+		require.NotNil(t, tx)
+		require.True(t, result.Succeeded())
+		require.Len(t, result.Outputs, 1)
+	})
+
+	t.Run("fail - unbalanced base tokens", func(t *testing.T) {
+		tx, result, err := newBuilder(500).BuildAndSimulate(nil, nil, nil)
+		require.ErrorIs(t, err, iotago.ErrInputOutputBaseTokenMismatch)
+		require.NotNil(t, tx)
+		require.False(t, result.Succeeded())
+		require.Equal(t, "ExecFuncBalancedBaseTokens", result.FailedExecFunc)
+	})
+}
@@ -1,5 +1,7 @@
 package iotago
 
+import "fmt"
+
 // ChainOutput is a type of Output which represents a chain of state transitions.
 type ChainOutput interface {
 	Output
@@ -26,5 +28,15 @@ const (
 	ChainTransitionTypeDestroy
 )
 
+var chainTransitionTypeNames = [ChainTransitionTypeDestroy + 1]string{"Genesis", "StateChange", "Destroy"}
+
+func (chainTransType ChainTransitionType) String() string {
+	if int(chainTransType) >= len(chainTransitionTypeNames) {
+		return fmt.Sprintf("unknown chain transition type: %d", chainTransType)
+	}
+
+	return chainTransitionTypeNames[chainTransType]
+}
+
 // ChainOutputSet is a map of ChainID to ChainOutput.
 type ChainOutputSet map[ChainID]ChainOutput
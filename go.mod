@@ -3,6 +3,7 @@ module github.com/iotaledger/iota.go/v4
 go 1.22
 
 require (
+	filippo.io/edwards25519 v1.1.0
 	github.com/eclipse/paho.mqtt.golang v1.4.3
 	github.com/ethereum/go-ethereum v1.13.14
 	github.com/holiman/uint256 v1.2.4
@@ -19,11 +20,11 @@ require (
 	github.com/samber/lo v1.39.0
 	github.com/stretchr/testify v1.9.0
 	golang.org/x/crypto v0.21.0
+	golang.org/x/text v0.14.0
 	gopkg.in/h2non/gock.v1 v1.1.2
 )
 
 require (
-	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/btcsuite/btcd/btcec/v2 v2.3.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.2.0 // indirect
@@ -38,6 +39,5 @@ require (
 	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sync v0.6.0 // indirect
 	golang.org/x/sys v0.18.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
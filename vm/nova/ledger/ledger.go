@@ -0,0 +1,414 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/serializer/v2/serix"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+var (
+	// ErrInputNotFound gets returned if an input of a transaction doesn't exist in the ledger.
+	ErrInputNotFound = ierrors.New("input not found in the ledger")
+	// ErrAccountNotFound gets returned if an account referenced by a transaction doesn't exist in the ledger.
+	ErrAccountNotFound = ierrors.New("account not found in the ledger")
+	// ErrTransactionCreatedInFuture gets returned if the creation slot of a transaction is after the current slot of the ledger.
+	ErrTransactionCreatedInFuture = ierrors.New("transaction creation slot is after the current slot")
+)
+
+// Ledger is an in-memory ledger which applies transactions with the Nova VirtualMachine.
+// It keeps track of the unspent outputs, the current slot, the produced commitments and the
+// block issuance credits of the accounts, which makes it usable as a deterministic sandbox for end-to-end flows.
+type Ledger struct {
+	api iotago.API
+
+	currentSlot         iotago.SlotIndex
+	commitments         map[iotago.SlotIndex]*iotago.Commitment
+	commitmentsByID     map[iotago.CommitmentID]*iotago.Commitment
+	latestCommitment    *iotago.Commitment
+	acceptedTxsBySlot   map[iotago.SlotIndex][]iotago.TransactionID
+	unspentOutputs      iotago.OutputSet
+	spentOutputs        iotago.OutputSet
+	blockIssuanceCredit vm.BlockIssuanceCreditInputSet
+	rewards             vm.RewardsInputSet
+	genesisOutputsCount uint32
+
+	mutex sync.RWMutex
+}
+
+// New creates a new Ledger with the given API, starting at the genesis slot.
+func New(api iotago.API) *Ledger {
+	genesisCommitment := iotago.NewEmptyCommitment(api)
+
+	l := &Ledger{
+		api:                 api,
+		currentSlot:         api.ProtocolParameters().GenesisSlot(),
+		commitments:         make(map[iotago.SlotIndex]*iotago.Commitment),
+		commitmentsByID:     make(map[iotago.CommitmentID]*iotago.Commitment),
+		latestCommitment:    genesisCommitment,
+		acceptedTxsBySlot:   make(map[iotago.SlotIndex][]iotago.TransactionID),
+		unspentOutputs:      make(iotago.OutputSet),
+		spentOutputs:        make(iotago.OutputSet),
+		blockIssuanceCredit: make(vm.BlockIssuanceCreditInputSet),
+		rewards:             make(vm.RewardsInputSet),
+	}
+
+	l.commitments[genesisCommitment.Slot] = genesisCommitment
+	l.commitmentsByID[genesisCommitment.MustID()] = genesisCommitment
+
+	return l
+}
+
+// API returns the API the ledger operates with.
+func (l *Ledger) API() iotago.API {
+	return l.api
+}
+
+// CurrentSlot returns the slot in which transactions are currently applied.
+func (l *Ledger) CurrentSlot() iotago.SlotIndex {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.currentSlot
+}
+
+// CurrentEpoch returns the epoch of the current slot.
+func (l *Ledger) CurrentEpoch() iotago.EpochIndex {
+	return l.api.TimeProvider().EpochFromSlot(l.CurrentSlot())
+}
+
+// AdvanceSlots moves the current slot the given amount of slots forward and commits all slots
+// that are old enough to be referenced by a commitment input in the new current slot.
+func (l *Ledger) AdvanceSlots(slots iotago.SlotIndex) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.advanceToSlot(l.currentSlot + slots)
+}
+
+// AdvanceToEpoch moves the current slot to the start of the given epoch.
+// It does nothing if the epoch already started.
+func (l *Ledger) AdvanceToEpoch(epoch iotago.EpochIndex) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if epochStart := l.api.TimeProvider().EpochStart(epoch); epochStart > l.currentSlot {
+		l.advanceToSlot(epochStart)
+	}
+}
+
+// AdvanceToTime moves the current slot to the slot of the given time.
+// It does nothing if the slot of the given time is not after the current slot.
+func (l *Ledger) AdvanceToTime(targetTime time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if slot := l.api.TimeProvider().SlotFromTime(targetTime); slot > l.currentSlot {
+		l.advanceToSlot(slot)
+	}
+}
+
+// LatestCommitment returns the commitment of the latest committed slot.
+func (l *Ledger) LatestCommitment() *iotago.Commitment {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.latestCommitment
+}
+
+// Commitment returns the commitment of the given slot.
+func (l *Ledger) Commitment(slot iotago.SlotIndex) (*iotago.Commitment, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	commitment, exists := l.commitments[slot]
+
+	return commitment, exists
+}
+
+// AddGenesisOutput adds the given output to the ledger without a transaction and returns its OutputID.
+// Accounts created this way start with zero block issuance credits.
+func (l *Ledger) AddGenesisOutput(output iotago.Output) iotago.OutputID {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	genesisOutputIndex := make([]byte, 4)
+	binary.LittleEndian.PutUint32(genesisOutputIndex, l.genesisOutputsCount)
+	l.genesisOutputsCount++
+
+	outputID := iotago.OutputIDFromTransactionIDAndIndex(iotago.TransactionIDRepresentingData(l.currentSlot, genesisOutputIndex), 0)
+	l.addOutput(outputID, output)
+
+	return outputID
+}
+
+// Output returns the unspent output with the given ID.
+func (l *Ledger) Output(outputID iotago.OutputID) (iotago.Output, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	output, exists := l.unspentOutputs[outputID]
+	if !exists {
+		if _, spent := l.spentOutputs[outputID]; spent {
+			return nil, ierrors.WithMessagef(iotago.ErrInputAlreadySpent, "output %s", outputID.ToHex())
+		}
+
+		return nil, ierrors.WithMessagef(ErrInputNotFound, "output %s", outputID.ToHex())
+	}
+
+	return output, nil
+}
+
+// UnspentOutputs returns a copy of all unspent outputs.
+func (l *Ledger) UnspentOutputs() iotago.OutputSet {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.unspentOutputs.Clone()
+}
+
+// BlockIssuanceCredits returns the block issuance credits of the given account.
+func (l *Ledger) BlockIssuanceCredits(accountID iotago.AccountID) (iotago.BlockIssuanceCredits, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	bic, exists := l.blockIssuanceCredit[accountID]
+	if !exists {
+		return 0, ierrors.WithMessagef(ErrAccountNotFound, "account %s", accountID.ToHex())
+	}
+
+	return bic, nil
+}
+
+// SetBlockIssuanceCredits sets the block issuance credits of the given account.
+func (l *Ledger) SetBlockIssuanceCredits(accountID iotago.AccountID, bic iotago.BlockIssuanceCredits) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.blockIssuanceCredit[accountID] = bic
+}
+
+// SetRewards sets the mana rewards that can be claimed by the given account or delegation.
+// Chains without rewards set claim zero rewards.
+func (l *Ledger) SetRewards(chainID iotago.ChainID, rewards iotago.Mana) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.rewards[chainID] = rewards
+}
+
+// ResolveInputs resolves the inputs and context inputs of the given transaction against the current state of the ledger.
+func (l *Ledger) ResolveInputs(transaction *iotago.Transaction) (vm.ResolvedInputs, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.resolveInputs(transaction)
+}
+
+// ApplyTransaction validates the given SignedTransaction in the current slot, executes it with the Nova VirtualMachine
+// and applies it to the ledger. It returns the created outputs.
+// The returned errors can be mapped to the failure reasons a node would report with api.DetermineTransactionFailureReason.
+func (l *Ledger) ApplyTransaction(signedTransaction *iotago.SignedTransaction) (iotago.OutputSet, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, err := l.api.Encode(signedTransaction, serix.WithValidation()); err != nil {
+		return nil, ierrors.Wrap(err, "transaction is syntactically invalid")
+	}
+
+	transaction := signedTransaction.Transaction
+	if transaction.CreationSlot > l.currentSlot {
+		return nil, ierrors.WithMessagef(ErrTransactionCreatedInFuture, "%d > %d", transaction.CreationSlot, l.currentSlot)
+	}
+
+	resolvedInputs, err := l.resolveInputs(transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	novaVM := nova.NewVirtualMachine()
+
+	unlockedAddrs, err := novaVM.ValidateUnlocks(signedTransaction, resolvedInputs)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to validate unlocks")
+	}
+
+	if _, err := novaVM.Execute(transaction, resolvedInputs, unlockedAddrs); err != nil {
+		return nil, err
+	}
+
+	// allotments are credited to the block issuance credits of the accounts, so they need to exist
+	for _, allotment := range transaction.Allotments {
+		if _, exists := l.blockIssuanceCredit[allotment.AccountID]; !exists {
+			return nil, ierrors.WithMessagef(ErrAccountNotFound, "allotment to account %s", allotment.AccountID.ToHex())
+		}
+	}
+
+	txID, err := transaction.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to compute transaction ID")
+	}
+
+	for inputID, input := range resolvedInputs.InputSet {
+		delete(l.unspentOutputs, inputID)
+		l.spentOutputs[inputID] = input
+	}
+
+	createdOutputs := make(iotago.OutputSet, len(transaction.Outputs))
+	for outputIndex, output := range transaction.Outputs {
+		outputID := iotago.OutputIDFromTransactionIDAndIndex(txID, uint16(outputIndex))
+		createdOutputs[outputID] = output
+		l.addOutput(outputID, output)
+	}
+
+	for _, allotment := range transaction.Allotments {
+		l.blockIssuanceCredit[allotment.AccountID] += iotago.BlockIssuanceCredits(allotment.Mana)
+	}
+
+	// claimed rewards can't be claimed again
+	for chainID := range resolvedInputs.RewardsInputSet {
+		delete(l.rewards, chainID)
+	}
+
+	l.acceptedTxsBySlot[l.currentSlot] = append(l.acceptedTxsBySlot[l.currentSlot], txID)
+
+	return createdOutputs, nil
+}
+
+func (l *Ledger) addOutput(outputID iotago.OutputID, output iotago.Output) {
+	l.unspentOutputs[outputID] = output
+
+	accountOutput, isAccount := output.(*iotago.AccountOutput)
+	if !isAccount {
+		return
+	}
+
+	accountID := accountOutput.AccountID
+	if accountID.Empty() {
+		accountID = iotago.AccountIDFromOutputID(outputID)
+	}
+
+	if _, exists := l.blockIssuanceCredit[accountID]; !exists {
+		l.blockIssuanceCredit[accountID] = 0
+	}
+}
+
+func (l *Ledger) resolveInputs(transaction *iotago.Transaction) (vm.ResolvedInputs, error) {
+	resolvedInputs := vm.ResolvedInputs{
+		InputSet:                    make(vm.InputSet),
+		BlockIssuanceCreditInputSet: make(vm.BlockIssuanceCreditInputSet),
+		RewardsInputSet:             make(vm.RewardsInputSet),
+	}
+
+	inputIDs := make([]iotago.OutputID, 0, len(transaction.TransactionEssence.Inputs))
+	for _, input := range transaction.Inputs() {
+		inputID := input.OutputID()
+
+		output, exists := l.unspentOutputs[inputID]
+		if !exists {
+			if _, spent := l.spentOutputs[inputID]; spent {
+				return vm.ResolvedInputs{}, ierrors.WithMessagef(iotago.ErrInputAlreadySpent, "input %s", inputID.ToHex())
+			}
+
+			return vm.ResolvedInputs{}, ierrors.WithMessagef(ErrInputNotFound, "input %s", inputID.ToHex())
+		}
+
+		resolvedInputs.InputSet[inputID] = output
+		inputIDs = append(inputIDs, inputID)
+	}
+
+	if commitmentInput := transaction.CommitmentInput(); commitmentInput != nil {
+		commitment, exists := l.commitmentsByID[commitmentInput.CommitmentID]
+		if !exists {
+			return vm.ResolvedInputs{}, ierrors.WithMessagef(iotago.ErrCommitmentInputReferenceInvalid, "commitment %s is unknown", commitmentInput.CommitmentID.ToHex())
+		}
+
+		// the commitment needs to be referenceable by a block issued in the current slot
+		minCommittableAge := l.api.ProtocolParameters().MinCommittableAge()
+		maxCommittableAge := l.api.ProtocolParameters().MaxCommittableAge()
+		if commitment.Slot+minCommittableAge > l.currentSlot || commitment.Slot+maxCommittableAge < l.currentSlot {
+			return vm.ResolvedInputs{}, ierrors.WithMessagef(iotago.ErrCommitmentInputReferenceInvalid, "commitment slot %d can't be referenced in slot %d", commitment.Slot, l.currentSlot)
+		}
+
+		resolvedInputs.CommitmentInput = commitment
+	}
+
+	for _, bicInput := range transaction.BICInputs() {
+		bic, exists := l.blockIssuanceCredit[bicInput.AccountID]
+		if !exists {
+			return vm.ResolvedInputs{}, ierrors.WithMessagef(iotago.ErrBICInputReferenceInvalid, "account %s is unknown", bicInput.AccountID.ToHex())
+		}
+
+		resolvedInputs.BlockIssuanceCreditInputSet[bicInput.AccountID] = bic
+	}
+
+	for _, rewardInput := range transaction.RewardInputs() {
+		if int(rewardInput.Index) >= len(inputIDs) {
+			return vm.ResolvedInputs{}, ierrors.WithMessagef(iotago.ErrRewardInputReferenceInvalid, "reward input references input %d which doesn't exist", rewardInput.Index)
+		}
+
+		inputID := inputIDs[rewardInput.Index]
+		chainOutput, isChainOutput := resolvedInputs.InputSet[inputID].(iotago.ChainOutput)
+		if !isChainOutput {
+			return vm.ResolvedInputs{}, ierrors.WithMessagef(iotago.ErrRewardInputReferenceInvalid, "reward input references input %d which is not a chain output", rewardInput.Index)
+		}
+
+		chainID := chainOutput.ChainID()
+		if utxoIDChainID, isUTXOIDChainID := chainID.(iotago.UTXOIDChainID); isUTXOIDChainID && chainID.Empty() {
+			chainID = utxoIDChainID.FromOutputID(inputID)
+		}
+
+		resolvedInputs.RewardsInputSet[chainID] = l.rewards[chainID]
+	}
+
+	return resolvedInputs, nil
+}
+
+// advanceToSlot sets the current slot and commits all slots which can be referenced in it.
+func (l *Ledger) advanceToSlot(slot iotago.SlotIndex) {
+	l.currentSlot = slot
+
+	minCommittableAge := l.api.ProtocolParameters().MinCommittableAge()
+	if slot < minCommittableAge {
+		return
+	}
+
+	for commitSlot := l.latestCommitment.Slot + 1; commitSlot <= slot-minCommittableAge; commitSlot++ {
+		l.commit(commitSlot)
+	}
+}
+
+// commit creates the commitment of the given slot, which commits to the transactions accepted in that slot.
+func (l *Ledger) commit(slot iotago.SlotIndex) {
+	acceptedTxs := l.acceptedTxsBySlot[slot]
+	sort.Slice(acceptedTxs, func(i, j int) bool {
+		return bytes.Compare(acceptedTxs[i][:], acceptedTxs[j][:]) < 0
+	})
+
+	var rootsData []byte
+	for _, txID := range acceptedTxs {
+		rootsData = append(rootsData, txID[:]...)
+	}
+
+	commitment := iotago.NewCommitment(
+		l.api.ProtocolParameters().Version(),
+		slot,
+		l.latestCommitment.MustID(),
+		iotago.IdentifierFromData(rootsData),
+		// there are no validators in the ledger, so there is no weight to accumulate
+		l.latestCommitment.CumulativeWeight,
+		l.api.ProtocolParameters().CongestionControlParameters().MinReferenceManaCost,
+	)
+
+	l.commitments[slot] = commitment
+	l.commitmentsByID[commitment.MustID()] = commitment
+	l.latestCommitment = commitment
+	delete(l.acceptedTxsBySlot, slot)
+}
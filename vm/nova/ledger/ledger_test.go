@@ -0,0 +1,181 @@
+//nolint:forcetypeassert
+package ledger_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm/nova/ledger"
+)
+
+const OneIOTA iotago.BaseToken = 1_000_000
+
+var testAPI = iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+
+func newBasicOutput(addr iotago.Address, amount iotago.BaseToken, unlockConditions ...iotago.BasicOutputUnlockCondition) *iotago.BasicOutput {
+	return &iotago.BasicOutput{
+		Amount:           amount,
+		UnlockConditions: append(iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr}}, unlockConditions...),
+	}
+}
+
+// transfer builds a transaction that sends the given output to the target address and stores all mana on the new output.
+func transfer(t *testing.T, l *ledger.Ledger, prvKey ed25519.PrivateKey, inputID iotago.OutputID, targetAddr iotago.Address, opts ...func(txBuilder *builder.TransactionBuilder)) *iotago.SignedTransaction {
+	t.Helper()
+
+	input, err := l.Output(inputID)
+	require.NoError(t, err)
+
+	txBuilder := builder.NewTransactionBuilder(testAPI, iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(prvKey)).
+		SetCreationSlot(l.CurrentSlot()).
+		AddInput(&builder.TxInput{UnlockTarget: input.UnlockConditionSet().Address().Address, InputID: inputID, Input: input}).
+		AddOutput(newBasicOutput(targetAddr, input.BaseTokenAmount()))
+
+	for _, opt := range opts {
+		opt(txBuilder)
+	}
+
+	signedTx, err := txBuilder.StoreRemainingManaInOutputAndAllotRemainingAccountBoundMana(l.CurrentSlot(), 0).Build()
+	require.NoError(t, err)
+
+	return signedTx
+}
+
+func TestLedger(t *testing.T) {
+	prvKey, addr, _ := tpkg.RandEd25519Identity()
+	otherAddr := tpkg.RandEd25519Address()
+
+	t.Run("ok - transfer and double spend", func(t *testing.T) {
+		l := ledger.New(testAPI)
+		genesisOutputID := l.AddGenesisOutput(newBasicOutput(addr, OneIOTA))
+
+		l.AdvanceSlots(5)
+		signedTx := transfer(t, l, prvKey, genesisOutputID, otherAddr)
+		doubleSpendTx := transfer(t, l, prvKey, genesisOutputID, addr)
+
+		createdOutputs, err := l.ApplyTransaction(signedTx)
+		require.NoError(t, err)
+		require.Len(t, createdOutputs, 1)
+
+		for outputID, output := range createdOutputs {
+			ledgerOutput, err := l.Output(outputID)
+			require.NoError(t, err)
+			require.Equal(t, output, ledgerOutput)
+			require.True(t, output.UnlockConditionSet().Address().Address.Equal(otherAddr))
+		}
+
+		_, err = l.Output(genesisOutputID)
+		require.ErrorIs(t, err, iotago.ErrInputAlreadySpent)
+		require.Len(t, l.UnspentOutputs(), 1)
+
+		// spending the same input again is rejected
+		_, err = l.ApplyTransaction(doubleSpendTx)
+		require.ErrorIs(t, err, iotago.ErrInputAlreadySpent)
+		require.Equal(t, api.TxFailureInputAlreadySpent, api.DetermineTransactionFailureReason(err))
+	})
+
+	t.Run("err - unknown input", func(t *testing.T) {
+		l := ledger.New(testAPI)
+		inputID := tpkg.RandOutputIDWithCreationSlot(0, 0)
+		input := newBasicOutput(addr, OneIOTA)
+
+		signedTx, err := builder.NewTransactionBuilder(testAPI, iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(prvKey)).
+			AddInput(&builder.TxInput{UnlockTarget: addr, InputID: inputID, Input: input}).
+			AddOutput(newBasicOutput(otherAddr, OneIOTA)).
+			Build()
+		require.NoError(t, err)
+
+		_, err = l.ApplyTransaction(signedTx)
+		require.ErrorIs(t, err, ledger.ErrInputNotFound)
+	})
+
+	t.Run("err - transaction created in the future", func(t *testing.T) {
+		l := ledger.New(testAPI)
+		genesisOutputID := l.AddGenesisOutput(newBasicOutput(addr, OneIOTA))
+
+		signedTx := transfer(t, l, prvKey, genesisOutputID, otherAddr, func(txBuilder *builder.TransactionBuilder) {
+			txBuilder.SetCreationSlot(l.CurrentSlot() + 1)
+		})
+
+		_, err := l.ApplyTransaction(signedTx)
+		require.ErrorIs(t, err, ledger.ErrTransactionCreatedInFuture)
+	})
+
+	t.Run("ok - timelock expires after advancing slots", func(t *testing.T) {
+		l := ledger.New(testAPI)
+		l.AdvanceToEpoch(1)
+
+		timelockSlot := l.CurrentSlot() + 10
+		genesisOutputID := l.AddGenesisOutput(newBasicOutput(addr, OneIOTA, &iotago.TimelockUnlockCondition{Slot: timelockSlot}))
+
+		withLatestCommitment := func(txBuilder *builder.TransactionBuilder) {
+			txBuilder.AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: l.LatestCommitment().MustID()})
+		}
+
+		_, err := l.ApplyTransaction(transfer(t, l, prvKey, genesisOutputID, otherAddr, withLatestCommitment))
+		require.ErrorIs(t, err, iotago.ErrTimelockNotExpired)
+		require.Equal(t, api.TxFailureTimelockNotExpired, api.DetermineTransactionFailureReason(err))
+
+		l.AdvanceSlots(10 + testAPI.ProtocolParameters().MinCommittableAge())
+		require.Equal(t, l.CurrentSlot()-testAPI.ProtocolParameters().MinCommittableAge(), l.LatestCommitment().Slot)
+
+		// a commitment that is too old can't be referenced anymore
+		_, err = l.ApplyTransaction(transfer(t, l, prvKey, genesisOutputID, otherAddr, func(txBuilder *builder.TransactionBuilder) {
+			genesisCommitment, exists := l.Commitment(testAPI.ProtocolParameters().GenesisSlot())
+			require.True(t, exists)
+			txBuilder.AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: genesisCommitment.MustID()})
+		}))
+		require.ErrorIs(t, err, iotago.ErrCommitmentInputReferenceInvalid)
+
+		_, err = l.ApplyTransaction(transfer(t, l, prvKey, genesisOutputID, otherAddr, withLatestCommitment))
+		require.NoError(t, err)
+	})
+
+	t.Run("ok - allotment increases block issuance credits", func(t *testing.T) {
+		l := ledger.New(testAPI)
+
+		accountOutputID := l.AddGenesisOutput(&iotago.AccountOutput{
+			Amount: OneIOTA,
+			UnlockConditions: iotago.AccountOutputUnlockConditions{
+				&iotago.AddressUnlockCondition{Address: addr},
+			},
+		})
+		accountID := iotago.AccountIDFromOutputID(accountOutputID)
+
+		bic, err := l.BlockIssuanceCredits(accountID)
+		require.NoError(t, err)
+		require.Zero(t, bic)
+
+		genesisOutputID := l.AddGenesisOutput(newBasicOutput(addr, OneIOTA, &iotago.StorageDepositReturnUnlockCondition{ReturnAddress: addr, Amount: OneIOTA / 2}))
+		l.AdvanceSlots(100)
+
+		input, err := l.Output(genesisOutputID)
+		require.NoError(t, err)
+
+		var allottedMana iotago.Mana
+		signedTx, err := builder.NewTransactionBuilder(testAPI, iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(prvKey)).
+			SetCreationSlot(l.CurrentSlot()).
+			AddInput(&builder.TxInput{UnlockTarget: addr, InputID: genesisOutputID, Input: input}).
+			AddOutput(newBasicOutput(otherAddr, OneIOTA)).
+			AllotAllMana(l.CurrentSlot(), accountID, 0).
+			Build()
+		require.NoError(t, err)
+		for _, allotment := range signedTx.Transaction.Allotments {
+			allottedMana += allotment.Mana
+		}
+		require.NotZero(t, allottedMana)
+
+		_, err = l.ApplyTransaction(signedTx)
+		require.NoError(t, err)
+
+		bic, err = l.BlockIssuanceCredits(accountID)
+		require.NoError(t, err)
+		require.EqualValues(t, allottedMana, bic)
+	})
+}
@@ -0,0 +1,75 @@
+package nova
+
+import (
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/vm"
+)
+
+// ErrSimulationInputNotResolved gets returned if an input of the simulated transaction is missing in the resolved inputs.
+var ErrSimulationInputNotResolved = ierrors.New("input of the transaction is not resolved")
+
+// SimulationResult holds the outcome of a locally simulated transaction.
+type SimulationResult struct {
+	// The addresses which were unlocked by the unlocks of the transaction.
+	UnlockedAddrs vm.UnlockedAddresses
+	// The outputs produced by the transaction, nil if the simulation failed.
+	Outputs []iotago.Output
+	// The name of the first ExecFunc that failed, empty if the unlocks are invalid or the simulation succeeded.
+	FailedExecFunc string
+	// The index of the first ExecFunc that failed, -1 if the unlocks are invalid or the simulation succeeded.
+	FailedExecFuncIndex int
+	// The trace of the execution, which is empty if the unlocks are invalid.
+	Trace *vm.Tracer
+}
+
+// Succeeded tells whether the simulated transaction passed all checks.
+func (r *SimulationResult) Succeeded() bool {
+	return r.Outputs != nil
+}
+
+// Simulate runs the full Nova pipeline of ValidateUnlocks and Execute on the given SignedTransaction locally,
+// so semantic errors are detected before the transaction is sent to a node.
+// If no ExecFunc(s) are given, the ones of the Nova VirtualMachine are used,
+// otherwise the given ExecFunc(s) are reported by their position, e.g. "ExecFunc#0".
+// The returned SimulationResult is always set, and in case of an error it tells which step failed.
+func Simulate(signedTransaction *iotago.SignedTransaction, resolvedInputs vm.ResolvedInputs, execFunctions ...vm.ExecFunc) (*SimulationResult, error) {
+	result := &SimulationResult{
+		FailedExecFuncIndex: -1,
+		Trace:               vm.NewTracer(),
+	}
+
+	// the VM expects all inputs to be resolved, so we check this beforehand to fail gracefully
+	for inputIndex, input := range signedTransaction.Transaction.Inputs() {
+		if _, exists := resolvedInputs.InputSet[input.OutputID()]; !exists {
+			return result, ierrors.WithMessagef(ErrSimulationInputNotResolved, "input %d (%s)", inputIndex, input.OutputID().ToHex())
+		}
+	}
+
+	//nolint:forcetypeassert // we know the type of our own VirtualMachine
+	novaVM := NewVirtualMachine().(*virtualMachine)
+
+	unlockedAddrs, err := novaVM.ValidateUnlocks(signedTransaction, resolvedInputs)
+	if err != nil {
+		return result, ierrors.Wrap(err, "failed to validate unlocks")
+	}
+	result.UnlockedAddrs = unlockedAddrs
+
+	vmParams, err := newVMParams(signedTransaction.Transaction, resolvedInputs, unlockedAddrs, result.Trace)
+	if err != nil {
+		return result, err
+	}
+
+	if err := novaVM.runExecFuncs(vmParams, execFunctions...); err != nil {
+		if failedExecFunc := result.Trace.FailedExecFunc(); failedExecFunc != nil {
+			result.FailedExecFunc = failedExecFunc.Name
+			result.FailedExecFuncIndex = len(result.Trace.ExecFuncs) - 1
+		}
+
+		return result, ierrors.Wrapf(err, "failed to execute transaction in %s", result.FailedExecFunc)
+	}
+
+	result.Outputs = transactionOutputs(signedTransaction.Transaction)
+
+	return result, nil
+}
@@ -0,0 +1,119 @@
+package nova_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestSimulate(t *testing.T) {
+	_, ownAddr, ownAddrKeys := tpkg.RandEd25519Identity()
+	_, _, otherAddrKeys := tpkg.RandEd25519Identity()
+
+	inputID := tpkg.RandOutputIDWithCreationSlot(0, 0)
+	input := &iotago.BasicOutput{
+		Amount:           OneIOTA,
+		UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: ownAddr}},
+	}
+
+	newSignedTx := func(signer iotago.AddressSigner, outputAmount iotago.BaseToken) *iotago.SignedTransaction {
+		signedTx, err := builder.NewTransactionBuilder(testAPI, signer).
+			AddInput(&builder.TxInput{UnlockTarget: ownAddr, InputID: inputID, Input: input}).
+			AddOutput(&iotago.BasicOutput{
+				Amount:           outputAmount,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()}},
+			}).
+			Build()
+		require.NoError(t, err)
+
+		return signedTx
+	}
+
+	type test struct {
+		name                   string
+		signedTx               *iotago.SignedTransaction
+		resolvedInputs         vm.ResolvedInputs
+		expectedFailedExecFunc string
+		expectedFailureReason  api.TransactionFailureReason
+		wantErr                error
+	}
+
+	tests := []*test{
+		{
+			name:           "ok",
+			signedTx:       newSignedTx(iotago.NewInMemoryAddressSigner(ownAddrKeys), OneIOTA),
+			resolvedInputs: vm.ResolvedInputs{InputSet: vm.InputSet{inputID: input}},
+		},
+		{
+			name:           "fail - input not resolved",
+			signedTx:       newSignedTx(iotago.NewInMemoryAddressSigner(ownAddrKeys), OneIOTA),
+			resolvedInputs: vm.ResolvedInputs{InputSet: vm.InputSet{}},
+			wantErr:        nova.ErrSimulationInputNotResolved,
+		},
+		func() *test {
+			// sign with the wrong key by mapping the own address to the keys of another address
+			signedTx := newSignedTx(iotago.NewInMemoryAddressSigner(iotago.AddressKeys{Address: ownAddr, Keys: otherAddrKeys.Keys}), OneIOTA)
+
+			return &test{
+				name:           "fail - invalid unlocks",
+				signedTx:       signedTx,
+				resolvedInputs: vm.ResolvedInputs{InputSet: vm.InputSet{inputID: input}},
+				wantErr:        iotago.ErrDirectUnlockableAddressUnlockInvalid,
+				// the failure reason matches the one a node would report
+				expectedFailureReason: api.TxFailureUnlockSignatureInvalid,
+			}
+		}(),
+		{
+			name:                   "fail - unbalanced base tokens",
+			signedTx:               newSignedTx(iotago.NewInMemoryAddressSigner(ownAddrKeys), OneIOTA/2),
+			resolvedInputs:         vm.ResolvedInputs{InputSet: vm.InputSet{inputID: input}},
+			expectedFailedExecFunc: "ExecFuncBalancedBaseTokens",
+			expectedFailureReason:  api.TxFailureInputOutputBaseTokenMismatch,
+			wantErr:                iotago.ErrInputOutputBaseTokenMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := nova.Simulate(test.signedTx, test.resolvedInputs)
+			require.NotNil(t, result)
+			require.Equal(t, test.expectedFailedExecFunc, result.FailedExecFunc)
+
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				require.False(t, result.Succeeded())
+
+				if test.expectedFailureReason != api.TxFailureNone {
+					require.Equal(t, test.expectedFailureReason, api.DetermineTransactionFailureReason(err))
+					require.ErrorIs(t, err, api.TransactionFailureReasonError(test.expectedFailureReason))
+				}
+
+				if test.expectedFailedExecFunc == "" {
+					require.Equal(t, -1, result.FailedExecFuncIndex)
+				}
+
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, result.Succeeded())
+			require.Nil(t, result.Trace.FailedExecFunc())
+			require.Len(t, result.Outputs, len(test.signedTx.Transaction.Outputs))
+		})
+	}
+
+	t.Run("fail - given exec funcs are named by their position", func(t *testing.T) {
+		signedTx := newSignedTx(iotago.NewInMemoryAddressSigner(ownAddrKeys), OneIOTA/2)
+
+		result, err := nova.Simulate(signedTx, vm.ResolvedInputs{InputSet: vm.InputSet{inputID: input}}, vm.ExecFuncTimelocks(), vm.ExecFuncBalancedBaseTokens())
+		require.ErrorIs(t, err, iotago.ErrInputOutputBaseTokenMismatch)
+		require.Equal(t, "ExecFunc#1", result.FailedExecFunc)
+		require.Equal(t, 1, result.FailedExecFuncIndex)
+	})
+}
@@ -0,0 +1,226 @@
+package nova_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm"
+	"github.com/iotaledger/iota.go/v4/vm/nova"
+)
+
+func TestTracer(t *testing.T) {
+	_, addr1, addr1AddrKeys := tpkg.RandEd25519Identity()
+
+	inputIDs := tpkg.RandOutputIDs(1)
+	inputs := vm.InputSet{
+		inputIDs[0]: &iotago.NFTOutput{
+			Amount: 2 * OneIOTA,
+			NFTID:  iotago.NFTID{},
+			UnlockConditions: iotago.NFTOutputUnlockConditions{
+				&iotago.AddressUnlockCondition{Address: addr1},
+			},
+		},
+	}
+	nftID := iotago.NFTAddressFromOutputID(inputIDs[0]).NFTID()
+
+	newSignedTx := func(newNFTAmount iotago.BaseToken) *iotago.SignedTransaction {
+		transaction := &iotago.Transaction{
+			API: testAPI,
+			TransactionEssence: &iotago.TransactionEssence{
+				Inputs:       inputIDs.UTXOInputs(),
+				Capabilities: iotago.TransactionCapabilitiesBitMaskWithCapabilities(iotago.WithTransactionCanDoAnything()),
+			},
+			Outputs: iotago.TxEssenceOutputs{
+				// state transition of the existing NFT
+				&iotago.NFTOutput{
+					Amount: OneIOTA,
+					NFTID:  nftID,
+					UnlockConditions: iotago.NFTOutputUnlockConditions{
+						&iotago.AddressUnlockCondition{Address: addr1},
+					},
+				},
+				// genesis of a new NFT
+				&iotago.NFTOutput{
+					Amount: newNFTAmount,
+					NFTID:  iotago.NFTID{},
+					UnlockConditions: iotago.NFTOutputUnlockConditions{
+						&iotago.AddressUnlockCondition{Address: addr1},
+					},
+				},
+			},
+		}
+
+		sigs, err := transaction.Sign(addr1AddrKeys)
+		require.NoError(t, err)
+
+		return &iotago.SignedTransaction{
+			API:         testAPI,
+			Transaction: transaction,
+			Unlocks: iotago.Unlocks{
+				&iotago.SignatureUnlock{Signature: sigs[0]},
+			},
+		}
+	}
+
+	execute := func(signedTx *iotago.SignedTransaction) (*vm.Tracer, error) {
+		resolvedInputs := vm.ResolvedInputs{InputSet: inputs}
+
+		var tracer *vm.Tracer
+		tracingVM := nova.NewVirtualMachine(nova.WithTracer(func(trace *vm.Tracer) { tracer = trace }))
+
+		unlockedAddrs, err := tracingVM.ValidateUnlocks(signedTx, resolvedInputs)
+		require.NoError(t, err)
+
+		_, err = tracingVM.Execute(signedTx.Transaction, resolvedInputs, unlockedAddrs)
+
+		return tracer, err
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		signedTx := newSignedTx(OneIOTA)
+
+		tracer, err := execute(signedTx)
+		require.NoError(t, err)
+		require.Nil(t, tracer.FailedExecFunc())
+
+		require.Equal(t, signedTx.Transaction.MustID().ToHex(), tracer.TransactionID)
+		// the signature unlocks the owner and the NFT itself
+		unlockedAddrs := make([]string, 0, len(tracer.UnlockedAddresses))
+		for _, unlockedAddr := range tracer.UnlockedAddresses {
+			unlockedAddrs = append(unlockedAddrs, unlockedAddr.Address)
+		}
+		require.ElementsMatch(t, []string{
+			addr1.Bech32(testAPI.ProtocolParameters().Bech32HRP()),
+			nftID.ToAddress().Bech32(testAPI.ProtocolParameters().Bech32HRP()),
+		}, unlockedAddrs)
+		require.Equal(t, "2000000", tracer.Balances.BaseTokensIn)
+		require.Equal(t, "2000000", tracer.Balances.BaseTokensOut)
+
+		require.Equal(t, []string{
+			"ExecFuncTimelocks",
+			"ExecFuncSenderUnlocked",
+			"ExecFuncBalancedBaseTokens",
+			"ExecFuncBalancedNativeTokens",
+			"ExecFuncChainTransitions",
+			"ExecFuncBalancedMana",
+			"ExecFuncAtMostOneImplicitAccountCreationAddress",
+		}, execFuncNames(tracer))
+
+		require.Len(t, tracer.ChainTransitions, 2)
+		for _, chainTransition := range tracer.ChainTransitions {
+			require.True(t, chainTransition.Passed)
+			require.Equal(t, iotago.OutputNFT.String(), chainTransition.OutputType)
+			require.NotNil(t, chainTransition.OutputIndex)
+
+			switch chainTransition.TransitionType {
+			case iotago.ChainTransitionTypeStateChange.String():
+				require.Equal(t, nftID.ToHex(), chainTransition.ChainID)
+				require.Equal(t, uint16(0), *chainTransition.InputIndex)
+				require.Equal(t, uint16(0), *chainTransition.OutputIndex)
+				require.Equal(t, "2000000", chainTransition.Balances.BaseTokensIn)
+				require.Equal(t, "1000000", chainTransition.Balances.BaseTokensOut)
+				// the input is unlocked by the owner and unlocks the NFT address itself
+				require.ElementsMatch(t, unlockedAddrs, chainTransition.UnlockedAddresses)
+			case iotago.ChainTransitionTypeGenesis.String():
+				require.Nil(t, chainTransition.InputIndex)
+				require.Equal(t, uint16(1), *chainTransition.OutputIndex)
+				require.Empty(t, chainTransition.Balances.BaseTokensIn)
+				require.Equal(t, "1000000", chainTransition.Balances.BaseTokensOut)
+				require.Empty(t, chainTransition.UnlockedAddresses)
+			default:
+				require.Failf(t, "unexpected transition type", chainTransition.TransitionType)
+			}
+		}
+
+		traceJSON, err := tracer.JSON()
+		require.NoError(t, err)
+
+		decoded := &vm.Tracer{}
+		require.NoError(t, json.Unmarshal(traceJSON, decoded))
+		require.Equal(t, tracer, decoded)
+	})
+
+	t.Run("fail - unbalanced base tokens", func(t *testing.T) {
+		tracer, err := execute(newSignedTx(2 * OneIOTA))
+		require.ErrorIs(t, err, iotago.ErrInputOutputBaseTokenMismatch)
+
+		// the execution stops at the first failing ExecFunc
+		require.Equal(t, []string{
+			"ExecFuncTimelocks",
+			"ExecFuncSenderUnlocked",
+			"ExecFuncBalancedBaseTokens",
+		}, execFuncNames(tracer))
+
+		failedExecFunc := tracer.FailedExecFunc()
+		require.NotNil(t, failedExecFunc)
+		require.Equal(t, "ExecFuncBalancedBaseTokens", failedExecFunc.Name)
+		require.Contains(t, failedExecFunc.Error, iotago.ErrInputOutputBaseTokenMismatch.Error())
+		require.Empty(t, tracer.ChainTransitions)
+	})
+}
+
+func TestTracerReusedVirtualMachine(t *testing.T) {
+	_, addr, addrKeys := tpkg.RandEd25519Identity()
+
+	var traces []*vm.Tracer
+	tracingVM := nova.NewVirtualMachine(nova.WithTracer(func(trace *vm.Tracer) { traces = append(traces, trace) }))
+
+	transactionIDs := make([]string, 0, 2)
+	for range 2 {
+		inputIDs := tpkg.RandOutputIDs(1)
+		resolvedInputs := vm.ResolvedInputs{InputSet: vm.InputSet{
+			inputIDs[0]: &iotago.BasicOutput{
+				Amount:           OneIOTA,
+				UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: addr}},
+			},
+		}}
+
+		transaction := &iotago.Transaction{
+			API: testAPI,
+			TransactionEssence: &iotago.TransactionEssence{
+				Inputs:       inputIDs.UTXOInputs(),
+				Capabilities: iotago.TransactionCapabilitiesBitMask{},
+			},
+			Outputs: iotago.TxEssenceOutputs{
+				&iotago.BasicOutput{
+					Amount:           OneIOTA,
+					UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()}},
+				},
+			},
+		}
+
+		sigs, err := transaction.Sign(addrKeys)
+		require.NoError(t, err)
+
+		signedTx := &iotago.SignedTransaction{API: testAPI, Transaction: transaction, Unlocks: iotago.Unlocks{&iotago.SignatureUnlock{Signature: sigs[0]}}}
+
+		unlockedAddrs, err := tracingVM.ValidateUnlocks(signedTx, resolvedInputs)
+		require.NoError(t, err)
+
+		_, err = tracingVM.Execute(transaction, resolvedInputs, unlockedAddrs)
+		require.NoError(t, err)
+
+		transactionIDs = append(transactionIDs, transaction.MustID().ToHex())
+	}
+
+	// every execution is traced on its own
+	require.Len(t, traces, 2)
+	for i, trace := range traces {
+		require.Equal(t, transactionIDs[i], trace.TransactionID)
+		require.Len(t, trace.ExecFuncs, 7)
+		require.Empty(t, trace.ChainTransitions)
+	}
+}
+
+func execFuncNames(tracer *vm.Tracer) []string {
+	names := make([]string, 0, len(tracer.ExecFuncs))
+	for _, execFuncTrace := range tracer.ExecFuncs {
+		names = append(names, execFuncTrace.Name)
+	}
+
+	return names
+}
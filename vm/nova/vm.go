@@ -5,27 +5,39 @@ import (
 
 	"github.com/iotaledger/hive.go/core/safemath"
 	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
 	iotago "github.com/iotaledger/iota.go/v4"
 	"github.com/iotaledger/iota.go/v4/vm"
 )
 
+// WithTracer enables the tracing of the checks performed by the VirtualMachine.
+// Every execution records its own Tracer, which is passed to the given function once the execution is done.
+// The function is called by concurrent executions concurrently.
+func WithTracer(onTrace func(trace *vm.Tracer)) options.Option[virtualMachine] {
+	return func(novaVM *virtualMachine) {
+		novaVM.optsOnTrace = onTrace
+	}
+}
+
 // NewVirtualMachine returns an VirtualMachine adhering to the Nova protocol.
-func NewVirtualMachine() vm.VirtualMachine {
-	return &virtualMachine{
-		execList: []vm.ExecFunc{
-			vm.ExecFuncTimelocks(),
-			vm.ExecFuncSenderUnlocked(),
-			vm.ExecFuncBalancedBaseTokens(),
-			vm.ExecFuncBalancedNativeTokens(),
-			vm.ExecFuncChainTransitions(),
-			vm.ExecFuncBalancedMana(),
-			vm.ExecFuncAtMostOneImplicitAccountCreationAddress(),
+func NewVirtualMachine(opts ...options.Option[virtualMachine]) vm.VirtualMachine {
+	return options.Apply(&virtualMachine{
+		execList: []vm.NamedExecFunc{
+			{Name: "ExecFuncTimelocks", ExecFunc: vm.ExecFuncTimelocks()},
+			{Name: "ExecFuncSenderUnlocked", ExecFunc: vm.ExecFuncSenderUnlocked()},
+			{Name: "ExecFuncBalancedBaseTokens", ExecFunc: vm.ExecFuncBalancedBaseTokens()},
+			{Name: "ExecFuncBalancedNativeTokens", ExecFunc: vm.ExecFuncBalancedNativeTokens()},
+			{Name: "ExecFuncChainTransitions", ExecFunc: vm.ExecFuncChainTransitions()},
+			{Name: "ExecFuncBalancedMana", ExecFunc: vm.ExecFuncBalancedMana()},
+			{Name: "ExecFuncAtMostOneImplicitAccountCreationAddress", ExecFunc: vm.ExecFuncAtMostOneImplicitAccountCreationAddress()},
 		},
-	}
+	}, opts)
 }
 
 type virtualMachine struct {
-	execList []vm.ExecFunc
+	execList []vm.NamedExecFunc
+
+	optsOnTrace func(trace *vm.Tracer)
 }
 
 func NewVMParamsWorkingSet(api iotago.API, t *iotago.Transaction, resolvedInputs vm.ResolvedInputs) (*vm.WorkingSet, error) {
@@ -104,30 +116,58 @@ func (novaVM *virtualMachine) ValidateUnlocks(signedTransaction *iotago.SignedTr
 }
 
 func (novaVM *virtualMachine) Execute(transaction *iotago.Transaction, resolvedInputs vm.ResolvedInputs, unlockedAddrs vm.UnlockedAddresses, execFunctions ...vm.ExecFunc) (outputs []iotago.Output, err error) {
-	vmParams := &vm.Params{
-		API: transaction.API,
+	var tracer *vm.Tracer
+	if novaVM.optsOnTrace != nil {
+		tracer = vm.NewTracer()
 	}
 
-	if vmParams.WorkingSet, err = NewVMParamsWorkingSet(vmParams.API, transaction, resolvedInputs); err != nil {
-		return nil, ierrors.Wrap(err, "failed to create working set")
+	vmParams, err := newVMParams(transaction, resolvedInputs, unlockedAddrs, tracer)
+	if err != nil {
+		return nil, err
 	}
-	vmParams.WorkingSet.UnlockedAddrs = unlockedAddrs
 
-	if len(execFunctions) == 0 {
-		execFunctions = novaVM.execList
+	if tracer != nil {
+		defer novaVM.optsOnTrace(tracer)
 	}
 
-	err = vm.RunVMFuncs(novaVM, vmParams, execFunctions...)
-	if err != nil {
+	if err = novaVM.runExecFuncs(vmParams, execFunctions...); err != nil {
 		return nil, ierrors.Wrap(err, "failed to execute transaction")
 	}
 
-	outputs = make([]iotago.Output, len(transaction.Outputs))
+	return transactionOutputs(transaction), nil
+}
+
+// runExecFuncs runs the given ExecFunc(s), or the ones of the VirtualMachine if none are given.
+func (novaVM *virtualMachine) runExecFuncs(vmParams *vm.Params, execFunctions ...vm.ExecFunc) error {
+	if len(execFunctions) == 0 {
+		return vm.RunNamedVMFuncs(novaVM, vmParams, novaVM.execList...)
+	}
+
+	return vm.RunVMFuncs(novaVM, vmParams, execFunctions...)
+}
+
+func newVMParams(transaction *iotago.Transaction, resolvedInputs vm.ResolvedInputs, unlockedAddrs vm.UnlockedAddresses, tracer *vm.Tracer) (*vm.Params, error) {
+	var err error
+	vmParams := &vm.Params{
+		API:    transaction.API,
+		Tracer: tracer,
+	}
+
+	if vmParams.WorkingSet, err = NewVMParamsWorkingSet(vmParams.API, transaction, resolvedInputs); err != nil {
+		return nil, ierrors.Wrap(err, "failed to create working set")
+	}
+	vmParams.WorkingSet.UnlockedAddrs = unlockedAddrs
+
+	return vmParams, nil
+}
+
+func transactionOutputs(transaction *iotago.Transaction) []iotago.Output {
+	outputs := make([]iotago.Output, len(transaction.Outputs))
 	for i, output := range transaction.Outputs {
 		outputs[i] = output
 	}
 
-	return outputs, nil
+	return outputs
 }
 
 func (novaVM *virtualMachine) ChainSTVF(vmParams *vm.Params, transType iotago.ChainTransitionType, input *vm.ChainOutputWithIDs, next iotago.ChainOutput) error {
@@ -0,0 +1,279 @@
+package vm
+
+import (
+	"encoding/json"
+	"sort"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+)
+
+// Tracer records the checks the VM performs while executing a transaction, so that the outcome
+// of the execution can be explained without re-deriving the VM logic.
+// It is optional: if no Tracer is set on the Params, nothing is recorded.
+// A Tracer records a single execution, so it must not be shared between executions.
+type Tracer struct {
+	// The ID of the executed transaction.
+	TransactionID string `json:"transactionId"`
+	// The addresses which were unlocked on the input side.
+	UnlockedAddresses []*UnlockedAddressTrace `json:"unlockedAddresses"`
+	// The balances of the input and output side.
+	Balances *BalancesTrace `json:"balances"`
+	// The executed ExecFunc(s) in execution order.
+	ExecFuncs []*ExecFuncTrace `json:"execFuncs"`
+	// The executed chain state transition validations in execution order.
+	ChainTransitions []*ChainTransitionTrace `json:"chainTransitions"`
+}
+
+// UnlockedAddressTrace describes an address which was unlocked on the input side.
+type UnlockedAddressTrace struct {
+	// The bech32 representation of the address.
+	Address string `json:"address"`
+	// The index of the input by which the address was unlocked.
+	UnlockedAtInputIndex uint16 `json:"unlockedAtInputIndex"`
+	// The indexes of the inputs which referenced the address.
+	ReferencedByInputIndexes []uint16 `json:"referencedByInputIndexes,omitempty"`
+}
+
+// BalancesTrace describes the balances of the input and output side of a transaction.
+type BalancesTrace struct {
+	BaseTokensIn    string            `json:"baseTokensIn"`
+	BaseTokensOut   string            `json:"baseTokensOut"`
+	ManaIn          string            `json:"manaIn"`
+	ManaOut         string            `json:"manaOut"`
+	NativeTokensIn  map[string]string `json:"nativeTokensIn,omitempty"`
+	NativeTokensOut map[string]string `json:"nativeTokensOut,omitempty"`
+}
+
+// ExecFuncTrace describes the outcome of an ExecFunc.
+type ExecFuncTrace struct {
+	// The name of the ExecFunc.
+	Name string `json:"name"`
+	// Whether the checks of the ExecFunc passed.
+	Passed bool `json:"passed"`
+	// The error returned by the ExecFunc, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// ChainTransitionTrace describes the outcome of a chain state transition validation.
+type ChainTransitionTrace struct {
+	// The hex encoded ID of the chain.
+	ChainID string `json:"chainId"`
+	// The type of the chain output.
+	OutputType string `json:"outputType"`
+	// The type of the transition.
+	TransitionType string `json:"transitionType"`
+	// The index of the chain's input, nil in case of a genesis transition.
+	InputIndex *uint16 `json:"inputIndex,omitempty"`
+	// The index of the chain's output, nil in case of a destroy transition.
+	OutputIndex *uint16 `json:"outputIndex,omitempty"`
+	// The base tokens, stored mana and native tokens of the chain's input and output.
+	Balances *BalancesTrace `json:"balances"`
+	// The bech32 representations of the addresses which unlocked the chain's input or were referenced by it.
+	UnlockedAddresses []string `json:"unlockedAddresses,omitempty"`
+	// Whether the transition is valid.
+	Passed bool `json:"passed"`
+	// The error returned by the chain state transition validation, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// NewTracer creates a new empty Tracer.
+func NewTracer() *Tracer {
+	return &Tracer{
+		UnlockedAddresses: make([]*UnlockedAddressTrace, 0),
+		ExecFuncs:         make([]*ExecFuncTrace, 0),
+		ChainTransitions:  make([]*ChainTransitionTrace, 0),
+	}
+}
+
+// JSON returns the JSON representation of the recorded trace.
+func (t *Tracer) JSON() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// FailedExecFunc returns the trace of the first ExecFunc which failed, or nil.
+func (t *Tracer) FailedExecFunc() *ExecFuncTrace {
+	if t == nil {
+		return nil
+	}
+
+	for _, execFuncTrace := range t.ExecFuncs {
+		if !execFuncTrace.Passed {
+			return execFuncTrace
+		}
+	}
+
+	return nil
+}
+
+// traceWorkingSet records the transaction ID, the unlocked addresses and the balances of the working set.
+func (t *Tracer) traceWorkingSet(vmParams *Params) {
+	if t == nil {
+		return
+	}
+
+	workingSet := vmParams.WorkingSet
+	hrp := vmParams.API.ProtocolParameters().Bech32HRP()
+
+	if txID, err := workingSet.Tx.ID(); err == nil {
+		t.TransactionID = txID.ToHex()
+	}
+
+	t.UnlockedAddresses = make([]*UnlockedAddressTrace, 0, len(workingSet.UnlockedAddrs))
+	for _, unlockedAddr := range workingSet.UnlockedAddrs {
+		var referencedBy []uint16
+		for inputIndex := range unlockedAddr.ReferencedByInputIndex {
+			referencedBy = append(referencedBy, inputIndex)
+		}
+		sort.Slice(referencedBy, func(i, j int) bool { return referencedBy[i] < referencedBy[j] })
+
+		t.UnlockedAddresses = append(t.UnlockedAddresses, &UnlockedAddressTrace{
+			Address:                  unlockedAddr.Address.Bech32(hrp),
+			UnlockedAtInputIndex:     unlockedAddr.UnlockedAtInputIndex,
+			ReferencedByInputIndexes: referencedBy,
+		})
+	}
+	sort.Slice(t.UnlockedAddresses, func(i, j int) bool {
+		if t.UnlockedAddresses[i].UnlockedAtInputIndex != t.UnlockedAddresses[j].UnlockedAtInputIndex {
+			return t.UnlockedAddresses[i].UnlockedAtInputIndex < t.UnlockedAddresses[j].UnlockedAtInputIndex
+		}
+
+		return t.UnlockedAddresses[i].Address < t.UnlockedAddresses[j].Address
+	})
+
+	var baseTokensIn, baseTokensOut iotago.BaseToken
+	for _, input := range workingSet.UTXOInputs {
+		baseTokensIn += input.BaseTokenAmount()
+	}
+	for _, output := range workingSet.Tx.Outputs {
+		baseTokensOut += output.BaseTokenAmount()
+	}
+
+	t.Balances = &BalancesTrace{
+		BaseTokensIn:  hexutil.EncodeUint64(uint64(baseTokensIn)),
+		BaseTokensOut: hexutil.EncodeUint64(uint64(baseTokensOut)),
+		ManaIn:        hexutil.EncodeUint64(uint64(workingSet.TotalManaIn)),
+		ManaOut:       hexutil.EncodeUint64(uint64(workingSet.TotalManaOut)),
+	}
+
+	// invalid native token amounts are reported by ExecFuncBalancedNativeTokens
+	if nativeTokensIn, err := workingSet.UTXOInputs.NativeTokenSum(); err == nil {
+		t.Balances.NativeTokensIn = nativeTokenSumTrace(nativeTokensIn)
+	}
+	if nativeTokensOut, err := workingSet.Tx.Outputs.NativeTokenSum(); err == nil {
+		t.Balances.NativeTokensOut = nativeTokenSumTrace(nativeTokensOut)
+	}
+}
+
+// traceExecFunc records the outcome of the ExecFunc with the given name.
+func (t *Tracer) traceExecFunc(name string, err error) {
+	if t == nil {
+		return
+	}
+
+	execFuncTrace := &ExecFuncTrace{
+		Name:   name,
+		Passed: err == nil,
+	}
+	if err != nil {
+		execFuncTrace.Error = err.Error()
+	}
+
+	t.ExecFuncs = append(t.ExecFuncs, execFuncTrace)
+}
+
+// traceChainTransition records the outcome of a chain state transition validation.
+func (t *Tracer) traceChainTransition(vmParams *Params, transType iotago.ChainTransitionType, input *ChainOutputWithIDs, next iotago.ChainOutput, err error) {
+	if t == nil {
+		return
+	}
+
+	chainTransitionTrace := &ChainTransitionTrace{
+		TransitionType: transType.String(),
+		Balances:       &BalancesTrace{},
+		Passed:         err == nil,
+	}
+	if err != nil {
+		chainTransitionTrace.Error = err.Error()
+	}
+
+	if input != nil {
+		chainTransitionTrace.ChainID = input.ChainID.ToHex()
+		chainTransitionTrace.OutputType = input.Output.Type().String()
+
+		chainTransitionTrace.Balances.BaseTokensIn = hexutil.EncodeUint64(uint64(input.Output.BaseTokenAmount()))
+		chainTransitionTrace.Balances.ManaIn = hexutil.EncodeUint64(uint64(input.Output.StoredMana()))
+		chainTransitionTrace.Balances.NativeTokensIn = outputNativeTokenTrace(input.Output)
+
+		if inputIndex, exists := vmParams.WorkingSet.InputIDToInputIndex[input.OutputID]; exists {
+			chainTransitionTrace.InputIndex = &inputIndex
+			chainTransitionTrace.UnlockedAddresses = inputUnlockedAddressesTrace(vmParams, inputIndex)
+		}
+	}
+
+	if next != nil {
+		chainTransitionTrace.Balances.BaseTokensOut = hexutil.EncodeUint64(uint64(next.BaseTokenAmount()))
+		chainTransitionTrace.Balances.ManaOut = hexutil.EncodeUint64(uint64(next.StoredMana()))
+		chainTransitionTrace.Balances.NativeTokensOut = outputNativeTokenTrace(next)
+
+		if input == nil {
+			// the chain ID of a new chain is derived from the output ID, so we take it from the output set
+			for chainID, outputChain := range vmParams.WorkingSet.OutChains {
+				if outputChain == next {
+					chainTransitionTrace.ChainID = chainID.ToHex()
+				}
+			}
+			chainTransitionTrace.OutputType = next.Type().String()
+		}
+
+		for outputIndex, output := range vmParams.WorkingSet.Tx.Outputs {
+			if iotago.Output(output) == iotago.Output(next) {
+				index := uint16(outputIndex)
+				chainTransitionTrace.OutputIndex = &index
+
+				break
+			}
+		}
+	}
+
+	t.ChainTransitions = append(t.ChainTransitions, chainTransitionTrace)
+}
+
+// inputUnlockedAddressesTrace returns the addresses which were unlocked by the input at the given index or referenced by it.
+func inputUnlockedAddressesTrace(vmParams *Params, inputIndex uint16) []string {
+	hrp := vmParams.API.ProtocolParameters().Bech32HRP()
+
+	var addresses []string
+	for _, unlockedAddr := range vmParams.WorkingSet.UnlockedAddrs {
+		_, referenced := unlockedAddr.ReferencedByInputIndex[inputIndex]
+		if unlockedAddr.UnlockedAtInputIndex == inputIndex || referenced {
+			addresses = append(addresses, unlockedAddr.Address.Bech32(hrp))
+		}
+	}
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+// outputNativeTokenTrace returns the trace of the native token held by the output, if any.
+func outputNativeTokenTrace(output iotago.Output) map[string]string {
+	nativeTokenFeature := output.FeatureSet().NativeToken()
+	if nativeTokenFeature == nil {
+		return nil
+	}
+
+	return nativeTokenSumTrace(iotago.NativeTokenSum{nativeTokenFeature.ID: nativeTokenFeature.Amount})
+}
+
+func nativeTokenSumTrace(nativeTokenSum iotago.NativeTokenSum) map[string]string {
+	if len(nativeTokenSum) == 0 {
+		return nil
+	}
+
+	trace := make(map[string]string, len(nativeTokenSum))
+	for nativeTokenID, amount := range nativeTokenSum {
+		trace[nativeTokenID.ToHex()] = hexutil.EncodeUint256(amount)
+	}
+
+	return trace
+}
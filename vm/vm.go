@@ -27,6 +27,9 @@ type Params struct {
 
 	// The working set which is auto. populated during the semantic validation.
 	WorkingSet *WorkingSet
+
+	// The optional Tracer which records the checks performed during the execution.
+	Tracer *Tracer
 }
 
 // WorkingSet contains fields which get automatically populated
@@ -143,10 +146,33 @@ func (params *Params) FutureBoundedSlotIndex(commitmentInputSlot iotago.SlotInde
 	return commitmentInputSlot + params.API.ProtocolParameters().MinCommittableAge()
 }
 
+// NamedExecFunc is an ExecFunc together with the name it is reported with, e.g. by the Tracer.
+type NamedExecFunc struct {
+	Name     string
+	ExecFunc ExecFunc
+}
+
 // RunVMFuncs runs the given ExecFunc(s) in serial order.
+// The ExecFunc(s) are named by their position, e.g. "ExecFunc#0".
 func RunVMFuncs(vm VirtualMachine, vmParams *Params, execFuncs ...ExecFunc) error {
+	namedExecFuncs := make([]NamedExecFunc, 0, len(execFuncs))
+	for i, execFunc := range execFuncs {
+		namedExecFuncs = append(namedExecFuncs, NamedExecFunc{Name: fmt.Sprintf("ExecFunc#%d", i), ExecFunc: execFunc})
+	}
+
+	return RunNamedVMFuncs(vm, vmParams, namedExecFuncs...)
+}
+
+// RunNamedVMFuncs runs the given NamedExecFunc(s) in serial order.
+// If a Tracer is set on the Params, the working set and the outcome of every ExecFunc are recorded under its name.
+func RunNamedVMFuncs(vm VirtualMachine, vmParams *Params, execFuncs ...NamedExecFunc) error {
+	vmParams.Tracer.traceWorkingSet(vmParams)
+
 	for _, execFunc := range execFuncs {
-		if err := execFunc(vm, vmParams); err != nil {
+		err := execFunc.ExecFunc(vm, vmParams)
+		vmParams.Tracer.traceExecFunc(execFunc.Name, err)
+
+		if err != nil {
 			return err
 		}
 	}
@@ -787,16 +813,23 @@ func ExecFuncTimelocks() ExecFunc {
 // ExecFuncChainTransitions executes state transition validation functions on ChainOutput(s).
 func ExecFuncChainTransitions() ExecFunc {
 	return func(vm VirtualMachine, vmParams *Params) error {
+		chainSTVF := func(transType iotago.ChainTransitionType, input *ChainOutputWithIDs, next iotago.ChainOutput) error {
+			err := vm.ChainSTVF(vmParams, transType, input, next)
+			vmParams.Tracer.traceChainTransition(vmParams, transType, input, next, err)
+
+			return err
+		}
+
 		for chainID, inputChain := range vmParams.WorkingSet.InChains {
 			next := vmParams.WorkingSet.OutChains[chainID]
 			if next == nil {
-				if err := vm.ChainSTVF(vmParams, iotago.ChainTransitionTypeDestroy, inputChain, nil); err != nil {
+				if err := chainSTVF(iotago.ChainTransitionTypeDestroy, inputChain, nil); err != nil {
 					return ierrors.Wrapf(err, "invalid destruction for %s %s", inputChain.Output.Type(), chainID)
 				}
 
 				continue
 			}
-			if err := vm.ChainSTVF(vmParams, iotago.ChainTransitionTypeStateChange, inputChain, next); err != nil {
+			if err := chainSTVF(iotago.ChainTransitionTypeStateChange, inputChain, next); err != nil {
 				return ierrors.Wrapf(err, "invalid transition for %s %s", inputChain.Output.Type(), chainID)
 			}
 		}
@@ -806,7 +839,7 @@ func ExecFuncChainTransitions() ExecFunc {
 				continue
 			}
 
-			if err := vm.ChainSTVF(vmParams, iotago.ChainTransitionTypeGenesis, nil, outputChain); err != nil {
+			if err := chainSTVF(iotago.ChainTransitionTypeGenesis, nil, outputChain); err != nil {
 				return ierrors.Wrapf(err, "invalid creation of %s %s", outputChain.Type(), chainID)
 			}
 		}
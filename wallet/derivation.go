@@ -0,0 +1,255 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/iotaledger/iota-crypto-demo/pkg/bip32path"
+	"github.com/iotaledger/iota-crypto-demo/pkg/slip10"
+	"github.com/iotaledger/iota-crypto-demo/pkg/slip10/eddsa"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+const (
+	// BIP44Purpose is the purpose of a BIP44 path.
+	BIP44Purpose uint32 = 44
+
+	// CoinTypeIOTA is the registered SLIP-44 coin type of IOTA.
+	CoinTypeIOTA uint32 = 4218
+	// CoinTypeShimmer is the registered SLIP-44 coin type of Shimmer.
+	CoinTypeShimmer uint32 = 4219
+
+	// BIP44ChangeExternal is the change of the external chain, used for addresses that receive funds.
+	BIP44ChangeExternal uint32 = 0
+	// BIP44ChangeInternal is the change of the internal chain, used for remainder addresses.
+	BIP44ChangeInternal uint32 = 1
+
+	bip44PathLength = 5
+
+	// keyCacheDepth is the length of the longest cached path prefix, which is the account level of BIP44 paths.
+	// The keys of the addresses below an account are derived from the cached key of the account.
+	keyCacheDepth = 3
+	// keyCacheSize is the maximum number of cached keys.
+	keyCacheSize = 64
+)
+
+// ErrInvalidBIP44Path gets returned when a path is not a valid BIP44 path.
+var ErrInvalidBIP44Path = ierrors.New("invalid BIP44 path")
+
+// BIP44Path is a BIP44 path of the form m/44'/coin_type'/account'/change'/address_index'.
+// All segments are hardened, since SLIP-10 only supports hardened derivation for ed25519.
+type BIP44Path struct {
+	CoinType     uint32
+	Account      uint32
+	Change       uint32
+	AddressIndex uint32
+}
+
+// BIP44PathFromBIP32Path converts a BIP32 path to a BIP44Path.
+func BIP44PathFromBIP32Path(path bip32path.Path) (BIP44Path, error) {
+	if len(path) != bip44PathLength {
+		return BIP44Path{}, ierrors.WithMessagef(ErrInvalidBIP44Path, "path %s has %d segments instead of %d", path, len(path), bip44PathLength)
+	}
+
+	for _, segment := range path {
+		if segment < slip10.Hardened {
+			return BIP44Path{}, ierrors.WithMessagef(ErrInvalidBIP44Path, "path %s contains non-hardened segments", path)
+		}
+	}
+
+	if path[0] != BIP44Purpose|slip10.Hardened {
+		return BIP44Path{}, ierrors.WithMessagef(ErrInvalidBIP44Path, "path %s has purpose %d instead of %d", path, path[0]&^slip10.Hardened, BIP44Purpose)
+	}
+
+	return BIP44Path{
+		CoinType:     path[1] &^ slip10.Hardened,
+		Account:      path[2] &^ slip10.Hardened,
+		Change:       path[3] &^ slip10.Hardened,
+		AddressIndex: path[4] &^ slip10.Hardened,
+	}, nil
+}
+
+// BIP32Path returns the hardened BIP32 path.
+func (p BIP44Path) BIP32Path() bip32path.Path {
+	return bip32path.Path{
+		BIP44Purpose | slip10.Hardened,
+		p.CoinType | slip10.Hardened,
+		p.Account | slip10.Hardened,
+		p.Change | slip10.Hardened,
+		p.AddressIndex | slip10.Hardened,
+	}
+}
+
+// WithAddressIndex returns a copy of the path with the given address index.
+func (p BIP44Path) WithAddressIndex(addressIndex uint32) BIP44Path {
+	p.AddressIndex = addressIndex
+
+	return p
+}
+
+func (p BIP44Path) String() string {
+	return fmt.Sprintf("m/%d'/%d'/%d'/%d'/%d'", BIP44Purpose, p.CoinType, p.Account, p.Change, p.AddressIndex)
+}
+
+// DerivedAddress is an address together with the BIP44 path it was derived from.
+type DerivedAddress struct {
+	Path    BIP44Path
+	Address iotago.DirectUnlockableAddress
+}
+
+// BIP44Path returns the path of the key manager as BIP44Path.
+func (k *KeyManager) BIP44Path() (BIP44Path, error) {
+	return BIP44PathFromBIP32Path(k.path)
+}
+
+// KeyPairForPath calculates an ed25519 key pair for the given path by using slip10.
+// The keys of the path prefixes up to the account level are cached, so only the segments below are computed.
+func (k *KeyManager) KeyPairForPath(path bip32path.Path) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	key, cached, err := k.deriveKey(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cached {
+		defer zeroExtendedKey(key)
+	}
+
+	seed, ok := key.Key.(eddsa.Seed)
+	if !ok {
+		return nil, nil, ierrors.Errorf("derived key of path %s is not an ed25519 seed", path)
+	}
+	pubKey, privKey := seed.Ed25519Key()
+
+	return ed25519.PrivateKey(privKey), ed25519.PublicKey(pubKey), nil
+}
+
+// KeyPairForBIP44Path calculates an ed25519 key pair for the given BIP44 path.
+func (k *KeyManager) KeyPairForBIP44Path(path BIP44Path) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	return k.KeyPairForPath(path.BIP32Path())
+}
+
+// AddressForBIP44Path calculates an address of the specified type for the given BIP44 path.
+func (k *KeyManager) AddressForBIP44Path(addressType iotago.AddressType, path BIP44Path) (iotago.DirectUnlockableAddress, error) {
+	_, pubKey, err := k.KeyPairForBIP44Path(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return addressFromPublicKey(addressType, pubKey), nil
+}
+
+// AddressRange calculates count addresses of the specified type, starting at the given address index,
+// for the given account and change of the coin type of the key manager's path.
+func (k *KeyManager) AddressRange(addressType iotago.AddressType, account uint32, change uint32, startIndex uint32, count uint32) ([]*DerivedAddress, error) {
+	basePath, err := k.BIP44Path()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "the coin type can't be determined from the key manager's path")
+	}
+
+	if uint64(startIndex)+uint64(count) > uint64(slip10.Hardened) {
+		return nil, ierrors.WithMessagef(ErrInvalidBIP44Path, "address range %d+%d exceeds the maximum address index", startIndex, count)
+	}
+
+	addresses := make([]*DerivedAddress, 0, count)
+	for i := uint32(0); i < count; i++ {
+		path := BIP44Path{
+			CoinType:     basePath.CoinType,
+			Account:      account,
+			Change:       change,
+			AddressIndex: startIndex + i,
+		}
+
+		address, err := k.AddressForBIP44Path(addressType, path)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, &DerivedAddress{Path: path, Address: address})
+	}
+
+	return addresses, nil
+}
+
+// AddressSignerForBIP44Paths returns an address signer holding the keys of the given BIP44 paths.
+func (k *KeyManager) AddressSignerForBIP44Paths(paths ...BIP44Path) (iotago.AddressSigner, error) {
+	privKeys := make([]ed25519.PrivateKey, 0, len(paths))
+	for _, path := range paths {
+		privKey, _, err := k.KeyPairForBIP44Path(path)
+		if err != nil {
+			return nil, err
+		}
+
+		privKeys = append(privKeys, privKey)
+	}
+
+	return iotago.NewInMemoryAddressSignerFromEd25519PrivateKeys(privKeys...), nil
+}
+
+// deriveKey derives the SLIP-10 key of the given path, starting from the longest cached prefix of the path.
+// The derived keys of the prefixes up to keyCacheDepth are cached while the cache is not full, the other derived keys are zeroed.
+// It also returns whether the key of the path is cached, otherwise the caller needs to zero it after use.
+func (k *KeyManager) deriveKey(path bip32path.Path) (*slip10.ExtendedKey, bool, error) {
+	k.keyCacheMutex.Lock()
+	defer k.keyCacheMutex.Unlock()
+
+	if k.closed {
+		return nil, false, ErrKeyManagerClosed
+	}
+
+	// find the longest prefix of the path that was already derived
+	var key *slip10.ExtendedKey
+	derivedSegments := min(len(path), keyCacheDepth)
+	for ; derivedSegments >= 0; derivedSegments-- {
+		if cachedKey, exists := k.keyCache[path[:derivedSegments].String()]; exists {
+			key = cachedKey
+			break
+		}
+	}
+
+	cached := key != nil
+	if !cached {
+		masterKey, err := slip10.NewMasterKey(k.seed, eddsa.Ed25519())
+		if err != nil {
+			return nil, false, ierrors.Wrap(err, "failed to generate master key")
+		}
+
+		key = masterKey
+		derivedSegments = 0
+		cached = k.cacheKey(path[:0], key)
+	}
+
+	for i := derivedSegments; i < len(path); i++ {
+		childKey, err := key.DeriveChild(path[i])
+		if !cached {
+			zeroExtendedKey(key)
+		}
+		if err != nil {
+			return nil, false, ierrors.Wrapf(err, "failed to derive child key of path %s", path[:i+1])
+		}
+
+		key = childKey
+		cached = k.cacheKey(path[:i+1], key)
+	}
+
+	return key, cached, nil
+}
+
+// cacheKey caches the key of the given path prefix if the prefix is not longer than keyCacheDepth and the cache is not full.
+// It returns whether the key was cached.
+func (k *KeyManager) cacheKey(pathPrefix bip32path.Path, key *slip10.ExtendedKey) bool {
+	if len(pathPrefix) > keyCacheDepth || len(k.keyCache) >= keyCacheSize {
+		return false
+	}
+	k.keyCache[pathPrefix.String()] = key
+
+	return true
+}
+
+// zeroExtendedKey overwrites the chain code and the private key of the given key with zeros.
+func zeroExtendedKey(key *slip10.ExtendedKey) {
+	zeroBytes(key.ChainCode)
+	if seed, ok := key.Key.(eddsa.Seed); ok {
+		zeroBytes(seed)
+	}
+}
@@ -0,0 +1,170 @@
+package wallet_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/iota-crypto-demo/pkg/bip32path"
+	"github.com/iotaledger/iota-crypto-demo/pkg/slip10"
+	"github.com/iotaledger/iota-crypto-demo/pkg/slip10/eddsa"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestBIP44Path(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected wallet.BIP44Path
+		err      error
+	}{
+		{
+			name:     "ok - iota",
+			path:     wallet.DefaultIOTAPath,
+			expected: wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA},
+		},
+		{
+			name:     "ok - shimmer internal",
+			path:     "m/44'/4219'/3'/1'/7'",
+			expected: wallet.BIP44Path{CoinType: wallet.CoinTypeShimmer, Account: 3, Change: wallet.BIP44ChangeInternal, AddressIndex: 7},
+		},
+		{
+			name: "err - too short",
+			path: "m/44'/4218'/0'",
+			err:  wallet.ErrInvalidBIP44Path,
+		},
+		{
+			name: "err - not hardened",
+			path: "m/44'/4218'/0'/0/0",
+			err:  wallet.ErrInvalidBIP44Path,
+		},
+		{
+			name: "err - wrong purpose",
+			path: "m/49'/4218'/0'/0'/0'",
+			err:  wallet.ErrInvalidBIP44Path,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := bip32path.ParsePath(test.path)
+			require.NoError(t, err)
+
+			bip44Path, err := wallet.BIP44PathFromBIP32Path(path)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, bip44Path)
+			require.Equal(t, path, bip44Path.BIP32Path())
+			require.Equal(t, test.path, bip44Path.String())
+		})
+	}
+}
+
+func TestKeyManagerDerivation(t *testing.T) {
+	seed := tpkg.RandBytes(64)
+
+	keyManager, err := wallet.NewKeyManager(seed, wallet.DefaultShimmerPath)
+	require.NoError(t, err)
+
+	// derives the key without the cache of the key manager
+	expectedKeyPair := func(path bip32path.Path) (ed25519.PrivateKey, ed25519.PublicKey) {
+		key, err := slip10.DeriveKeyFromPath(seed, eddsa.Ed25519(), path)
+		require.NoError(t, err)
+
+		pubKey, privKey := key.Key.(eddsa.Seed).Ed25519Key()
+
+		return ed25519.PrivateKey(privKey), ed25519.PublicKey(pubKey)
+	}
+
+	t.Run("ok - cached derivation matches plain derivation", func(t *testing.T) {
+		paths := []wallet.BIP44Path{
+			{CoinType: wallet.CoinTypeShimmer, Account: 0, Change: wallet.BIP44ChangeExternal, AddressIndex: 0},
+			{CoinType: wallet.CoinTypeShimmer, Account: 0, Change: wallet.BIP44ChangeExternal, AddressIndex: 1},
+			{CoinType: wallet.CoinTypeShimmer, Account: 0, Change: wallet.BIP44ChangeInternal, AddressIndex: 0},
+			{CoinType: wallet.CoinTypeShimmer, Account: 5, Change: wallet.BIP44ChangeExternal, AddressIndex: 0},
+			{CoinType: wallet.CoinTypeIOTA, Account: 0, Change: wallet.BIP44ChangeExternal, AddressIndex: 0},
+		}
+
+		// derive every path twice to hit the cache on the second run
+		for range 2 {
+			for _, path := range paths {
+				expectedPrivKey, expectedPubKey := expectedKeyPair(path.BIP32Path())
+
+				privKey, pubKey, err := keyManager.KeyPairForBIP44Path(path)
+				require.NoError(t, err)
+				require.Equal(t, expectedPrivKey, privKey)
+				require.Equal(t, expectedPubKey, pubKey)
+			}
+		}
+	})
+
+	t.Run("ok - more accounts than the cache holds", func(t *testing.T) {
+		// the keys that don't fit into the cache are derived from scratch and zeroed after use
+		for range 2 {
+			for account := range uint32(100) {
+				path := wallet.BIP44Path{CoinType: wallet.CoinTypeShimmer, Account: account, Change: wallet.BIP44ChangeExternal, AddressIndex: account}
+				expectedPrivKey, _ := expectedKeyPair(path.BIP32Path())
+
+				privKey, _, err := keyManager.KeyPairForBIP44Path(path)
+				require.NoError(t, err)
+				require.Equal(t, expectedPrivKey, privKey)
+			}
+		}
+	})
+
+	t.Run("ok - arbitrary hardened path", func(t *testing.T) {
+		path, err := bip32path.ParsePath("m/1'/2'")
+		require.NoError(t, err)
+
+		expectedPrivKey, _ := expectedKeyPair(path)
+		privKey, _, err := keyManager.KeyPairForPath(path)
+		require.NoError(t, err)
+		require.Equal(t, expectedPrivKey, privKey)
+	})
+
+	t.Run("ok - address range", func(t *testing.T) {
+		addresses, err := keyManager.AddressRange(iotago.AddressEd25519, 0, wallet.BIP44ChangeExternal, 2, 3)
+		require.NoError(t, err)
+		require.Len(t, addresses, 3)
+
+		for i, address := range addresses {
+			require.Equal(t, wallet.BIP44Path{CoinType: wallet.CoinTypeShimmer, AddressIndex: uint32(2 + i)}, address.Path)
+			require.Equal(t, keyManager.Address(iotago.AddressEd25519, uint32(2+i)), address.Address)
+		}
+
+		internalAddresses, err := keyManager.AddressRange(iotago.AddressImplicitAccountCreation, 0, wallet.BIP44ChangeInternal, 0, 1)
+		require.NoError(t, err)
+
+		_, expectedPubKey := expectedKeyPair(internalAddresses[0].Path.BIP32Path())
+		require.Equal(t, iotago.ImplicitAccountCreationAddressFromPubKey(expectedPubKey), internalAddresses[0].Address)
+	})
+
+	t.Run("ok - address signer", func(t *testing.T) {
+		path := wallet.BIP44Path{CoinType: wallet.CoinTypeShimmer, Account: 1, Change: wallet.BIP44ChangeInternal, AddressIndex: 4}
+		address, err := keyManager.AddressForBIP44Path(iotago.AddressEd25519, path)
+		require.NoError(t, err)
+
+		signer, err := keyManager.AddressSignerForBIP44Paths(path)
+		require.NoError(t, err)
+
+		_, err = signer.Sign(address, []byte("message"))
+		require.NoError(t, err)
+	})
+
+	t.Run("err - closed", func(t *testing.T) {
+		closedKeyManager, err := wallet.NewKeyManager(tpkg.RandBytes(64), wallet.DefaultShimmerPath)
+		require.NoError(t, err)
+		closedKeyManager.Close()
+
+		_, err = closedKeyManager.AddressRange(iotago.AddressEd25519, 0, 0, 0, 1)
+		require.ErrorIs(t, err, wallet.ErrKeyManagerClosed)
+	})
+}
@@ -0,0 +1,166 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+)
+
+// DefaultDiscoveryGapLimit is the default number of consecutive unused address indexes after which the discovery stops.
+const DefaultDiscoveryGapLimit = 20
+
+// DiscoveredAddress is a derived address for which outputs were found.
+type DiscoveredAddress struct {
+	*DerivedAddress
+
+	// The unspent outputs owned by the address.
+	Outputs iotago.OutputSet
+}
+
+// DiscoveryOptions are the options used for the address discovery.
+type DiscoveryOptions struct {
+	gapLimit      uint32
+	account       uint32
+	changes       []uint32
+	addressTypes  []iotago.AddressType
+	startingIndex uint32
+}
+
+// WithDiscoveryGapLimit sets the number of consecutive unused address indexes after which the discovery of a chain stops.
+func WithDiscoveryGapLimit(gapLimit uint32) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.gapLimit = gapLimit
+	}
+}
+
+// WithDiscoveryAccount sets the BIP44 account whose addresses are discovered.
+func WithDiscoveryAccount(account uint32) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.account = account
+	}
+}
+
+// WithDiscoveryChanges sets the BIP44 chains whose addresses are discovered.
+func WithDiscoveryChanges(changes ...uint32) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.changes = changes
+	}
+}
+
+// WithDiscoveryAddressTypes sets the address types that are derived for every address index.
+func WithDiscoveryAddressTypes(addressTypes ...iotago.AddressType) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.addressTypes = addressTypes
+	}
+}
+
+// WithDiscoveryStartingIndex sets the address index at which the discovery starts.
+func WithDiscoveryStartingIndex(startingIndex uint32) options.Option[DiscoveryOptions] {
+	return func(opts *DiscoveryOptions) {
+		opts.startingIndex = startingIndex
+	}
+}
+
+// DiscoverAddresses derives the addresses of the key manager and queries the indexer for their outputs.
+// The discovery of every chain stops after gap limit consecutive address indexes without any outputs.
+// By default, the Ed25519 and ImplicitAccountCreation addresses of the external and internal chain of account 0 are discovered.
+// NOTE: The indexer only knows about unspent outputs, so addresses whose outputs were all spent are considered unused.
+func DiscoverAddresses(ctx context.Context, indexer nodeclient.IndexerClient, keyManager *KeyManager, hrp iotago.NetworkPrefix, opts ...options.Option[DiscoveryOptions]) ([]*DiscoveredAddress, error) {
+	discoveryOpts := options.Apply(&DiscoveryOptions{
+		gapLimit:     DefaultDiscoveryGapLimit,
+		changes:      []uint32{BIP44ChangeExternal, BIP44ChangeInternal},
+		addressTypes: []iotago.AddressType{iotago.AddressEd25519, iotago.AddressImplicitAccountCreation},
+	}, opts)
+
+	if discoveryOpts.gapLimit == 0 {
+		return nil, ierrors.New("discovery gap limit must be greater than zero")
+	}
+
+	basePath, err := keyManager.BIP44Path()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "the coin type can't be determined from the key manager's path")
+	}
+
+	discoveredAddresses := make([]*DiscoveredAddress, 0)
+	for _, change := range discoveryOpts.changes {
+		var unusedIndexes uint32
+		for addressIndex := discoveryOpts.startingIndex; unusedIndexes < discoveryOpts.gapLimit; addressIndex++ {
+			path := BIP44Path{
+				CoinType:     basePath.CoinType,
+				Account:      discoveryOpts.account,
+				Change:       change,
+				AddressIndex: addressIndex,
+			}
+
+			used := false
+			for _, addressType := range discoveryOpts.addressTypes {
+				address, err := keyManager.AddressForBIP44Path(addressType, path)
+				if err != nil {
+					return nil, err
+				}
+
+				outputs, err := addressOutputs(ctx, indexer, address.Bech32(hrp))
+				if err != nil {
+					return nil, ierrors.Wrapf(err, "failed to query outputs of address %s at path %s", address.Bech32(hrp), path)
+				}
+
+				if len(outputs) == 0 {
+					continue
+				}
+
+				used = true
+				discoveredAddresses = append(discoveredAddresses, &DiscoveredAddress{
+					DerivedAddress: &DerivedAddress{Path: path, Address: address},
+					Outputs:        outputs,
+				})
+			}
+
+			if used {
+				unusedIndexes = 0
+			} else {
+				unusedIndexes++
+			}
+		}
+	}
+
+	return discoveredAddresses, nil
+}
+
+// addressOutputs queries the indexer for all outputs that can be unlocked by the given address,
+// as well as the basic outputs owned by the address that are currently locked by timelocks or expirations.
+func addressOutputs(ctx context.Context, indexer nodeclient.IndexerClient, addressBech32 string) (iotago.OutputSet, error) {
+	outputs := make(iotago.OutputSet)
+
+	for _, query := range []nodeclient.IndexerQuery{
+		&api.OutputsQuery{IndexerUnlockableByAddressParams: api.IndexerUnlockableByAddressParams{UnlockableByAddressBech32: addressBech32}},
+		&api.BasicOutputsQuery{AddressBech32: addressBech32},
+	} {
+		resultSet, err := indexer.Outputs(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for resultSet.Next() {
+			outputIDs := resultSet.Response.Items.MustOutputIDs()
+
+			pageOutputs, err := resultSet.Outputs(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			for i, output := range pageOutputs {
+				outputs[outputIDs[i]] = output
+			}
+		}
+
+		if resultSet.Error != nil {
+			return nil, resultSet.Error
+		}
+	}
+
+	return outputs, nil
+}
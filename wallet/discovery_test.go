@@ -0,0 +1,162 @@
+package wallet_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+const nodeAPIUrl = "http://127.0.0.1:14265"
+
+var mockAPI = iotago.V3API(tpkg.IOTAMainnetV3TestProtocolParameters)
+
+func mockGetJSON(route string, body interface{}) *gock.Request {
+	m := gock.New(nodeAPIUrl).Get(route)
+	m.Reply(200).
+		SetHeader("Content-Type", api.MIMEApplicationJSON).
+		BodyString(string(lo.PanicOnErr(mockAPI.JSONEncode(body))))
+
+	return m
+}
+
+func mockIndexerOutputs(route string, key string, value string, outputIDs ...iotago.OutputID) {
+	mockGetJSON(route, &api.IndexerResponse{Items: iotago.HexOutputIDsFromOutputIDs(outputIDs...), PageSize: 1000}).
+		MatchParam(key, value).
+		Persist()
+}
+
+// mockOutput mocks the output endpoint for a new random transaction creating the given output.
+func mockOutput(output iotago.Output) iotago.OutputID {
+	outputIDProof := lo.PanicOnErr(iotago.NewOutputIDProof(mockAPI, tpkg.Rand32ByteArray(), tpkg.RandSlot(), iotago.TxEssenceOutputs{output}, 0))
+	outputID := lo.PanicOnErr(outputIDProof.OutputID(output))
+
+	gock.New(nodeAPIUrl).
+		Get(api.EndpointWithNamedParameterValue(api.CoreRouteOutput, api.ParameterOutputID, outputID.ToHex())).
+		Persist().
+		Reply(200).
+		SetHeader("Content-Type", api.MIMEApplicationVendorIOTASerializerV2).
+		BodyString(string(lo.PanicOnErr(mockAPI.Encode(&api.OutputResponse{Output: output, OutputIDProof: outputIDProof}))))
+
+	return outputID
+}
+
+// mockInfo mocks a single request to the info endpoint with the given latest commitment.
+func mockInfo(latestCommitmentID iotago.CommitmentID) {
+	ts := time.Now()
+	mockGetJSON(api.CoreRouteInfo, &api.InfoResponse{
+		Name:    "iota-core",
+		Version: "1.0.0",
+		Status: &api.InfoResNodeStatus{
+			IsHealthy:                   true,
+			AcceptedTangleTime:          ts,
+			RelativeAcceptedTangleTime:  ts,
+			ConfirmedTangleTime:         ts,
+			RelativeConfirmedTangleTime: ts,
+			LatestCommitmentID:          latestCommitmentID,
+		},
+		ProtocolParameters: []*api.InfoResProtocolParameters{
+			{
+				StartEpoch: 0,
+				Parameters: tpkg.IOTAMainnetV3TestProtocolParameters,
+			},
+		},
+		BaseToken: &api.InfoResBaseToken{Name: "TestCoin", TickerSymbol: "TEST", Unit: "TEST", Decimals: 6},
+	})
+}
+
+//nolint:thelper
+func nodeClient(t *testing.T, plugins ...string) *nodeclient.Client {
+	mockInfo(tpkg.Rand36ByteArray())
+
+	routes := make([]iotago.PrefixedStringUint8, 0, len(plugins))
+	for _, plugin := range plugins {
+		routes = append(routes, iotago.PrefixedStringUint8(plugin))
+	}
+	mockGetJSON(api.RouteRoutes, &api.RoutesResponse{Routes: routes}).Persist()
+
+	client, err := nodeclient.New(nodeAPIUrl)
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestDiscoverAddresses(t *testing.T) {
+	defer gock.Off()
+
+	hrp := mockAPI.ProtocolParameters().Bech32HRP()
+
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	address := func(addressType iotago.AddressType, change uint32, addressIndex uint32) iotago.DirectUnlockableAddress {
+		return lo.PanicOnErr(keyManager.AddressForBIP44Path(addressType, wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA, Change: change, AddressIndex: addressIndex}))
+	}
+
+	basicOutput := func(address iotago.Address) *iotago.BasicOutput {
+		return &iotago.BasicOutput{
+			Amount:           1_000_000,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: address}},
+		}
+	}
+
+	var (
+		firstAddress    = address(iotago.AddressEd25519, wallet.BIP44ChangeExternal, 0)
+		implicitAddress = address(iotago.AddressImplicitAccountCreation, wallet.BIP44ChangeExternal, 3)
+		beyondGap       = address(iotago.AddressEd25519, wallet.BIP44ChangeExternal, 9)
+		internalAddress = address(iotago.AddressEd25519, wallet.BIP44ChangeInternal, 1)
+
+		firstOutputID     = mockOutput(basicOutput(firstAddress))
+		timelockedOutput  = basicOutput(firstAddress)
+		implicitOutputID  = mockOutput(basicOutput(implicitAddress))
+		beyondGapOutputID = mockOutput(basicOutput(beyondGap))
+		internalOutputID  = mockOutput(basicOutput(internalAddress))
+	)
+	timelockedOutput.UnlockConditions.Upsert(&iotago.TimelockUnlockCondition{Slot: 1000})
+	timelockedOutputID := mockOutput(timelockedOutput)
+
+	client := nodeClient(t, api.IndexerPluginName)
+
+	// the timelocked output can't be unlocked yet, so it's only returned by the basic outputs query
+	mockIndexerOutputs(api.IndexerRouteOutputs, "unlockableByAddress", firstAddress.Bech32(hrp), firstOutputID)
+	mockIndexerOutputs(api.IndexerRouteOutputsBasic, "address", firstAddress.Bech32(hrp), firstOutputID, timelockedOutputID)
+	mockIndexerOutputs(api.IndexerRouteOutputs, "unlockableByAddress", implicitAddress.Bech32(hrp), implicitOutputID)
+	mockIndexerOutputs(api.IndexerRouteOutputs, "unlockableByAddress", beyondGap.Bech32(hrp), beyondGapOutputID)
+	mockIndexerOutputs(api.IndexerRouteOutputs, "unlockableByAddress", internalAddress.Bech32(hrp), internalOutputID)
+
+	// all other addresses are unused
+	mockGetJSON(api.IndexerRouteOutputs, &api.IndexerResponse{Items: iotago.HexOutputIDs{}}).Persist()
+	mockGetJSON(api.IndexerRouteOutputsBasic, &api.IndexerResponse{Items: iotago.HexOutputIDs{}}).Persist()
+
+	indexer, err := client.Indexer(context.Background())
+	require.NoError(t, err)
+
+	discoveredAddresses, err := wallet.DiscoverAddresses(context.Background(), indexer, keyManager, hrp, wallet.WithDiscoveryGapLimit(5))
+	require.NoError(t, err)
+	require.Len(t, discoveredAddresses, 3)
+
+	require.Equal(t, firstAddress, discoveredAddresses[0].Address)
+	require.Equal(t, wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA}, discoveredAddresses[0].Path)
+	require.ElementsMatch(t, []iotago.OutputID{firstOutputID, timelockedOutputID}, lo.Keys(discoveredAddresses[0].Outputs))
+
+	require.Equal(t, implicitAddress, discoveredAddresses[1].Address)
+	require.Equal(t, []iotago.OutputID{implicitOutputID}, lo.Keys(discoveredAddresses[1].Outputs))
+
+	require.Equal(t, internalAddress, discoveredAddresses[2].Address)
+	require.Equal(t, wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA, Change: wallet.BIP44ChangeInternal, AddressIndex: 1}, discoveredAddresses[2].Path)
+
+	// with a larger gap limit, the address beyond the gap is found as well
+	discoveredAddresses, err = wallet.DiscoverAddresses(context.Background(), indexer, keyManager, hrp, wallet.WithDiscoveryGapLimit(6), wallet.WithDiscoveryChanges(wallet.BIP44ChangeExternal))
+	require.NoError(t, err)
+	require.Len(t, discoveredAddresses, 3)
+	require.Equal(t, beyondGap, discoveredAddresses[2].Address)
+}
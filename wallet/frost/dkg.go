@@ -0,0 +1,241 @@
+package frost
+
+import (
+	"encoding/binary"
+	"slices"
+
+	"filippo.io/edwards25519"
+
+	"github.com/iotaledger/hive.go/ierrors"
+)
+
+var (
+	// ErrInvalidProofOfKnowledge gets returned when the proof of knowledge of a participant's secret is invalid.
+	ErrInvalidProofOfKnowledge = ierrors.New("invalid proof of knowledge")
+	// ErrInvalidSecretShare gets returned when a secret share doesn't match the commitment of its sender.
+	ErrInvalidSecretShare = ierrors.New("invalid secret share")
+)
+
+// DKGRound1Secret is the secret state of a participant after the first round of the distributed key generation.
+// It must not be shared with other participants.
+type DKGRound1Secret struct {
+	identifier   Identifier
+	maxSigners   uint16
+	minSigners   uint16
+	coefficients []*edwards25519.Scalar
+	commitment   []*edwards25519.Point
+}
+
+// DKGRound1Package is broadcast to all other participants in the first round of the distributed key generation.
+type DKGRound1Package struct {
+	// The identifier of the sender.
+	Identifier Identifier
+	// The commitment to the coefficients of the sender's secret polynomial.
+	Commitment []*edwards25519.Point
+	// The commitment of the proof of knowledge of the sender's secret.
+	ProofOfKnowledgeR *edwards25519.Point
+	// The response of the proof of knowledge of the sender's secret.
+	ProofOfKnowledgeZ *edwards25519.Scalar
+}
+
+// DKGRound2Secret is the secret state of a participant after the second round of the distributed key generation.
+// It must not be shared with other participants.
+type DKGRound2Secret struct {
+	identifier   Identifier
+	maxSigners   uint16
+	minSigners   uint16
+	coefficients []*edwards25519.Scalar
+	commitments  map[Identifier][]*edwards25519.Point
+}
+
+// DKGRound2Package is sent to a single other participant over a confidential and authenticated channel
+// in the second round of the distributed key generation.
+type DKGRound2Package struct {
+	// The identifier of the sender.
+	Sender Identifier
+	// The identifier of the receiver.
+	Receiver Identifier
+	// The secret share of the receiver, which is the sender's secret polynomial evaluated at the receiver's identifier.
+	SigningShare *edwards25519.Scalar
+}
+
+// DKGRound1 starts the distributed key generation for the participant with the given identifier,
+// in a group of maxSigners participants of which minSigners are needed to produce a signature.
+// The returned package needs to be broadcast to all other participants.
+func DKGRound1(identifier Identifier, maxSigners uint16, minSigners uint16) (*DKGRound1Secret, *DKGRound1Package, error) {
+	if identifier == 0 {
+		return nil, nil, ierrors.WithMessage(ErrInvalidParameters, "identifier must not be zero")
+	}
+	if minSigners < 2 || minSigners > maxSigners {
+		return nil, nil, ierrors.WithMessagef(ErrInvalidParameters, "minSigners %d must be between 2 and maxSigners %d", minSigners, maxSigners)
+	}
+
+	coefficients := make([]*edwards25519.Scalar, minSigners)
+	commitment := make([]*edwards25519.Point, minSigners)
+	for i := range coefficients {
+		coefficient, err := randomScalar()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		coefficients[i] = coefficient
+		commitment[i] = edwards25519.NewIdentityPoint().ScalarBaseMult(coefficient)
+	}
+
+	// prove the knowledge of the secret to prevent rogue key attacks
+	k, err := randomScalar()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zeroScalar(k)
+
+	proofR := edwards25519.NewIdentityPoint().ScalarBaseMult(k)
+	challenge := dkgChallenge(identifier, commitment[0], proofR)
+	proofZ := edwards25519.NewScalar().MultiplyAdd(coefficients[0], challenge, k)
+
+	return &DKGRound1Secret{
+		identifier:   identifier,
+		maxSigners:   maxSigners,
+		minSigners:   minSigners,
+		coefficients: coefficients,
+		commitment:   commitment,
+	}, &DKGRound1Package{
+		Identifier:        identifier,
+		Commitment:        commitment,
+		ProofOfKnowledgeR: proofR,
+		ProofOfKnowledgeZ: proofZ,
+	}, nil
+}
+
+// DKGRound2 verifies the round 1 packages of all other participants and computes their secret shares.
+// Every returned package needs to be sent to its receiver only.
+func DKGRound2(secret *DKGRound1Secret, round1Packages []*DKGRound1Package) (*DKGRound2Secret, []*DKGRound2Package, error) {
+	if len(round1Packages) != int(secret.maxSigners)-1 {
+		return nil, nil, ierrors.WithMessagef(ErrInvalidParticipants, "expected %d round 1 packages, got %d", secret.maxSigners-1, len(round1Packages))
+	}
+
+	commitments := map[Identifier][]*edwards25519.Point{secret.identifier: secret.commitment}
+	for _, round1Package := range round1Packages {
+		if round1Package.Identifier == 0 {
+			return nil, nil, ierrors.WithMessage(ErrInvalidParticipants, "identifier must not be zero")
+		}
+		if _, exists := commitments[round1Package.Identifier]; exists {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidParticipants, "duplicate round 1 package of participant %d", round1Package.Identifier)
+		}
+		if len(round1Package.Commitment) != int(secret.minSigners) || slices.Contains(round1Package.Commitment, nil) {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidParticipants, "commitment of participant %d must have %d coefficients", round1Package.Identifier, secret.minSigners)
+		}
+		if round1Package.ProofOfKnowledgeR == nil || round1Package.ProofOfKnowledgeZ == nil {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidProofOfKnowledge, "participant %d sent no proof", round1Package.Identifier)
+		}
+
+		// G * z == R + C_0 * c
+		challenge := dkgChallenge(round1Package.Identifier, round1Package.Commitment[0], round1Package.ProofOfKnowledgeR)
+		expectedR := edwards25519.NewIdentityPoint().VarTimeDoubleScalarBaseMult(
+			edwards25519.NewScalar().Negate(challenge), round1Package.Commitment[0], round1Package.ProofOfKnowledgeZ,
+		)
+		if expectedR.Equal(round1Package.ProofOfKnowledgeR) != 1 {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidProofOfKnowledge, "participant %d", round1Package.Identifier)
+		}
+
+		commitments[round1Package.Identifier] = round1Package.Commitment
+	}
+
+	round2Packages := make([]*DKGRound2Package, 0, len(round1Packages))
+	for _, round1Package := range round1Packages {
+		round2Packages = append(round2Packages, &DKGRound2Package{
+			Sender:       secret.identifier,
+			Receiver:     round1Package.Identifier,
+			SigningShare: evaluatePolynomial(secret.coefficients, round1Package.Identifier.scalar()),
+		})
+	}
+
+	return &DKGRound2Secret{
+		identifier:   secret.identifier,
+		maxSigners:   secret.maxSigners,
+		minSigners:   secret.minSigners,
+		coefficients: secret.coefficients,
+		commitments:  commitments,
+	}, round2Packages, nil
+}
+
+// DKGFinalize verifies the secret shares received from all other participants and computes the key package
+// of the participant as well as the public key package of the group. The secret state is cleared afterwards.
+func DKGFinalize(secret *DKGRound2Secret, round2Packages []*DKGRound2Package) (*KeyPackage, *PublicKeyPackage, error) {
+	if len(round2Packages) != int(secret.maxSigners)-1 {
+		return nil, nil, ierrors.WithMessagef(ErrInvalidParticipants, "expected %d round 2 packages, got %d", secret.maxSigners-1, len(round2Packages))
+	}
+
+	x := secret.identifier.scalar()
+	signingShare := evaluatePolynomial(secret.coefficients, x)
+
+	received := make(map[Identifier]struct{}, len(round2Packages))
+	for _, round2Package := range round2Packages {
+		if round2Package.Receiver != secret.identifier {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidParticipants, "round 2 package of participant %d is addressed to participant %d", round2Package.Sender, round2Package.Receiver)
+		}
+
+		commitment, known := secret.commitments[round2Package.Sender]
+		if !known || round2Package.Sender == secret.identifier {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidParticipants, "unexpected round 2 package of participant %d", round2Package.Sender)
+		}
+		if round2Package.SigningShare == nil {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidSecretShare, "participant %d sent no share", round2Package.Sender)
+		}
+		if _, exists := received[round2Package.Sender]; exists {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidParticipants, "duplicate round 2 package of participant %d", round2Package.Sender)
+		}
+		received[round2Package.Sender] = struct{}{}
+
+		if edwards25519.NewIdentityPoint().ScalarBaseMult(round2Package.SigningShare).Equal(evaluateCommitment(commitment, x)) != 1 {
+			return nil, nil, ierrors.WithMessagef(ErrInvalidSecretShare, "participant %d", round2Package.Sender)
+		}
+
+		signingShare.Add(signingShare, round2Package.SigningShare)
+	}
+
+	identifiers := make([]Identifier, 0, len(secret.commitments))
+	for identifier := range secret.commitments {
+		identifiers = append(identifiers, identifier)
+	}
+	slices.Sort(identifiers)
+
+	groupPublicKey := edwards25519.NewIdentityPoint()
+	for _, identifier := range identifiers {
+		groupPublicKey.Add(groupPublicKey, secret.commitments[identifier][0])
+	}
+
+	verifyingShares := make(map[Identifier]*edwards25519.Point, len(identifiers))
+	for _, identifier := range identifiers {
+		verifyingShare := edwards25519.NewIdentityPoint()
+		for _, commitment := range secret.commitments {
+			verifyingShare.Add(verifyingShare, evaluateCommitment(commitment, identifier.scalar()))
+		}
+		verifyingShares[identifier] = verifyingShare
+	}
+
+	for _, coefficient := range secret.coefficients {
+		zeroScalar(coefficient)
+	}
+	secret.coefficients = nil
+
+	return &KeyPackage{
+		Identifier:     secret.identifier,
+		SigningShare:   signingShare,
+		VerifyingShare: verifyingShares[secret.identifier],
+		GroupPublicKey: groupPublicKey,
+		MinSigners:     secret.minSigners,
+	}, &PublicKeyPackage{
+		VerifyingShares: verifyingShares,
+		GroupPublicKey:  groupPublicKey,
+		MinSigners:      secret.minSigners,
+	}, nil
+}
+
+// dkgChallenge computes the challenge of the proof of knowledge of a participant's secret.
+func dkgChallenge(identifier Identifier, verifyingKey *edwards25519.Point, r *edwards25519.Point) *edwards25519.Scalar {
+	var identifierBytes [2]byte
+	binary.LittleEndian.PutUint16(identifierBytes[:], uint16(identifier))
+
+	return hashToScalar([]byte(contextString+"dkg"), identifierBytes[:], verifyingKey.Bytes(), r.Bytes())
+}
@@ -0,0 +1,159 @@
+// Package frost implements FROST threshold signatures for Ed25519 as specified in RFC 9591 (FROST(Ed25519, SHA-512)),
+// together with the distributed key generation of the original FROST paper.
+//
+// A group of participants jointly controls a single Ed25519 key without the key ever being assembled,
+// any minSigners of them can produce a standard Ed25519 signature that verifies against the group public key.
+package frost
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+
+	"filippo.io/edwards25519"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+// contextString is the context string of the FROST(Ed25519, SHA-512) ciphersuite.
+const contextString = "FROST-ED25519-SHA512-v1"
+
+// scalarOne is the scalar with value one.
+var scalarOne = Identifier(1).scalar()
+
+var (
+	// ErrInvalidParameters gets returned when the identifier or the threshold parameters are invalid.
+	ErrInvalidParameters = ierrors.New("invalid FROST parameters")
+	// ErrInvalidParticipants gets returned when the set of participants of a protocol step is invalid.
+	ErrInvalidParticipants = ierrors.New("invalid participants")
+)
+
+// Identifier identifies a participant, it must not be zero.
+type Identifier uint16
+
+// scalar returns the identifier as scalar.
+func (i Identifier) scalar() *edwards25519.Scalar {
+	var b [32]byte
+	binary.LittleEndian.PutUint16(b[:], uint16(i))
+
+	// a little-endian uint16 is always a canonical scalar
+	s, _ := edwards25519.NewScalar().SetCanonicalBytes(b[:])
+
+	return s
+}
+
+// KeyPackage holds the signing share of a participant together with the public information of the group.
+type KeyPackage struct {
+	// The identifier of the participant.
+	Identifier Identifier
+	// The secret signing share of the participant.
+	SigningShare *edwards25519.Scalar
+	// The public verifying share of the participant.
+	VerifyingShare *edwards25519.Point
+	// The public key of the group.
+	GroupPublicKey *edwards25519.Point
+	// The minimum number of participants needed to produce a signature.
+	MinSigners uint16
+}
+
+// PublicKeyPackage holds the public information of the group, used to aggregate the signature shares.
+type PublicKeyPackage struct {
+	// The public verifying shares of all participants.
+	VerifyingShares map[Identifier]*edwards25519.Point
+	// The public key of the group.
+	GroupPublicKey *edwards25519.Point
+	// The minimum number of participants needed to produce a signature.
+	MinSigners uint16
+}
+
+// PublicKey returns the group public key as Ed25519 public key.
+func (p *PublicKeyPackage) PublicKey() ed25519.PublicKey {
+	return p.GroupPublicKey.Bytes()
+}
+
+// Ed25519Address returns the Ed25519Address of the group public key.
+func (p *PublicKeyPackage) Ed25519Address() *iotago.Ed25519Address {
+	return iotago.Ed25519AddressFromPubKey(p.PublicKey())
+}
+
+// randomScalar returns a uniformly distributed random scalar.
+func randomScalar() (*edwards25519.Scalar, error) {
+	var b [64]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, ierrors.Wrap(err, "failed to read randomness")
+	}
+
+	return edwards25519.NewScalar().SetUniformBytes(b[:])
+}
+
+// hashToScalar hashes the given inputs with SHA-512 and reduces the digest to a scalar.
+func hashToScalar(inputs ...[]byte) *edwards25519.Scalar {
+	h := sha512.New()
+	for _, input := range inputs {
+		h.Write(input)
+	}
+
+	// a SHA-512 digest always has the expected length of 64 bytes
+	s, _ := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+
+	return s
+}
+
+// hash hashes the given inputs with SHA-512.
+func hash(inputs ...[]byte) []byte {
+	h := sha512.New()
+	for _, input := range inputs {
+		h.Write(input)
+	}
+
+	return h.Sum(nil)
+}
+
+// evaluatePolynomial evaluates the polynomial with the given coefficients at x.
+func evaluatePolynomial(coefficients []*edwards25519.Scalar, x *edwards25519.Scalar) *edwards25519.Scalar {
+	result := edwards25519.NewScalar()
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result.MultiplyAdd(result, x, coefficients[i])
+	}
+
+	return result
+}
+
+// evaluateCommitment evaluates the commitment to the coefficients of a polynomial at x,
+// which results in the commitment to the evaluation of the polynomial at x.
+func evaluateCommitment(commitment []*edwards25519.Point, x *edwards25519.Scalar) *edwards25519.Point {
+	result := edwards25519.NewIdentityPoint()
+	for i := len(commitment) - 1; i >= 0; i-- {
+		result.ScalarMult(x, result)
+		result.Add(result, commitment[i])
+	}
+
+	return result
+}
+
+// lagrangeCoefficient returns the Lagrange coefficient of the given identifier at x = 0 for the given set of identifiers.
+func lagrangeCoefficient(identifier Identifier, identifiers []Identifier) *edwards25519.Scalar {
+	numerator := edwards25519.NewScalar().Set(scalarOne)
+	denominator := edwards25519.NewScalar().Set(scalarOne)
+
+	x := identifier.scalar()
+	for _, other := range identifiers {
+		if other == identifier {
+			continue
+		}
+
+		numerator.Multiply(numerator, other.scalar())
+		denominator.Multiply(denominator, edwards25519.NewScalar().Subtract(other.scalar(), x))
+	}
+
+	return numerator.Multiply(numerator, edwards25519.NewScalar().Invert(denominator))
+}
+
+// zeroScalar overwrites the value of the given scalar.
+func zeroScalar(s *edwards25519.Scalar) {
+	if s != nil {
+		s.Set(edwards25519.NewScalar())
+	}
+}
@@ -0,0 +1,352 @@
+package frost_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet/frost"
+)
+
+// dkgRound1 runs the first round of the distributed key generation for all participants.
+func dkgRound1(t *testing.T, maxSigners uint16, minSigners uint16) ([]*frost.DKGRound1Secret, []*frost.DKGRound1Package) {
+	t.Helper()
+
+	secrets := make([]*frost.DKGRound1Secret, 0, maxSigners)
+	packages := make([]*frost.DKGRound1Package, 0, maxSigners)
+	for i := uint16(1); i <= maxSigners; i++ {
+		secret, round1Package, err := frost.DKGRound1(frost.Identifier(i), maxSigners, minSigners)
+		require.NoError(t, err)
+
+		secrets = append(secrets, secret)
+		packages = append(packages, round1Package)
+	}
+
+	return secrets, packages
+}
+
+// othersRound1Packages returns the round 1 packages of all participants except the one at index i.
+func othersRound1Packages(packages []*frost.DKGRound1Package, i int) []*frost.DKGRound1Package {
+	others := make([]*frost.DKGRound1Package, 0, len(packages)-1)
+	others = append(others, packages[:i]...)
+
+	return append(others, packages[i+1:]...)
+}
+
+// runDKG runs the distributed key generation between all participants.
+func runDKG(t *testing.T, maxSigners uint16, minSigners uint16) ([]*frost.KeyPackage, *frost.PublicKeyPackage) {
+	t.Helper()
+
+	round1Secrets, round1Packages := dkgRound1(t, maxSigners, minSigners)
+
+	round2Secrets := make([]*frost.DKGRound2Secret, 0, maxSigners)
+	round2PackagesByReceiver := make(map[frost.Identifier][]*frost.DKGRound2Package)
+	for i, round1Secret := range round1Secrets {
+		round2Secret, round2Packages, err := frost.DKGRound2(round1Secret, othersRound1Packages(round1Packages, i))
+		require.NoError(t, err)
+
+		round2Secrets = append(round2Secrets, round2Secret)
+		for _, round2Package := range round2Packages {
+			round2PackagesByReceiver[round2Package.Receiver] = append(round2PackagesByReceiver[round2Package.Receiver], round2Package)
+		}
+	}
+
+	var publicKeyPackage *frost.PublicKeyPackage
+	keyPackages := make([]*frost.KeyPackage, 0, maxSigners)
+	for i, round2Secret := range round2Secrets {
+		keyPackage, participantPublicKeyPackage, err := frost.DKGFinalize(round2Secret, round2PackagesByReceiver[frost.Identifier(i+1)])
+		require.NoError(t, err)
+
+		// all participants need to agree on the public key package
+		if publicKeyPackage != nil {
+			require.Equal(t, publicKeyPackage.PublicKey(), participantPublicKeyPackage.PublicKey())
+			for identifier, verifyingShare := range publicKeyPackage.VerifyingShares {
+				require.Equal(t, 1, verifyingShare.Equal(participantPublicKeyPackage.VerifyingShares[identifier]))
+			}
+		}
+		publicKeyPackage = participantPublicKeyPackage
+
+		keyPackages = append(keyPackages, keyPackage)
+	}
+
+	return keyPackages, publicKeyPackage
+}
+
+// sign runs the signing protocol between the given signers.
+func sign(t *testing.T, publicKeyPackage *frost.PublicKeyPackage, signers []*frost.KeyPackage, message []byte) (*iotago.Ed25519Signature, error) {
+	t.Helper()
+
+	nonces := make([]*frost.SigningNonces, 0, len(signers))
+	commitments := make([]*frost.SigningCommitments, 0, len(signers))
+	for _, signer := range signers {
+		signerNonces, err := frost.Commit(signer)
+		require.NoError(t, err)
+
+		nonces = append(nonces, signerNonces)
+		commitments = append(commitments, signerNonces.Commitments())
+	}
+
+	signatureShares := make([]*frost.SignatureShare, 0, len(signers))
+	for i, signer := range signers {
+		signatureShare, err := frost.Sign(signer, nonces[i], message, commitments)
+		require.NoError(t, err)
+
+		signatureShares = append(signatureShares, signatureShare)
+	}
+
+	return frost.Aggregate(publicKeyPackage, message, commitments, signatureShares)
+}
+
+func TestDKG(t *testing.T) {
+	keyPackages, publicKeyPackage := runDKG(t, 5, 3)
+	require.Len(t, publicKeyPackage.VerifyingShares, 5)
+
+	for _, keyPackage := range keyPackages {
+		require.Equal(t, 1, edwards25519.NewIdentityPoint().ScalarBaseMult(keyPackage.SigningShare).Equal(keyPackage.VerifyingShare))
+		require.Equal(t, 1, keyPackage.VerifyingShare.Equal(publicKeyPackage.VerifyingShares[keyPackage.Identifier]))
+		require.Equal(t, 1, keyPackage.GroupPublicKey.Equal(publicKeyPackage.GroupPublicKey))
+	}
+
+	t.Run("err - invalid parameters", func(t *testing.T) {
+		_, _, err := frost.DKGRound1(0, 3, 2)
+		require.ErrorIs(t, err, frost.ErrInvalidParameters)
+
+		_, _, err = frost.DKGRound1(1, 3, 4)
+		require.ErrorIs(t, err, frost.ErrInvalidParameters)
+
+		_, _, err = frost.DKGRound1(1, 3, 1)
+		require.ErrorIs(t, err, frost.ErrInvalidParameters)
+	})
+
+	t.Run("err - invalid proof of knowledge", func(t *testing.T) {
+		round1Secrets, round1Packages := dkgRound1(t, 3, 2)
+		round1Packages[1].ProofOfKnowledgeZ = edwards25519.NewScalar().Add(round1Packages[1].ProofOfKnowledgeZ, round1Packages[1].ProofOfKnowledgeZ)
+
+		_, _, err := frost.DKGRound2(round1Secrets[0], othersRound1Packages(round1Packages, 0))
+		require.ErrorIs(t, err, frost.ErrInvalidProofOfKnowledge)
+	})
+
+	t.Run("err - missing participant", func(t *testing.T) {
+		round1Secrets, round1Packages := dkgRound1(t, 3, 2)
+
+		_, _, err := frost.DKGRound2(round1Secrets[0], round1Packages[1:2])
+		require.ErrorIs(t, err, frost.ErrInvalidParticipants)
+	})
+
+	t.Run("err - invalid secret share", func(t *testing.T) {
+		round1Secrets, round1Packages := dkgRound1(t, 3, 2)
+
+		round2Secret, _, err := frost.DKGRound2(round1Secrets[0], othersRound1Packages(round1Packages, 0))
+		require.NoError(t, err)
+
+		var received []*frost.DKGRound2Package
+		for i := 1; i < 3; i++ {
+			_, round2Packages, err := frost.DKGRound2(round1Secrets[i], othersRound1Packages(round1Packages, i))
+			require.NoError(t, err)
+
+			for _, round2Package := range round2Packages {
+				if round2Package.Receiver == 1 {
+					received = append(received, round2Package)
+				}
+			}
+		}
+		received[1].SigningShare = edwards25519.NewScalar().Add(received[1].SigningShare, received[1].SigningShare)
+
+		_, _, err = frost.DKGFinalize(round2Secret, received)
+		require.ErrorIs(t, err, frost.ErrInvalidSecretShare)
+	})
+}
+
+func TestSign(t *testing.T) {
+	keyPackages, publicKeyPackage := runDKG(t, 5, 3)
+	message := tpkg.RandBytes(64)
+
+	t.Run("ok - any subset of signers", func(t *testing.T) {
+		for _, signers := range [][]*frost.KeyPackage{
+			{keyPackages[0], keyPackages[1], keyPackages[2]},
+			{keyPackages[4], keyPackages[2], keyPackages[0]},
+			{keyPackages[1], keyPackages[3], keyPackages[4]},
+			keyPackages,
+		} {
+			signature, err := sign(t, publicKeyPackage, signers, message)
+			require.NoError(t, err)
+
+			// the signature is a standard Ed25519 signature of the group public key
+			require.True(t, ed25519.Verify(publicKeyPackage.PublicKey(), message, signature.Signature[:]))
+			require.NoError(t, signature.Valid(message, publicKeyPackage.Ed25519Address()))
+		}
+	})
+
+	t.Run("err - not enough signers", func(t *testing.T) {
+		nonces, err := frost.Commit(keyPackages[0])
+		require.NoError(t, err)
+
+		otherNonces, err := frost.Commit(keyPackages[1])
+		require.NoError(t, err)
+
+		_, err = frost.Sign(keyPackages[0], nonces, message, []*frost.SigningCommitments{nonces.Commitments(), otherNonces.Commitments()})
+		require.ErrorIs(t, err, frost.ErrInvalidParticipants)
+	})
+
+	t.Run("err - nonces already used", func(t *testing.T) {
+		signers := keyPackages[:3]
+
+		nonces := make([]*frost.SigningNonces, 0, len(signers))
+		commitments := make([]*frost.SigningCommitments, 0, len(signers))
+		for _, signer := range signers {
+			signerNonces, err := frost.Commit(signer)
+			require.NoError(t, err)
+
+			nonces = append(nonces, signerNonces)
+			commitments = append(commitments, signerNonces.Commitments())
+		}
+
+		_, err := frost.Sign(signers[0], nonces[0], message, commitments)
+		require.NoError(t, err)
+
+		_, err = frost.Sign(signers[0], nonces[0], tpkg.RandBytes(64), commitments)
+		require.ErrorIs(t, err, frost.ErrNoncesAlreadyUsed)
+	})
+
+	t.Run("err - invalid signature share", func(t *testing.T) {
+		signers := keyPackages[:3]
+
+		nonces := make([]*frost.SigningNonces, 0, len(signers))
+		commitments := make([]*frost.SigningCommitments, 0, len(signers))
+		for _, signer := range signers {
+			signerNonces, err := frost.Commit(signer)
+			require.NoError(t, err)
+
+			nonces = append(nonces, signerNonces)
+			commitments = append(commitments, signerNonces.Commitments())
+		}
+
+		signatureShares := make([]*frost.SignatureShare, 0, len(signers))
+		for i, signer := range signers {
+			// the last signer signs a different message
+			signerMessage := message
+			if i == len(signers)-1 {
+				signerMessage = tpkg.RandBytes(64)
+			}
+
+			signatureShare, err := frost.Sign(signer, nonces[i], signerMessage, commitments)
+			require.NoError(t, err)
+
+			signatureShares = append(signatureShares, signatureShare)
+		}
+
+		_, err := frost.Aggregate(publicKeyPackage, message, commitments, signatureShares)
+		require.ErrorIs(t, err, frost.ErrInvalidSignatureShare)
+	})
+}
+
+// unavailableParticipant is a participant that fails to commit or to sign.
+type unavailableParticipant struct {
+	frost.Participant
+
+	failCommit bool
+	failSign   bool
+}
+
+func (p *unavailableParticipant) Commit() (*frost.SigningCommitments, error) {
+	if p.failCommit {
+		return nil, ierrors.New("participant is unavailable")
+	}
+
+	return p.Participant.Commit()
+}
+
+func (p *unavailableParticipant) Sign(message []byte, commitments []*frost.SigningCommitments) (*frost.SignatureShare, error) {
+	if p.failSign {
+		return nil, ierrors.New("participant is unavailable")
+	}
+
+	return p.Participant.Sign(message, commitments)
+}
+
+func TestAddressSigner(t *testing.T) {
+	keyPackages, publicKeyPackage := runDKG(t, 3, 2)
+
+	participants := make([]frost.Participant, 0, len(keyPackages))
+	for _, keyPackage := range keyPackages {
+		participants = append(participants, frost.NewLocalParticipant(keyPackage))
+	}
+
+	_, err := frost.NewAddressSigner(publicKeyPackage, participants[0])
+	require.ErrorIs(t, err, frost.ErrInvalidParticipants)
+
+	signer, err := frost.NewAddressSigner(publicKeyPackage, participants[1], participants[2])
+	require.NoError(t, err)
+
+	address := signer.Address()
+	require.Equal(t, publicKeyPackage.Ed25519Address(), address)
+
+	_, err = signer.Sign(tpkg.RandEd25519Address(), tpkg.RandBytes(32))
+	require.ErrorIs(t, err, iotago.ErrAddressKeysNotMapped)
+
+	signedTransaction, err := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, signer).
+		AddInput(&builder.TxInput{UnlockTarget: address, InputID: tpkg.RandOutputID(0), Input: tpkg.RandOutputOnAddressWithAmount(iotago.OutputBasic, address, 1_000_000)}).
+		AddOutput(tpkg.RandOutputOnAddressWithAmount(iotago.OutputBasic, tpkg.RandEd25519Address(), 1_000_000)).
+		Build()
+	require.NoError(t, err)
+
+	signingMessage, err := signedTransaction.Transaction.SigningMessage()
+	require.NoError(t, err)
+
+	//nolint:forcetypeassert // we know that the unlock is a signature unlock
+	signature := signedTransaction.Unlocks[0].(*iotago.SignatureUnlock).Signature.(*iotago.Ed25519Signature)
+	require.NoError(t, signature.Valid(signingMessage, address))
+}
+
+func TestAddressSignerUnavailableParticipants(t *testing.T) {
+	keyPackages, publicKeyPackage := runDKG(t, 3, 2)
+
+	participant := func(i int) frost.Participant {
+		return frost.NewLocalParticipant(keyPackages[i])
+	}
+
+	tests := []struct {
+		name         string
+		participants []frost.Participant
+		err          error
+	}{
+		{
+			name:         "ok - participant fails to commit",
+			participants: []frost.Participant{&unavailableParticipant{Participant: participant(0), failCommit: true}, participant(1), participant(2)},
+		},
+		{
+			name:         "ok - participant fails to sign",
+			participants: []frost.Participant{participant(0), &unavailableParticipant{Participant: participant(1), failSign: true}, participant(2)},
+		},
+		{
+			name:         "err - not enough participants available",
+			participants: []frost.Participant{participant(0), &unavailableParticipant{Participant: participant(1), failCommit: true}, &unavailableParticipant{Participant: participant(2), failSign: true}},
+			err:          frost.ErrInvalidParticipants,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signer, err := frost.NewAddressSigner(publicKeyPackage, test.participants...)
+			require.NoError(t, err)
+
+			msg := tpkg.RandBytes(32)
+			signature, err := signer.Sign(signer.Address(), msg)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+
+				return
+			}
+			require.NoError(t, err)
+
+			//nolint:forcetypeassert // we know that the signature is an Ed25519 signature
+			require.NoError(t, signature.(*iotago.Ed25519Signature).Valid(msg, signer.Address()))
+		})
+	}
+}
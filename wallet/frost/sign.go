@@ -0,0 +1,241 @@
+package frost
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"slices"
+
+	"filippo.io/edwards25519"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+var (
+	// ErrNoncesAlreadyUsed gets returned when signing nonces are used more than once.
+	ErrNoncesAlreadyUsed = ierrors.New("signing nonces were already used")
+	// ErrInvalidSignatureShare gets returned when the signature share of a participant is invalid.
+	ErrInvalidSignatureShare = ierrors.New("invalid signature share")
+)
+
+// SigningNonces are the secret nonces of a participant for a single signing operation.
+// They must not be shared with other participants and must only be used once.
+type SigningNonces struct {
+	hiding      *edwards25519.Scalar
+	binding     *edwards25519.Scalar
+	commitments *SigningCommitments
+	used        bool
+}
+
+// Commitments returns the public commitments to the nonces.
+func (n *SigningNonces) Commitments() *SigningCommitments {
+	return n.commitments
+}
+
+// SigningCommitments are the public commitments of a participant to its signing nonces.
+type SigningCommitments struct {
+	// The identifier of the participant.
+	Identifier Identifier
+	// The commitment to the hiding nonce.
+	Hiding *edwards25519.Point
+	// The commitment to the binding nonce.
+	Binding *edwards25519.Point
+}
+
+// SignatureShare is the signature share of a participant.
+type SignatureShare struct {
+	// The identifier of the participant.
+	Identifier Identifier
+	// The signature share.
+	Share *edwards25519.Scalar
+}
+
+// Commit generates the signing nonces of the first round of the signing protocol.
+// The returned commitments need to be sent to the coordinator of the signing operation.
+func Commit(keyPackage *KeyPackage) (*SigningNonces, error) {
+	hiding, err := generateNonce(keyPackage.SigningShare)
+	if err != nil {
+		return nil, err
+	}
+
+	binding, err := generateNonce(keyPackage.SigningShare)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningNonces{
+		hiding:  hiding,
+		binding: binding,
+		commitments: &SigningCommitments{
+			Identifier: keyPackage.Identifier,
+			Hiding:     edwards25519.NewIdentityPoint().ScalarBaseMult(hiding),
+			Binding:    edwards25519.NewIdentityPoint().ScalarBaseMult(binding),
+		},
+	}, nil
+}
+
+// Sign computes the signature share of the second round of the signing protocol for the given message,
+// using the commitments of all participating signers. The nonces are cleared afterwards.
+func Sign(keyPackage *KeyPackage, nonces *SigningNonces, message []byte, commitments []*SigningCommitments) (*SignatureShare, error) {
+	if nonces.used {
+		return nil, ErrNoncesAlreadyUsed
+	}
+	nonces.used = true
+	defer func() {
+		zeroScalar(nonces.hiding)
+		zeroScalar(nonces.binding)
+	}()
+
+	session, err := newSigningSession(keyPackage.GroupPublicKey, keyPackage.MinSigners, message, commitments)
+	if err != nil {
+		return nil, err
+	}
+
+	ownCommitments, exists := session.commitments[keyPackage.Identifier]
+	if !exists || ownCommitments.Hiding.Equal(nonces.commitments.Hiding) != 1 || ownCommitments.Binding.Equal(nonces.commitments.Binding) != 1 {
+		return nil, ierrors.WithMessagef(ErrInvalidParticipants, "commitments of participant %d are missing or don't match the nonces", keyPackage.Identifier)
+	}
+
+	// z_i = d_i + e_i * rho_i + lambda_i * s_i * c
+	lambda := lagrangeCoefficient(keyPackage.Identifier, session.identifiers)
+	share := edwards25519.NewScalar().Multiply(lambda, keyPackage.SigningShare)
+	share.Multiply(share, session.challenge)
+	share.MultiplyAdd(nonces.binding, session.bindingFactors[keyPackage.Identifier], share)
+	share.Add(share, nonces.hiding)
+
+	return &SignatureShare{
+		Identifier: keyPackage.Identifier,
+		Share:      share,
+	}, nil
+}
+
+// Aggregate verifies the signature shares of all participating signers and aggregates them to an Ed25519 signature
+// of the group public key, which can be verified with Ed25519Signature.Valid like any other Ed25519 signature.
+func Aggregate(publicKeyPackage *PublicKeyPackage, message []byte, commitments []*SigningCommitments, signatureShares []*SignatureShare) (*iotago.Ed25519Signature, error) {
+	session, err := newSigningSession(publicKeyPackage.GroupPublicKey, publicKeyPackage.MinSigners, message, commitments)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(signatureShares) != len(session.identifiers) {
+		return nil, ierrors.WithMessagef(ErrInvalidParticipants, "expected %d signature shares, got %d", len(session.identifiers), len(signatureShares))
+	}
+
+	z := edwards25519.NewScalar()
+	received := make(map[Identifier]struct{}, len(signatureShares))
+	for _, signatureShare := range signatureShares {
+		signerCommitments, exists := session.commitments[signatureShare.Identifier]
+		if !exists {
+			return nil, ierrors.WithMessagef(ErrInvalidParticipants, "signature share of participant %d without commitments", signatureShare.Identifier)
+		}
+		if _, exists := received[signatureShare.Identifier]; exists {
+			return nil, ierrors.WithMessagef(ErrInvalidParticipants, "duplicate signature share of participant %d", signatureShare.Identifier)
+		}
+		received[signatureShare.Identifier] = struct{}{}
+
+		verifyingShare, exists := publicKeyPackage.VerifyingShares[signatureShare.Identifier]
+		if !exists {
+			return nil, ierrors.WithMessagef(ErrInvalidParticipants, "unknown participant %d", signatureShare.Identifier)
+		}
+		if signatureShare.Share == nil {
+			return nil, ierrors.WithMessagef(ErrInvalidSignatureShare, "participant %d", signatureShare.Identifier)
+		}
+
+		// G * z_i == D_i + E_i * rho_i + Y_i * (c * lambda_i)
+		commitment := edwards25519.NewIdentityPoint().ScalarMult(session.bindingFactors[signatureShare.Identifier], signerCommitments.Binding)
+		commitment.Add(commitment, signerCommitments.Hiding)
+
+		lambda := lagrangeCoefficient(signatureShare.Identifier, session.identifiers)
+		expected := edwards25519.NewIdentityPoint().ScalarMult(edwards25519.NewScalar().Multiply(session.challenge, lambda), verifyingShare)
+		expected.Add(expected, commitment)
+
+		if edwards25519.NewIdentityPoint().ScalarBaseMult(signatureShare.Share).Equal(expected) != 1 {
+			return nil, ierrors.WithMessagef(ErrInvalidSignatureShare, "participant %d", signatureShare.Identifier)
+		}
+
+		z.Add(z, signatureShare.Share)
+	}
+
+	signature := &iotago.Ed25519Signature{}
+	copy(signature.PublicKey[:], publicKeyPackage.PublicKey())
+	copy(signature.Signature[:ed25519.SignatureSize/2], session.groupCommitment.Bytes())
+	copy(signature.Signature[ed25519.SignatureSize/2:], z.Bytes())
+
+	return signature, nil
+}
+
+// signingSession holds the values derived from the message and the commitments of all participating signers.
+type signingSession struct {
+	commitments     map[Identifier]*SigningCommitments
+	identifiers     []Identifier
+	bindingFactors  map[Identifier]*edwards25519.Scalar
+	groupCommitment *edwards25519.Point
+	challenge       *edwards25519.Scalar
+}
+
+// newSigningSession validates the commitments and computes the binding factors, the group commitment and the challenge.
+func newSigningSession(groupPublicKey *edwards25519.Point, minSigners uint16, message []byte, commitments []*SigningCommitments) (*signingSession, error) {
+	if len(commitments) < int(minSigners) {
+		return nil, ierrors.WithMessagef(ErrInvalidParticipants, "at least %d signers are needed, got %d", minSigners, len(commitments))
+	}
+
+	sortedCommitments := slices.Clone(commitments)
+	slices.SortFunc(sortedCommitments, func(a *SigningCommitments, b *SigningCommitments) int {
+		return int(a.Identifier) - int(b.Identifier)
+	})
+
+	session := &signingSession{
+		commitments:     make(map[Identifier]*SigningCommitments, len(sortedCommitments)),
+		identifiers:     make([]Identifier, 0, len(sortedCommitments)),
+		bindingFactors:  make(map[Identifier]*edwards25519.Scalar, len(sortedCommitments)),
+		groupCommitment: edwards25519.NewIdentityPoint(),
+	}
+
+	// encode_group_commitment_list
+	encodedCommitments := make([]byte, 0, len(sortedCommitments)*3*32)
+	for _, signerCommitments := range sortedCommitments {
+		if signerCommitments.Identifier == 0 || signerCommitments.Hiding == nil || signerCommitments.Binding == nil {
+			return nil, ierrors.WithMessagef(ErrInvalidParticipants, "invalid commitments of participant %d", signerCommitments.Identifier)
+		}
+		if _, exists := session.commitments[signerCommitments.Identifier]; exists {
+			return nil, ierrors.WithMessagef(ErrInvalidParticipants, "duplicate commitments of participant %d", signerCommitments.Identifier)
+		}
+
+		session.commitments[signerCommitments.Identifier] = signerCommitments
+		session.identifiers = append(session.identifiers, signerCommitments.Identifier)
+
+		encodedCommitments = append(encodedCommitments, signerCommitments.Identifier.scalar().Bytes()...)
+		encodedCommitments = append(encodedCommitments, signerCommitments.Hiding.Bytes()...)
+		encodedCommitments = append(encodedCommitments, signerCommitments.Binding.Bytes()...)
+	}
+
+	// compute_binding_factors
+	rhoInputPrefix := make([]byte, 0, 32+64+64)
+	rhoInputPrefix = append(rhoInputPrefix, groupPublicKey.Bytes()...)
+	rhoInputPrefix = append(rhoInputPrefix, hash([]byte(contextString+"msg"), message)...)
+	rhoInputPrefix = append(rhoInputPrefix, hash([]byte(contextString+"com"), encodedCommitments)...)
+
+	for _, signerCommitments := range sortedCommitments {
+		bindingFactor := hashToScalar([]byte(contextString+"rho"), rhoInputPrefix, signerCommitments.Identifier.scalar().Bytes())
+		session.bindingFactors[signerCommitments.Identifier] = bindingFactor
+
+		// compute_group_commitment
+		session.groupCommitment.Add(session.groupCommitment, signerCommitments.Hiding)
+		session.groupCommitment.Add(session.groupCommitment, edwards25519.NewIdentityPoint().ScalarMult(bindingFactor, signerCommitments.Binding))
+	}
+
+	// the challenge is computed like for plain Ed25519 signatures
+	session.challenge = hashToScalar(session.groupCommitment.Bytes(), groupPublicKey.Bytes(), message)
+
+	return session, nil
+}
+
+// generateNonce generates a nonce from fresh randomness and the secret, which protects against bad randomness.
+func generateNonce(secret *edwards25519.Scalar) (*edwards25519.Scalar, error) {
+	var randomBytes [32]byte
+	if _, err := rand.Read(randomBytes[:]); err != nil {
+		return nil, ierrors.Wrap(err, "failed to read randomness")
+	}
+
+	return hashToScalar([]byte(contextString+"nonce"), randomBytes[:], secret.Bytes()), nil
+}
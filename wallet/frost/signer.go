@@ -0,0 +1,241 @@
+package frost
+
+import (
+	"sync"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+)
+
+// Participant is a participant of the signing protocol, which might be running in a different process or on a different device.
+type Participant interface {
+	// Identifier returns the identifier of the participant.
+	Identifier() Identifier
+	// Commit generates new signing nonces and returns their commitments (round 1).
+	Commit() (*SigningCommitments, error)
+	// Sign computes the signature share for the given message with the nonces of the participant's commitments (round 2).
+	Sign(message []byte, commitments []*SigningCommitments) (*SignatureShare, error)
+}
+
+// LocalParticipant is a Participant holding its key package in memory.
+type LocalParticipant struct {
+	keyPackage *KeyPackage
+
+	// nonces are the pending signing nonces by the key of the commitment to their hiding nonce.
+	nonces      map[string]*SigningNonces
+	noncesMutex sync.Mutex
+}
+
+var _ Participant = &LocalParticipant{}
+
+// NewLocalParticipant creates a new LocalParticipant for the given key package.
+func NewLocalParticipant(keyPackage *KeyPackage) *LocalParticipant {
+	return &LocalParticipant{
+		keyPackage: keyPackage,
+		nonces:     make(map[string]*SigningNonces),
+	}
+}
+
+// Identifier returns the identifier of the participant.
+func (p *LocalParticipant) Identifier() Identifier {
+	return p.keyPackage.Identifier
+}
+
+// Commit generates new signing nonces and returns their commitments.
+func (p *LocalParticipant) Commit() (*SigningCommitments, error) {
+	nonces, err := Commit(p.keyPackage)
+	if err != nil {
+		return nil, err
+	}
+
+	p.noncesMutex.Lock()
+	defer p.noncesMutex.Unlock()
+
+	p.nonces[string(nonces.Commitments().Hiding.Bytes())] = nonces
+
+	return nonces.Commitments(), nil
+}
+
+// Sign computes the signature share for the given message with the nonces of the participant's commitments.
+// The nonces are discarded afterwards, even if signing failed.
+func (p *LocalParticipant) Sign(message []byte, commitments []*SigningCommitments) (*SignatureShare, error) {
+	var nonces *SigningNonces
+	for _, signerCommitments := range commitments {
+		if signerCommitments.Identifier != p.keyPackage.Identifier || signerCommitments.Hiding == nil {
+			continue
+		}
+
+		p.noncesMutex.Lock()
+		nonces = p.nonces[string(signerCommitments.Hiding.Bytes())]
+		delete(p.nonces, string(signerCommitments.Hiding.Bytes()))
+		p.noncesMutex.Unlock()
+
+		break
+	}
+
+	if nonces == nil {
+		return nil, ierrors.WithMessagef(ErrInvalidParticipants, "no pending nonces for the commitments of participant %d", p.keyPackage.Identifier)
+	}
+
+	return Sign(p.keyPackage, nonces, message, commitments)
+}
+
+// AddressSigner implements iotago.AddressSigner for the Ed25519Address of the group public key
+// by coordinating the signing protocol between the participants.
+type AddressSigner struct {
+	publicKeyPackage *PublicKeyPackage
+	participants     []Participant
+}
+
+var _ iotago.AddressSigner = &AddressSigner{}
+
+// NewAddressSigner creates a new AddressSigner for the group of the given public key package.
+// The first minSigners participants that are given take part in every signing operation,
+// the remaining participants take the place of participants that fail to commit or to sign.
+func NewAddressSigner(publicKeyPackage *PublicKeyPackage, participants ...Participant) (*AddressSigner, error) {
+	if len(participants) < int(publicKeyPackage.MinSigners) {
+		return nil, ierrors.WithMessagef(ErrInvalidParticipants, "at least %d participants are needed, got %d", publicKeyPackage.MinSigners, len(participants))
+	}
+
+	seen := make(map[Identifier]struct{}, len(participants))
+	for _, participant := range participants {
+		if _, known := publicKeyPackage.VerifyingShares[participant.Identifier()]; !known {
+			return nil, ierrors.WithMessagef(ErrInvalidParticipants, "unknown participant %d", participant.Identifier())
+		}
+		if _, exists := seen[participant.Identifier()]; exists {
+			return nil, ierrors.WithMessagef(ErrInvalidParticipants, "duplicate participant %d", participant.Identifier())
+		}
+		seen[participant.Identifier()] = struct{}{}
+	}
+
+	return &AddressSigner{
+		publicKeyPackage: publicKeyPackage,
+		participants:     participants,
+	}, nil
+}
+
+// Address returns the Ed25519Address of the group public key.
+func (s *AddressSigner) Address() *iotago.Ed25519Address {
+	return s.publicKeyPackage.Ed25519Address()
+}
+
+// SignerUIDForAddress returns the signer unique identifier for a given address.
+func (s *AddressSigner) SignerUIDForAddress(addr iotago.Address) (iotago.Identifier, error) {
+	if err := s.checkAddress(addr); err != nil {
+		return iotago.EmptyIdentifier, err
+	}
+
+	// the UID is the blake2b 256 hash of the public key
+	return iotago.IdentifierFromData(s.publicKeyPackage.PublicKey()), nil
+}
+
+// Sign runs the signing protocol with the participants and returns the aggregated Ed25519 signature.
+// Participants that fail to commit or to sign are replaced by the remaining participants,
+// if a participant fails to sign, the signing protocol is restarted without it.
+func (s *AddressSigner) Sign(addr iotago.Address, msg []byte) (iotago.Signature, error) {
+	if err := s.checkAddress(addr); err != nil {
+		return nil, err
+	}
+
+	var participantErrs error
+	failedParticipants := make(map[Identifier]struct{})
+
+	for {
+		signers, commitments, err := s.commit(failedParticipants)
+		participantErrs = ierrors.Join(participantErrs, err)
+		if len(signers) < int(s.publicKeyPackage.MinSigners) {
+			return nil, ierrors.Join(ierrors.WithMessagef(ErrInvalidParticipants, "less than %d participants are available", s.publicKeyPackage.MinSigners), participantErrs)
+		}
+
+		signatureShares, err := s.signatureShares(signers, msg, commitments, failedParticipants)
+		if err != nil {
+			participantErrs = ierrors.Join(participantErrs, err)
+
+			continue
+		}
+
+		signature, err := Aggregate(s.publicKeyPackage, msg, commitments, signatureShares)
+		if err != nil {
+			return nil, err
+		}
+
+		return signature, nil
+	}
+}
+
+// commit collects the commitments of the first minSigners participants that didn't fail yet (round 1).
+// Participants that fail to commit are added to the failed participants.
+func (s *AddressSigner) commit(failedParticipants map[Identifier]struct{}) ([]Participant, []*SigningCommitments, error) {
+	var participantErrs error
+
+	signers := make([]Participant, 0, s.publicKeyPackage.MinSigners)
+	commitments := make([]*SigningCommitments, 0, s.publicKeyPackage.MinSigners)
+	for _, participant := range s.participants {
+		if len(signers) == int(s.publicKeyPackage.MinSigners) {
+			break
+		}
+
+		if _, failed := failedParticipants[participant.Identifier()]; failed {
+			continue
+		}
+
+		signerCommitments, err := participant.Commit()
+		if err != nil {
+			failedParticipants[participant.Identifier()] = struct{}{}
+			participantErrs = ierrors.Join(participantErrs, ierrors.Wrapf(err, "participant %d failed to commit", participant.Identifier()))
+
+			continue
+		}
+
+		signers = append(signers, participant)
+		commitments = append(commitments, signerCommitments)
+	}
+
+	return signers, commitments, participantErrs
+}
+
+// signatureShares collects the signature shares of the signers (round 2).
+// The first signer that fails to sign is added to the failed participants.
+func (s *AddressSigner) signatureShares(signers []Participant, msg []byte, commitments []*SigningCommitments, failedParticipants map[Identifier]struct{}) ([]*SignatureShare, error) {
+	signatureShares := make([]*SignatureShare, 0, len(signers))
+	for _, participant := range signers {
+		signatureShare, err := participant.Sign(msg, commitments)
+		if err != nil {
+			failedParticipants[participant.Identifier()] = struct{}{}
+
+			return nil, ierrors.Wrapf(err, "participant %d failed to sign", participant.Identifier())
+		}
+
+		signatureShares = append(signatureShares, signatureShare)
+	}
+
+	return signatureShares, nil
+}
+
+// EmptySignatureForAddress returns an empty signature for the given address.
+func (s *AddressSigner) EmptySignatureForAddress(addr iotago.Address) (iotago.Signature, error) {
+	if err := s.checkAddress(addr); err != nil {
+		return nil, err
+	}
+
+	return &iotago.Ed25519Signature{}, nil
+}
+
+// checkAddress checks whether the given address is backed by the group public key.
+func (s *AddressSigner) checkAddress(addr iotago.Address) error {
+	addr = builder.ResolveUnderlyingAddress(addr)
+
+	switch address := addr.(type) {
+	case *iotago.Ed25519Address:
+		if address.Equal(s.publicKeyPackage.Ed25519Address()) {
+			return nil
+		}
+	case *iotago.ImplicitAccountCreationAddress:
+		if address.Equal(iotago.ImplicitAccountCreationAddressFromPubKey(s.publicKeyPackage.PublicKey())) {
+			return nil
+		}
+	}
+
+	return ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "address %s is not backed by the group public key", addr)
+}
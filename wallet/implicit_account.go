@@ -0,0 +1,278 @@
+package wallet
+
+import (
+	"context"
+	"time"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+)
+
+// DefaultImplicitAccountPollInterval is the default interval in which the node is asked whether an implicit account can be converted.
+const DefaultImplicitAccountPollInterval = 5 * time.Second
+
+var (
+	// ErrNotImplicitAccount gets returned when an output is not an implicit account of the wallet.
+	ErrNotImplicitAccount = ierrors.New("output is not an implicit account")
+	// ErrImplicitAccountInsufficientMana gets returned when an implicit account doesn't hold enough mana
+	// to allot the mana needed to issue the block that converts it.
+	ErrImplicitAccountInsufficientMana = ierrors.New("implicit account holds not enough mana to issue the conversion block")
+)
+
+// ImplicitAccountConversionOptions are the options used to convert an implicit account to an account output.
+type ImplicitAccountConversionOptions struct {
+	owner           iotago.Address
+	blockIssuerKeys iotago.BlockIssuerKeys
+	pollInterval    time.Duration
+}
+
+// WithImplicitAccountOwner sets the address that controls the account output.
+// By default, it is the Ed25519Address of the key of the implicit account.
+func WithImplicitAccountOwner(owner iotago.Address) options.Option[ImplicitAccountConversionOptions] {
+	return func(opts *ImplicitAccountConversionOptions) {
+		opts.owner = owner
+	}
+}
+
+// WithImplicitAccountBlockIssuerKeys sets the block issuer keys of the BlockIssuerFeature of the account output.
+// By default, the key of the implicit account is used.
+func WithImplicitAccountBlockIssuerKeys(blockIssuerKeys ...iotago.BlockIssuerKey) options.Option[ImplicitAccountConversionOptions] {
+	return func(opts *ImplicitAccountConversionOptions) {
+		opts.blockIssuerKeys = iotago.NewBlockIssuerKeys(blockIssuerKeys...)
+	}
+}
+
+// WithImplicitAccountPollInterval sets the interval in which the node is asked whether the implicit account can be converted.
+func WithImplicitAccountPollInterval(pollInterval time.Duration) options.Option[ImplicitAccountConversionOptions] {
+	return func(opts *ImplicitAccountConversionOptions) {
+		opts.pollInterval = pollInterval
+	}
+}
+
+// BuildImplicitAccountConversion builds and signs the block that transitions the implicit account to an AccountOutput
+// with a BlockIssuerFeature. The block is issued by the implicit account itself, so the mana needed to issue it
+// is allotted from the mana of the implicit account, the remaining mana is stored on the account output.
+// The parents and the commitment of the block are taken from the block issuance response of the node,
+// the transaction references the same commitment and the block issuance credits of the account as context inputs.
+// Native tokens of the implicit account are sent to the owner of the account output in a basic output,
+// whose storage deposit is paid from the base tokens of the implicit account.
+// It fails with ErrImplicitAccountInsufficientMana if the implicit account can't pay for the block yet.
+func BuildImplicitAccountConversion(apiForSlot iotago.API, signer iotago.AddressSigner, outputID iotago.OutputID, implicitAccount *iotago.BasicOutput, blockIssuance *api.IssuanceBlockHeaderResponse, rmc iotago.Mana, opts ...options.Option[ImplicitAccountConversionOptions]) (*iotago.Block, error) {
+	implicitAddress, isImplicitAccount := implicitAccountAddress(implicitAccount)
+	if !isImplicitAccount {
+		return nil, ierrors.WithMessagef(ErrNotImplicitAccount, "output %s is not owned by an implicit account creation address", outputID)
+	}
+
+	// the ImplicitAccountCreationAddress is the hash of the public key, just like the Ed25519Address
+	ed25519Address := iotago.Ed25519Address(*implicitAddress)
+	conversionOpts := options.Apply(&ImplicitAccountConversionOptions{
+		owner:           &ed25519Address,
+		blockIssuerKeys: iotago.NewBlockIssuerKeys(iotago.Ed25519PublicKeyHashBlockIssuerKeyFromImplicitAccountCreationAddress(implicitAddress)),
+	}, opts)
+
+	accountID := iotago.AccountIDFromOutputID(outputID)
+	accountOutput, err := builder.NewAccountOutputBuilder(conversionOpts.owner, implicitAccount.Amount).
+		AccountID(accountID).
+		BlockIssuer(conversionOpts.blockIssuerKeys, iotago.MaxSlotIndex).
+		Build()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to build account output")
+	}
+
+	// account outputs can't hold native tokens, so they are sent to the owner in a basic output,
+	// whose storage deposit is taken from the base tokens of the implicit account
+	var nativeTokenOutput *iotago.BasicOutput
+	if nativeToken := implicitAccount.FeatureSet().NativeToken(); nativeToken != nil {
+		//nolint:forcetypeassert // we can safely assume that this is a NativeTokenFeature
+		nativeTokenOutput = builder.NewBasicOutputBuilder(conversionOpts.owner, 0).
+			NativeToken(nativeToken.Clone().(*iotago.NativeTokenFeature)).
+			MustBuild()
+
+		minDeposit, err := apiForSlot.StorageScoreStructure().MinDeposit(nativeTokenOutput)
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to calculate the minimum storage deposit of the native token output")
+		}
+
+		if accountOutput.Amount < minDeposit {
+			return nil, ierrors.WithMessagef(iotago.ErrStorageDepositNotCovered, "the base tokens of implicit account %s don't cover the storage deposit of the native token output: %d < %d", accountID, accountOutput.Amount, minDeposit)
+		}
+		nativeTokenOutput.Amount = minDeposit
+		accountOutput.Amount -= minDeposit
+	}
+
+	if _, err := apiForSlot.StorageScoreStructure().CoversMinDeposit(accountOutput, accountOutput.Amount); err != nil {
+		return nil, ierrors.Wrapf(err, "the base tokens of implicit account %s don't cover the storage deposit of the account output", accountID)
+	}
+
+	// the issuing time of a block needs to be after the issuing time of its parents
+	issuingTime := time.Now().UTC()
+	if !issuingTime.After(blockIssuance.LatestParentBlockIssuingTime) {
+		issuingTime = blockIssuance.LatestParentBlockIssuingTime.Add(time.Nanosecond)
+	}
+	creationSlot := apiForSlot.TimeProvider().SlotFromTime(issuingTime)
+
+	commitmentID, err := blockIssuance.LatestCommitment.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to compute the ID of the latest commitment")
+	}
+
+	txBuilder := builder.NewTransactionBuilder(apiForSlot, signer).
+		AddInput(&builder.TxInput{UnlockTarget: implicitAddress, InputID: outputID, Input: implicitAccount}).
+		AddCommitmentInput(&iotago.CommitmentInput{CommitmentID: commitmentID}).
+		AddBlockIssuanceCreditInput(&iotago.BlockIssuanceCreditInput{AccountID: accountID}).
+		AddOutput(accountOutput).
+		SetCreationSlot(creationSlot)
+
+	if nativeTokenOutput != nil {
+		txBuilder.AddOutput(nativeTokenOutput)
+	}
+
+	availableMana, err := txBuilder.CalculateAvailableManaInputs(creationSlot)
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "failed to calculate the mana of implicit account %s", accountID)
+	}
+
+	requiredMana, err := txBuilder.MinRequiredAllottedMana(rmc, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if availableMana.TotalMana < requiredMana {
+		return nil, ierrors.WithMessagef(ErrImplicitAccountInsufficientMana, "implicit account %s holds %d mana, but %d mana is needed", accountID, availableMana.TotalMana, requiredMana)
+	}
+
+	return txBuilder.
+		AllotMinRequiredManaAndStoreRemainingManaInOutput(creationSlot, rmc, accountID, 0).
+		BuildAndSwapToBlockBuilder(nil).
+		StrongParents(blockIssuance.StrongParents).
+		WeakParents(blockIssuance.WeakParents).
+		ShallowLikeParents(blockIssuance.ShallowLikeParents).
+		IssuingTime(issuingTime).
+		SlotCommitmentID(commitmentID).
+		LatestFinalizedSlot(blockIssuance.LatestFinalizedSlot).
+		CalculateAndSetMaxBurnedMana(rmc).
+		SignWithSigner(accountID, signer, implicitAddress).
+		Build()
+}
+
+// ImplicitAccounts returns the owned implicit accounts, which are basic outputs owned by an ImplicitAccountCreationAddress.
+func (w *Wallet) ImplicitAccounts() iotago.OutputSet {
+	return w.Outputs().Filter(func(_ iotago.OutputID, output iotago.Output) bool {
+		_, isImplicitAccount := implicitAccountAddress(output)
+
+		return isImplicitAccount
+	})
+}
+
+// ConvertImplicitAccount converts the implicit account with the given output ID to an AccountOutput with a BlockIssuerFeature
+// and returns the ID of the issued block. The implicit account needs to be owned by the key of the given BIP44 path.
+// The node is polled until the implicit account is part of a commitment, its block issuance credits allow to issue a block
+// and its mana covers the cost of the block. Waiting stops if the context is canceled.
+// The implicit account stays in the wallet until the UTXO changes of the conversion are applied, e.g. via Sync.
+func (w *Wallet) ConvertImplicitAccount(ctx context.Context, outputID iotago.OutputID, path BIP44Path, opts ...options.Option[ImplicitAccountConversionOptions]) (iotago.BlockID, error) {
+	output, exists := w.ImplicitAccounts()[outputID]
+	if !exists {
+		return iotago.EmptyBlockID, ierrors.WithMessagef(ErrNotImplicitAccount, "output %s is not an implicit account of the wallet", outputID)
+	}
+	//nolint:forcetypeassert // implicit accounts are basic outputs
+	implicitAccount := output.(*iotago.BasicOutput)
+
+	address, err := w.keyManager.AddressForBIP44Path(iotago.AddressImplicitAccountCreation, path)
+	if err != nil {
+		return iotago.EmptyBlockID, err
+	}
+
+	if !address.Equal(implicitAccount.UnlockConditionSet().Address().Address) {
+		return iotago.EmptyBlockID, ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "implicit account %s is not owned by the key of path %s", outputID, path)
+	}
+
+	signer, err := w.keyManager.AddressSignerForBIP44Paths(path)
+	if err != nil {
+		return iotago.EmptyBlockID, err
+	}
+
+	pollInterval := options.Apply(&ImplicitAccountConversionOptions{pollInterval: DefaultImplicitAccountPollInterval}, opts).pollInterval
+	accountAddress := iotago.AccountAddressFromOutputID(outputID)
+
+	for {
+		block, err := w.buildImplicitAccountConversion(ctx, accountAddress, signer, outputID, implicitAccount, opts...)
+		if err != nil {
+			return iotago.EmptyBlockID, err
+		}
+
+		if block != nil {
+			blockID, err := w.client.SubmitBlock(ctx, block)
+			if err != nil {
+				return iotago.EmptyBlockID, ierrors.Wrapf(err, "failed to submit the conversion block of implicit account %s", outputID)
+			}
+
+			return blockID, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return iotago.EmptyBlockID, ierrors.Wrapf(ctx.Err(), "implicit account %s can't be converted yet", outputID)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// buildImplicitAccountConversion builds the conversion block with the current state of the node.
+// It returns a nil block if the implicit account can't issue the block yet.
+func (w *Wallet) buildImplicitAccountConversion(ctx context.Context, accountAddress *iotago.AccountAddress, signer iotago.AddressSigner, outputID iotago.OutputID, implicitAccount *iotago.BasicOutput, opts ...options.Option[ImplicitAccountConversionOptions]) (*iotago.Block, error) {
+	blockIssuance, err := w.client.BlockIssuance(ctx)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to get block issuance info")
+	}
+
+	commitmentID, err := blockIssuance.LatestCommitment.ID()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to compute the ID of the latest commitment")
+	}
+
+	// the account of the implicit account only exists once the slot it was created in is committed
+	congestion, err := w.client.Congestion(ctx, accountAddress, 0, commitmentID)
+	if err != nil {
+		if ierrors.Is(err, nodeclient.ErrHTTPNotFound) {
+			return nil, nil
+		}
+
+		return nil, ierrors.Wrapf(err, "failed to get congestion of implicit account %s", outputID)
+	}
+
+	if !congestion.Ready {
+		return nil, nil
+	}
+
+	block, err := BuildImplicitAccountConversion(w.client.APIForSlot(commitmentID.Slot()), signer, outputID, implicitAccount, blockIssuance, congestion.ReferenceManaCost, opts...)
+	if err != nil {
+		if ierrors.Is(err, ErrImplicitAccountInsufficientMana) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// implicitAccountAddress returns the ImplicitAccountCreationAddress that owns the output, if the output is an implicit account.
+func implicitAccountAddress(output iotago.Output) (*iotago.ImplicitAccountCreationAddress, bool) {
+	basicOutput, isBasicOutput := output.(*iotago.BasicOutput)
+	if !isBasicOutput {
+		return nil, false
+	}
+
+	addressUnlockCondition := basicOutput.UnlockConditionSet().Address()
+	if addressUnlockCondition == nil {
+		return nil, false
+	}
+
+	implicitAddress, isImplicitAddress := addressUnlockCondition.Address.(*iotago.ImplicitAccountCreationAddress)
+
+	return implicitAddress, isImplicitAddress
+}
@@ -0,0 +1,261 @@
+//nolint:forcetypeassert
+package wallet_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/vm/nova/ledger"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func implicitAccountOutput(address iotago.Address, amount iotago.BaseToken, mana iotago.Mana) *iotago.BasicOutput {
+	output := basicOutput(address, amount)
+	output.Mana = mana
+
+	return output
+}
+
+func withNativeToken(output *iotago.BasicOutput, nativeToken *iotago.NativeTokenFeature) *iotago.BasicOutput {
+	output.Features = append(output.Features, nativeToken)
+
+	return output
+}
+
+func blockIssuanceResponse(latestCommitment *iotago.Commitment) *api.IssuanceBlockHeaderResponse {
+	return &api.IssuanceBlockHeaderResponse{
+		StrongParents:                iotago.BlockIDs{tpkg.RandBlockID()},
+		LatestParentBlockIssuingTime: time.Now().Add(-time.Second),
+		LatestFinalizedSlot:          latestCommitment.Slot,
+		LatestCommitment:             latestCommitment,
+	}
+}
+
+func TestBuildImplicitAccountConversion(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	implicitAddress := keyManager.Address(iotago.AddressImplicitAccountCreation, 0).(*iotago.ImplicitAccountCreationAddress)
+	signer := keyManager.AddressSigner(0)
+	otherAddress := tpkg.RandEd25519Address()
+	nativeToken := &iotago.NativeTokenFeature{ID: tpkg.RandNativeTokenID(), Amount: big.NewInt(100)}
+
+	tests := []struct {
+		name            string
+		implicitAccount *iotago.BasicOutput
+		owner           iotago.Address
+		err             error
+	}{
+		{
+			name:            "ok",
+			implicitAccount: implicitAccountOutput(implicitAddress, 10_000_000, 10_000_000),
+			owner:           keyManager.Address(iotago.AddressEd25519, 0),
+		},
+		{
+			name:            "ok - native tokens",
+			implicitAccount: withNativeToken(implicitAccountOutput(implicitAddress, 10_000_000, 10_000_000), nativeToken),
+			owner:           keyManager.Address(iotago.AddressEd25519, 0),
+		},
+		{
+			name:            "err - insufficient mana",
+			implicitAccount: implicitAccountOutput(implicitAddress, 10_000_000, 0),
+			err:             wallet.ErrImplicitAccountInsufficientMana,
+		},
+		{
+			name:            "err - storage deposit not covered",
+			implicitAccount: implicitAccountOutput(implicitAddress, 1_000, 10_000_000),
+			err:             iotago.ErrStorageDepositNotCovered,
+		},
+		{
+			name:            "err - not an implicit account",
+			implicitAccount: implicitAccountOutput(keyManager.Address(iotago.AddressEd25519, 0), 10_000_000, 10_000_000),
+			err:             wallet.ErrNotImplicitAccount,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := ledger.New(mockAPI)
+			outputID := l.AddGenesisOutput(test.implicitAccount)
+			accountID := iotago.AccountIDFromOutputID(outputID)
+
+			// the account of an implicit account exists once its creation slot is committed
+			l.SetBlockIssuanceCredits(accountID, 0)
+			l.AdvanceSlots(mockAPI.ProtocolParameters().MaxCommittableAge())
+
+			block, err := wallet.BuildImplicitAccountConversion(mockAPI, signer, outputID, test.implicitAccount, blockIssuanceResponse(l.LatestCommitment()), 10)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, accountID, block.Header.IssuerID)
+			require.Equal(t, l.LatestCommitment().MustID(), block.Header.SlotCommitmentID)
+
+			valid, err := block.VerifySignature()
+			require.NoError(t, err)
+			require.True(t, valid)
+
+			// the block is signed with the key of the implicit account
+			signature := block.Signature.(*iotago.Ed25519Signature)
+			require.Equal(t, iotago.Ed25519PublicKeyHashBlockIssuerKeyFromImplicitAccountCreationAddress(implicitAddress), iotago.Ed25519PublicKeyHashBlockIssuerKeyFromPublicKey(signature.PublicKey))
+
+			signedTransaction := block.Body.(*iotago.BasicBlockBody).Payload.(*iotago.SignedTransaction)
+			require.NotNil(t, signedTransaction.Transaction.CommitmentInput())
+			require.Equal(t, l.LatestCommitment().MustID(), signedTransaction.Transaction.CommitmentInput().CommitmentID)
+			require.Len(t, signedTransaction.Transaction.BICInputs(), 1)
+			require.Equal(t, accountID, signedTransaction.Transaction.BICInputs()[0].AccountID)
+
+			// the mana to issue the block is allotted to the new account
+			maxBurnedMana := block.Body.(*iotago.BasicBlockBody).MaxBurnedMana
+			require.Len(t, signedTransaction.Transaction.Allotments, 1)
+			require.Equal(t, accountID, signedTransaction.Transaction.Allotments[0].AccountID)
+			require.GreaterOrEqual(t, signedTransaction.Transaction.Allotments[0].Mana, maxBurnedMana)
+
+			createdOutputs, err := l.ApplyTransaction(signedTransaction)
+			require.NoError(t, err)
+
+			implicitNativeToken := test.implicitAccount.FeatureSet().NativeToken()
+			if implicitNativeToken != nil {
+				require.Len(t, createdOutputs, 2)
+			} else {
+				require.Len(t, createdOutputs, 1)
+			}
+
+			var baseTokens iotago.BaseToken
+			for _, output := range createdOutputs {
+				baseTokens += output.BaseTokenAmount()
+				require.True(t, output.UnlockConditionSet().Address().Address.Equal(test.owner))
+
+				switch output := output.(type) {
+				case *iotago.AccountOutput:
+					require.Equal(t, accountID, output.AccountID)
+					require.NotZero(t, output.Mana)
+
+					blockIssuerFeature := output.FeatureSet().BlockIssuer()
+					require.NotNil(t, blockIssuerFeature)
+					require.True(t, blockIssuerFeature.BlockIssuerKeys.Has(iotago.Ed25519PublicKeyHashBlockIssuerKeyFromImplicitAccountCreationAddress(implicitAddress)))
+				case *iotago.BasicOutput:
+					// the native tokens are moved to a basic output of the owner
+					require.NotNil(t, implicitNativeToken)
+					require.Equal(t, implicitNativeToken, output.FeatureSet().NativeToken())
+				default:
+					require.Failf(t, "unexpected output", "output type %s", output.Type())
+				}
+			}
+			require.Equal(t, test.implicitAccount.Amount, baseTokens)
+
+			bic, err := l.BlockIssuanceCredits(accountID)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, bic, iotago.BlockIssuanceCredits(maxBurnedMana))
+		})
+	}
+
+	t.Run("ok - custom owner and block issuer keys", func(t *testing.T) {
+		implicitAccount := implicitAccountOutput(implicitAddress, 10_000_000, 10_000_000)
+		outputID := tpkg.RandOutputIDWithCreationSlot(0, 0)
+		blockIssuerKey := tpkg.RandBlockIssuerKey()
+
+		block, err := wallet.BuildImplicitAccountConversion(mockAPI, signer, outputID, implicitAccount, blockIssuanceResponse(iotago.NewEmptyCommitment(mockAPI)), 10,
+			wallet.WithImplicitAccountOwner(otherAddress),
+			wallet.WithImplicitAccountBlockIssuerKeys(blockIssuerKey),
+		)
+		require.NoError(t, err)
+
+		account := block.Body.(*iotago.BasicBlockBody).Payload.(*iotago.SignedTransaction).Transaction.Outputs[0].(*iotago.AccountOutput)
+		require.True(t, account.Owner().Equal(otherAddress))
+		require.Equal(t, iotago.NewBlockIssuerKeys(blockIssuerKey), account.FeatureSet().BlockIssuer().BlockIssuerKeys)
+	})
+
+	t.Run("err - native token storage deposit not covered", func(t *testing.T) {
+		// the base tokens only cover the storage deposit of the account output
+		accountOutput := builder.NewAccountOutputBuilder(keyManager.Address(iotago.AddressEd25519, 0), 0).
+			AccountID(tpkg.RandAccountID()).
+			BlockIssuer(iotago.NewBlockIssuerKeys(iotago.Ed25519PublicKeyHashBlockIssuerKeyFromImplicitAccountCreationAddress(implicitAddress)), iotago.MaxSlotIndex).
+			MustBuild()
+		minDeposit, err := mockAPI.StorageScoreStructure().MinDeposit(accountOutput)
+		require.NoError(t, err)
+
+		implicitAccount := withNativeToken(implicitAccountOutput(implicitAddress, minDeposit, 10_000_000), nativeToken)
+		_, err = wallet.BuildImplicitAccountConversion(mockAPI, signer, tpkg.RandOutputIDWithCreationSlot(0, 0), implicitAccount, blockIssuanceResponse(iotago.NewEmptyCommitment(mockAPI)), 10)
+		require.ErrorIs(t, err, iotago.ErrStorageDepositNotCovered)
+	})
+}
+
+func TestWalletConvertImplicitAccount(t *testing.T) {
+	defer gock.Off()
+
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	path := wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA, AddressIndex: 2}
+	implicitAddress, err := keyManager.AddressForBIP44Path(iotago.AddressImplicitAccountCreation, path)
+	require.NoError(t, err)
+
+	ed25519Address, err := keyManager.AddressForBIP44Path(iotago.AddressEd25519, path)
+	require.NoError(t, err)
+
+	w := wallet.NewWallet(nodeClient(t), keyManager)
+	w.TrackAddresses(implicitAddress, ed25519Address)
+
+	outputID := tpkg.RandOutputIDWithCreationSlot(0, 0)
+	otherOutputID := tpkg.RandOutputIDWithCreationSlot(0, 1)
+	w.ApplyUTXOChanges(utxoChanges(0, iotago.OutputSet{
+		outputID:      implicitAccountOutput(implicitAddress, 10_000_000, 10_000_000),
+		otherOutputID: basicOutput(ed25519Address, 1_000_000),
+	}, nil))
+	require.Len(t, w.ImplicitAccounts(), 1)
+	require.Contains(t, w.ImplicitAccounts(), outputID)
+
+	accountAddress := iotago.AccountAddressFromOutputID(outputID)
+	congestionRoute := api.EndpointWithNamedParameterValue(api.CoreRouteCongestion, api.ParameterBech32Address, accountAddress.Bech32(mockAPI.ProtocolParameters().Bech32HRP()))
+	blockID := tpkg.RandBlockID()
+
+	mockGetJSON(api.CoreRouteBlockIssuance, blockIssuanceResponse(iotago.NewEmptyCommitment(mockAPI))).Times(3)
+
+	// the account doesn't exist until the implicit account is committed, then it needs to wait for enough block issuance credits
+	gock.New(nodeAPIUrl).Get(congestionRoute).Times(1).Reply(404)
+	mockGetJSON(congestionRoute, &api.CongestionResponse{ReferenceManaCost: 10, Ready: false})
+	mockGetJSON(congestionRoute, &api.CongestionResponse{ReferenceManaCost: 10, Ready: true})
+
+	gock.New(nodeAPIUrl).
+		Post(api.CoreRouteBlocks).
+		MatchType(api.MIMEApplicationVendorIOTASerializerV2).
+		Reply(200).
+		AddHeader("Location", blockID.ToHex())
+
+	issuedBlockID, err := w.ConvertImplicitAccount(context.Background(), outputID, path, wallet.WithImplicitAccountPollInterval(time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, blockID, issuedBlockID)
+
+	t.Run("err - not an implicit account", func(t *testing.T) {
+		_, err := w.ConvertImplicitAccount(context.Background(), otherOutputID, path)
+		require.ErrorIs(t, err, wallet.ErrNotImplicitAccount)
+	})
+
+	t.Run("err - different key", func(t *testing.T) {
+		_, err := w.ConvertImplicitAccount(context.Background(), outputID, path.WithAddressIndex(3))
+		require.ErrorIs(t, err, iotago.ErrAddressKeysNotMapped)
+	})
+
+	t.Run("err - context canceled while waiting", func(t *testing.T) {
+		mockGetJSON(api.CoreRouteBlockIssuance, blockIssuanceResponse(iotago.NewEmptyCommitment(mockAPI))).Persist()
+		gock.New(nodeAPIUrl).Get(congestionRoute).Persist().Reply(404)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := w.ConvertImplicitAccount(ctx, outputID, path, wallet.WithImplicitAccountPollInterval(time.Millisecond))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -3,14 +3,15 @@ package wallet
 import (
 	"crypto/ed25519"
 	"fmt"
+	"sync"
 
 	"github.com/iotaledger/iota-crypto-demo/pkg/bip32path"
 	"github.com/iotaledger/iota-crypto-demo/pkg/bip39"
 	"github.com/iotaledger/iota-crypto-demo/pkg/slip10"
-	"github.com/iotaledger/iota-crypto-demo/pkg/slip10/eddsa"
 
 	"github.com/iotaledger/hive.go/ierrors"
 	"github.com/iotaledger/hive.go/lo"
+	"github.com/iotaledger/hive.go/runtime/options"
 	iotago "github.com/iotaledger/iota.go/v4"
 )
 
@@ -21,9 +22,16 @@ const (
 
 // KeyManager is a hierarchical deterministic key manager.
 // NOTE: The seed is stored in memory and is not protected against memory dumps.
+// Use a Keystore to persist it and call Close to wipe it from memory once it is no longer needed.
 type KeyManager struct {
-	seed []byte
-	path bip32path.Path
+	seed   []byte
+	path   bip32path.Path
+	closed bool
+
+	// keyCache caches the derived SLIP-10 keys of the path prefixes up to the account level by their path,
+	// so that derivations sharing a common prefix are only computed once. It holds at most keyCacheSize keys.
+	keyCache      map[string]*slip10.ExtendedKey
+	keyCacheMutex sync.Mutex
 }
 
 // NewKeyManagerFromRandom creates a new key manager from random entropy.
@@ -37,16 +45,12 @@ func NewKeyManagerFromRandom(path string) (*KeyManager, error) {
 	return NewKeyManager(random.Seed(), path)
 }
 
-// NewKeyManagerFromMnemonic creates a new key manager from a mnemonic.
-func NewKeyManagerFromMnemonic(mnemonic string, path string) (*KeyManager, error) {
-	mnemonicSentence := bip39.ParseMnemonic(mnemonic)
-	if len(mnemonicSentence) != 24 {
-		return nil, ierrors.New("mnemomic sentence is not 24 words long")
-	}
-
-	seed, err := bip39.MnemonicToSeed(mnemonicSentence, "")
+// NewKeyManagerFromMnemonic creates a new key manager from a 12, 15, 18, 21 or 24 words mnemonic.
+// The language of the mnemonic is detected automatically and an optional BIP39 passphrase can be set via the options.
+func NewKeyManagerFromMnemonic(mnemonic string, path string, opts ...options.Option[MnemonicOptions]) (*KeyManager, error) {
+	seed, err := MnemonicToSeed(mnemonic, opts...)
 	if err != nil {
-		return nil, ierrors.Wrap(err, "failed to convert mnemonic to seed")
+		return nil, err
 	}
 
 	return NewKeyManager(seed, path)
@@ -60,24 +64,24 @@ func NewKeyManager(seed []byte, path string) (*KeyManager, error) {
 	}
 
 	return &KeyManager{
-		seed: seed,
-		path: bip32Path,
+		seed:     seed,
+		path:     bip32Path,
+		keyCache: make(map[string]*slip10.ExtendedKey),
 	}, nil
 }
 
 // KeyPair calculates an ed25519 key pair by using slip10.
 func (k *KeyManager) KeyPair(index ...uint32) (ed25519.PrivateKey, ed25519.PublicKey) {
-	curve := eddsa.Ed25519()
-	key, err := slip10.DeriveKeyFromPath(k.seed, curve, k.Path(index...))
+	privKey, pubKey, err := k.KeyPairForPath(k.Path(index...))
 	if err != nil {
 		panic(err)
 	}
 
-	pubKey, privKey := key.Key.(eddsa.Seed).Ed25519Key()
-
-	return ed25519.PrivateKey(privKey), ed25519.PublicKey(pubKey)
+	return privKey, pubKey
 }
 
+// Path returns the path of the key manager, with the address index replaced by the given index.
+// Use the BIP44 derivation methods to address other accounts or the internal chain.
 func (k *KeyManager) Path(index ...uint32) bip32path.Path {
 	if len(index) == 0 {
 		// no additional index given, use the internal path
@@ -100,7 +104,11 @@ func (k *KeyManager) Path(index ...uint32) bip32path.Path {
 
 // Mnemonic returns the mnemonic of the key manager.
 func (k *KeyManager) Mnemonic() bip39.Mnemonic {
-	mnemonic, err := bip39.EntropyToMnemonic(k.seed)
+	if k.closed {
+		panic(ErrKeyManagerClosed)
+	}
+
+	mnemonic, err := entropyToMnemonic(MnemonicLanguageEnglish, k.seed)
 	if err != nil {
 		panic(ierrors.Wrap(err, "failed to convert seed to mnemonic"))
 	}
@@ -129,6 +137,27 @@ func (k *KeyManager) AddressSigner(indexes ...uint32) iotago.AddressSigner {
 func (k *KeyManager) Address(addressType iotago.AddressType, index ...uint32) iotago.DirectUnlockableAddress {
 	_, pubKey := k.KeyPair(index...)
 
+	return addressFromPublicKey(addressType, pubKey)
+}
+
+// Close overwrites the seed and the cached keys of the key manager with zeros.
+// The key manager can't be used anymore afterwards.
+func (k *KeyManager) Close() {
+	k.keyCacheMutex.Lock()
+	defer k.keyCacheMutex.Unlock()
+
+	for path, key := range k.keyCache {
+		zeroExtendedKey(key)
+		delete(k.keyCache, path)
+	}
+
+	zeroBytes(k.seed)
+	k.seed = nil
+	k.closed = true
+}
+
+// addressFromPublicKey calculates an address of the specified type from the given public key.
+func addressFromPublicKey(addressType iotago.AddressType, pubKey ed25519.PublicKey) iotago.DirectUnlockableAddress {
 	//nolint:exhaustive // we only support two address types
 	switch addressType {
 	case iotago.AddressEd25519:
@@ -0,0 +1,424 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/iotaledger/iota-crypto-demo/pkg/bip32path"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+)
+
+const (
+	// KeystoreVersion is the current version of the keystore format.
+	KeystoreVersion uint8 = 1
+
+	// KeystoreKDFArgon2id is the name of the argon2id key derivation function.
+	KeystoreKDFArgon2id = "argon2id"
+	// KeystoreCipherXChaCha20Poly1305 is the name of the XChaCha20-Poly1305 authenticated encryption scheme.
+	KeystoreCipherXChaCha20Poly1305 = "xchacha20-poly1305"
+
+	// DefaultKeystoreKDFTime is the default number of argon2id passes over the memory.
+	DefaultKeystoreKDFTime uint32 = 3
+	// DefaultKeystoreKDFMemory is the default amount of memory used by argon2id in KiB.
+	DefaultKeystoreKDFMemory uint32 = 64 * 1024
+	// DefaultKeystoreKDFThreads is the default number of threads used by argon2id.
+	DefaultKeystoreKDFThreads uint8 = 4
+
+	// MaxKeystoreKDFTime is the maximum number of argon2id passes over the memory of a keystore.
+	MaxKeystoreKDFTime uint32 = 100
+	// MaxKeystoreKDFMemory is the maximum amount of memory used by argon2id in KiB of a keystore.
+	MaxKeystoreKDFMemory uint32 = 4 * 1024 * 1024
+	// MaxKeystoreKDFThreads is the maximum number of threads used by argon2id of a keystore.
+	MaxKeystoreKDFThreads uint8 = 64
+
+	keystoreSaltLength = 32
+	keystoreKeyLength  = chacha20poly1305.KeySize
+)
+
+var (
+	// ErrKeystoreInvalidPassword gets returned when the keystore can't be decrypted with the given password.
+	ErrKeystoreInvalidPassword = ierrors.New("invalid keystore password")
+	// ErrKeystoreUnsupportedVersion gets returned when the version of the keystore is not supported.
+	ErrKeystoreUnsupportedVersion = ierrors.New("unsupported keystore version")
+	// ErrKeystoreInvalidFormat gets returned when the keystore is malformed.
+	ErrKeystoreInvalidFormat = ierrors.New("invalid keystore format")
+	// ErrKeyManagerClosed gets returned when a closed key manager is used.
+	ErrKeyManagerClosed = ierrors.New("key manager is closed")
+)
+
+// KeystoreKDFParams are the parameters of the argon2id key derivation function of a keystore.
+type KeystoreKDFParams struct {
+	// The name of the key derivation function.
+	Name string `json:"name"`
+	// The number of passes over the memory.
+	Time uint32 `json:"time"`
+	// The amount of memory used in KiB.
+	Memory uint32 `json:"memory"`
+	// The number of threads used.
+	Threads uint8 `json:"threads"`
+	// The hex encoded random salt.
+	Salt string `json:"salt"`
+}
+
+// KeystoreCipherParams are the parameters of the authenticated encryption of a keystore.
+type KeystoreCipherParams struct {
+	// The name of the authenticated encryption scheme.
+	Name string `json:"name"`
+	// The hex encoded random nonce.
+	Nonce string `json:"nonce"`
+}
+
+// Keystore holds the seed of a KeyManager encrypted with a password-derived key.
+// The header (version, KDF and cipher parameters and derivation path) is authenticated
+// as additional data, so it can't be altered without invalidating the ciphertext.
+type Keystore struct {
+	// The version of the keystore format.
+	Version uint8 `json:"version"`
+	// The parameters of the key derivation function.
+	KDF *KeystoreKDFParams `json:"kdf"`
+	// The parameters of the authenticated encryption.
+	Cipher *KeystoreCipherParams `json:"cipher"`
+	// The BIP32 derivation path of the key manager.
+	Path string `json:"path"`
+	// The hex encoded encrypted seed.
+	Ciphertext string `json:"ciphertext"`
+}
+
+// KeystoreOptions are the options used when a keystore gets encrypted.
+type KeystoreOptions struct {
+	kdfTime    uint32
+	kdfMemory  uint32
+	kdfThreads uint8
+}
+
+// WithKeystoreKDFParams sets the argon2id parameters used to derive the encryption key from the password.
+func WithKeystoreKDFParams(time uint32, memory uint32, threads uint8) options.Option[KeystoreOptions] {
+	return func(opts *KeystoreOptions) {
+		opts.kdfTime = time
+		opts.kdfMemory = memory
+		opts.kdfThreads = threads
+	}
+}
+
+// NewKeystore encrypts the seed and the derivation path of the given key manager with the given password.
+func NewKeystore(keyManager *KeyManager, password []byte, opts ...options.Option[KeystoreOptions]) (*Keystore, error) {
+	keyManager.keyCacheMutex.Lock()
+	if keyManager.closed {
+		keyManager.keyCacheMutex.Unlock()
+
+		return nil, ErrKeyManagerClosed
+	}
+
+	// the seed is copied, so the key manager isn't blocked while the encryption key is derived
+	seed := make([]byte, len(keyManager.seed))
+	copy(seed, keyManager.seed)
+	keyManager.keyCacheMutex.Unlock()
+	defer zeroBytes(seed)
+
+	return encryptKeystore(seed, keyManager.path, password, options.Apply(&KeystoreOptions{
+		kdfTime:    DefaultKeystoreKDFTime,
+		kdfMemory:  DefaultKeystoreKDFMemory,
+		kdfThreads: DefaultKeystoreKDFThreads,
+	}, opts))
+}
+
+// ParseKeystore parses a keystore from its JSON representation.
+func ParseKeystore(data []byte) (*Keystore, error) {
+	keystore := &Keystore{}
+	if err := json.Unmarshal(data, keystore); err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalidFormat, err)
+	}
+
+	if keystore.Version != KeystoreVersion {
+		return nil, ierrors.WithMessagef(ErrKeystoreUnsupportedVersion, "version %d, supported version %d", keystore.Version, KeystoreVersion)
+	}
+
+	if keystore.KDF == nil || keystore.KDF.Name != KeystoreKDFArgon2id {
+		return nil, ierrors.WithMessage(ErrKeystoreInvalidFormat, "unsupported key derivation function")
+	}
+
+	// the parameters are checked before they are used, since argon2id panics on zero values and allocates the given memory
+	if err := validateKeystoreKDFParams(keystore.KDF.Time, keystore.KDF.Memory, keystore.KDF.Threads); err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalidFormat, err)
+	}
+
+	if keystore.Cipher == nil || keystore.Cipher.Name != KeystoreCipherXChaCha20Poly1305 {
+		return nil, ierrors.WithMessage(ErrKeystoreInvalidFormat, "unsupported cipher")
+	}
+
+	if _, err := bip32path.ParsePath(keystore.Path); err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalidFormat, ierrors.Wrap(err, "failed to parse bip32 path"))
+	}
+
+	return keystore, nil
+}
+
+// CreateKeystoreFile encrypts the given key manager and writes the keystore to a new file at the given path.
+func CreateKeystoreFile(filePath string, keyManager *KeyManager, password []byte, opts ...options.Option[KeystoreOptions]) error {
+	keystore, err := NewKeystore(keyManager, password, opts...)
+	if err != nil {
+		return err
+	}
+
+	data, err := keystore.Bytes()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to create keystore file")
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return ierrors.Wrap(err, "failed to write keystore file")
+	}
+
+	return file.Sync()
+}
+
+// OpenKeystoreFile reads the keystore at the given path and decrypts it with the given password.
+func OpenKeystoreFile(filePath string, password []byte) (*KeyManager, error) {
+	keystore, err := ReadKeystoreFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return keystore.Open(password)
+}
+
+// ReadKeystoreFile reads the keystore at the given path without decrypting it.
+func ReadKeystoreFile(filePath string) (*Keystore, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to read keystore file")
+	}
+
+	return ParseKeystore(data)
+}
+
+// ChangeKeystoreFilePassword re-encrypts the keystore at the given path with a new password.
+// The file is replaced atomically, so the old keystore stays intact if anything fails.
+func ChangeKeystoreFilePassword(filePath string, oldPassword []byte, newPassword []byte, opts ...options.Option[KeystoreOptions]) error {
+	keystore, err := ReadKeystoreFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := keystore.ChangePassword(oldPassword, newPassword, opts...); err != nil {
+		return err
+	}
+
+	data, err := keystore.Bytes()
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return ierrors.Wrap(err, "failed to create temporary keystore file")
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+
+		return ierrors.Wrap(err, "failed to write temporary keystore file")
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+
+		return ierrors.Wrap(err, "failed to sync temporary keystore file")
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return ierrors.Wrap(err, "failed to close temporary keystore file")
+	}
+
+	return os.Rename(tmpFile.Name(), filePath)
+}
+
+// Bytes returns the JSON representation of the keystore.
+func (k *Keystore) Bytes() ([]byte, error) {
+	return json.MarshalIndent(k, "", "  ")
+}
+
+// Open decrypts the keystore with the given password and returns the key manager.
+func (k *Keystore) Open(password []byte) (*KeyManager, error) {
+	seed, err := k.decrypt(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyManager(seed, k.Path)
+}
+
+// ExportSeed decrypts the keystore with the given password and returns the plain seed.
+// The caller is responsible for zeroing the returned seed after use.
+func (k *Keystore) ExportSeed(password []byte) ([]byte, error) {
+	return k.decrypt(password)
+}
+
+// ChangePassword re-encrypts the keystore with a new password, using a fresh salt and nonce.
+// If no options are given, the KDF parameters of the keystore are kept.
+func (k *Keystore) ChangePassword(oldPassword []byte, newPassword []byte, opts ...options.Option[KeystoreOptions]) error {
+	seed, err := k.decrypt(oldPassword)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(seed)
+
+	path, err := bip32path.ParsePath(k.Path)
+	if err != nil {
+		return ierrors.Join(ErrKeystoreInvalidFormat, ierrors.Wrap(err, "failed to parse bip32 path"))
+	}
+
+	keystore, err := encryptKeystore(seed, path, newPassword, options.Apply(&KeystoreOptions{
+		kdfTime:    k.KDF.Time,
+		kdfMemory:  k.KDF.Memory,
+		kdfThreads: k.KDF.Threads,
+	}, opts))
+	if err != nil {
+		return err
+	}
+
+	*k = *keystore
+
+	return nil
+}
+
+// decrypt derives the key from the password and decrypts the seed.
+func (k *Keystore) decrypt(password []byte) ([]byte, error) {
+	salt, err := hexutil.DecodeHex(k.KDF.Salt)
+	if err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalidFormat, ierrors.Wrap(err, "failed to decode salt"))
+	}
+
+	nonce, err := hexutil.DecodeHex(k.Cipher.Nonce)
+	if err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalidFormat, ierrors.Wrap(err, "failed to decode nonce"))
+	}
+
+	if len(nonce) != chacha20poly1305.NonceSizeX {
+		return nil, ierrors.WithMessagef(ErrKeystoreInvalidFormat, "nonce must be %d bytes long", chacha20poly1305.NonceSizeX)
+	}
+
+	ciphertext, err := hexutil.DecodeHex(k.Ciphertext)
+	if err != nil {
+		return nil, ierrors.Join(ErrKeystoreInvalidFormat, ierrors.Wrap(err, "failed to decode ciphertext"))
+	}
+
+	key := argon2.IDKey(password, salt, k.KDF.Time, k.KDF.Memory, k.KDF.Threads, keystoreKeyLength)
+	defer zeroBytes(key)
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to create cipher")
+	}
+
+	seed, err := aead.Open(nil, nonce, ciphertext, k.additionalData())
+	if err != nil {
+		// the password and the header are authenticated together, so we can't tell them apart
+		return nil, ErrKeystoreInvalidPassword
+	}
+
+	return seed, nil
+}
+
+// additionalData returns the serialized header of the keystore which gets authenticated together with the seed.
+func (k *Keystore) additionalData() []byte {
+	var data []byte
+	data = append(data, k.Version)
+	data = appendString(data, k.KDF.Name)
+	data = binary.LittleEndian.AppendUint32(data, k.KDF.Time)
+	data = binary.LittleEndian.AppendUint32(data, k.KDF.Memory)
+	data = append(data, k.KDF.Threads)
+	data = appendString(data, k.KDF.Salt)
+	data = appendString(data, k.Cipher.Name)
+	data = appendString(data, k.Cipher.Nonce)
+	data = appendString(data, k.Path)
+
+	return data
+}
+
+// validateKeystoreKDFParams checks that the argon2id parameters are neither zero nor above their maximum.
+func validateKeystoreKDFParams(time uint32, memory uint32, threads uint8) error {
+	if time == 0 || memory == 0 || threads == 0 {
+		return ierrors.New("keystore KDF parameters must not be zero")
+	}
+
+	if time > MaxKeystoreKDFTime || memory > MaxKeystoreKDFMemory || threads > MaxKeystoreKDFThreads {
+		return ierrors.Errorf("keystore KDF parameters time %d, memory %d and threads %d exceed the maximum of %d, %d and %d", time, memory, threads, MaxKeystoreKDFTime, MaxKeystoreKDFMemory, MaxKeystoreKDFThreads)
+	}
+
+	return nil
+}
+
+func encryptKeystore(seed []byte, path bip32path.Path, password []byte, opts *KeystoreOptions) (*Keystore, error) {
+	if err := validateKeystoreKDFParams(opts.kdfTime, opts.kdfMemory, opts.kdfThreads); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, keystoreSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, ierrors.Wrap(err, "failed to generate salt")
+	}
+
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, ierrors.Wrap(err, "failed to generate nonce")
+	}
+
+	keystore := &Keystore{
+		Version: KeystoreVersion,
+		KDF: &KeystoreKDFParams{
+			Name:    KeystoreKDFArgon2id,
+			Time:    opts.kdfTime,
+			Memory:  opts.kdfMemory,
+			Threads: opts.kdfThreads,
+			Salt:    hexutil.EncodeHex(salt),
+		},
+		Cipher: &KeystoreCipherParams{
+			Name:  KeystoreCipherXChaCha20Poly1305,
+			Nonce: hexutil.EncodeHex(nonce),
+		},
+		Path: path.String(),
+	}
+
+	key := argon2.IDKey(password, salt, opts.kdfTime, opts.kdfMemory, opts.kdfThreads, keystoreKeyLength)
+	defer zeroBytes(key)
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to create cipher")
+	}
+
+	keystore.Ciphertext = hexutil.EncodeHex(aead.Seal(nil, nonce, seed, keystore.additionalData()))
+
+	return keystore, nil
+}
+
+// appendString appends the length prefixed string to the given data.
+func appendString(data []byte, s string) []byte {
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(s)))
+
+	return append(data, s...)
+}
+
+// zeroBytes overwrites the given byte slice with zeros.
+func zeroBytes(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
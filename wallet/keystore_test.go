@@ -0,0 +1,171 @@
+package wallet_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+// use cheap KDF parameters to keep the tests fast.
+var testKDFParams = wallet.WithKeystoreKDFParams(1, 64, 1)
+
+func TestKeystore(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	expectedAddress := keyManager.Address(iotago.AddressEd25519, 3)
+	expectedMnemonic := keyManager.Mnemonic()
+
+	keystore, err := wallet.NewKeystore(keyManager, []byte("password"), testKDFParams)
+	require.NoError(t, err)
+	require.Equal(t, wallet.KeystoreVersion, keystore.Version)
+	require.Equal(t, wallet.DefaultIOTAPath, keystore.Path)
+
+	t.Run("ok - round trip", func(t *testing.T) {
+		data, err := keystore.Bytes()
+		require.NoError(t, err)
+
+		parsedKeystore, err := wallet.ParseKeystore(data)
+		require.NoError(t, err)
+		require.Equal(t, keystore, parsedKeystore)
+
+		openedKeyManager, err := parsedKeystore.Open([]byte("password"))
+		require.NoError(t, err)
+		require.Equal(t, expectedAddress, openedKeyManager.Address(iotago.AddressEd25519, 3))
+		require.Equal(t, expectedMnemonic, openedKeyManager.Mnemonic())
+		require.Equal(t, keyManager.Path(), openedKeyManager.Path())
+	})
+
+	t.Run("err - wrong password", func(t *testing.T) {
+		_, err := keystore.Open([]byte("wrong password"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreInvalidPassword)
+	})
+
+	t.Run("err - tampered header", func(t *testing.T) {
+		tamperedKeystore := *keystore
+		tamperedKeystore.Path = wallet.DefaultShimmerPath
+
+		_, err := tamperedKeystore.Open([]byte("password"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreInvalidPassword)
+	})
+
+	t.Run("err - unsupported version", func(t *testing.T) {
+		unsupportedKeystore := *keystore
+		unsupportedKeystore.Version = wallet.KeystoreVersion + 1
+
+		data, err := unsupportedKeystore.Bytes()
+		require.NoError(t, err)
+
+		_, err = wallet.ParseKeystore(data)
+		require.ErrorIs(t, err, wallet.ErrKeystoreUnsupportedVersion)
+	})
+
+	kdfTests := []struct {
+		name   string
+		modify func(kdf *wallet.KeystoreKDFParams)
+	}{
+		{
+			name:   "err - zero time",
+			modify: func(kdf *wallet.KeystoreKDFParams) { kdf.Time = 0 },
+		},
+		{
+			name:   "err - zero memory",
+			modify: func(kdf *wallet.KeystoreKDFParams) { kdf.Memory = 0 },
+		},
+		{
+			name:   "err - zero threads",
+			modify: func(kdf *wallet.KeystoreKDFParams) { kdf.Threads = 0 },
+		},
+		{
+			name:   "err - time too high",
+			modify: func(kdf *wallet.KeystoreKDFParams) { kdf.Time = wallet.MaxKeystoreKDFTime + 1 },
+		},
+		{
+			name:   "err - memory too high",
+			modify: func(kdf *wallet.KeystoreKDFParams) { kdf.Memory = wallet.MaxKeystoreKDFMemory + 1 },
+		},
+		{
+			name:   "err - threads too high",
+			modify: func(kdf *wallet.KeystoreKDFParams) { kdf.Threads = wallet.MaxKeystoreKDFThreads + 1 },
+		},
+	}
+
+	for _, test := range kdfTests {
+		t.Run(test.name, func(t *testing.T) {
+			kdf := *keystore.KDF
+			test.modify(&kdf)
+
+			invalidKeystore := *keystore
+			invalidKeystore.KDF = &kdf
+
+			data, err := invalidKeystore.Bytes()
+			require.NoError(t, err)
+
+			_, err = wallet.ParseKeystore(data)
+			require.ErrorIs(t, err, wallet.ErrKeystoreInvalidFormat)
+		})
+	}
+
+	t.Run("ok - change password", func(t *testing.T) {
+		changedKeystore := *keystore
+		require.ErrorIs(t, changedKeystore.ChangePassword([]byte("wrong password"), []byte("new password")), wallet.ErrKeystoreInvalidPassword)
+		require.NoError(t, changedKeystore.ChangePassword([]byte("password"), []byte("new password")))
+		require.NotEqual(t, keystore.KDF.Salt, changedKeystore.KDF.Salt)
+
+		_, err := changedKeystore.Open([]byte("password"))
+		require.ErrorIs(t, err, wallet.ErrKeystoreInvalidPassword)
+
+		seed, err := changedKeystore.ExportSeed([]byte("new password"))
+		require.NoError(t, err)
+
+		exportedKeyManager, err := wallet.NewKeyManager(seed, changedKeystore.Path)
+		require.NoError(t, err)
+		require.Equal(t, expectedAddress, exportedKeyManager.Address(iotago.AddressEd25519, 3))
+	})
+
+	t.Run("ok - close zeroes the seed", func(t *testing.T) {
+		seed, err := keystore.ExportSeed([]byte("password"))
+		require.NoError(t, err)
+
+		closedKeyManager, err := wallet.NewKeyManager(seed, keystore.Path)
+		require.NoError(t, err)
+
+		closedKeyManager.Close()
+		require.Equal(t, make([]byte, len(seed)), seed)
+		require.PanicsWithValue(t, wallet.ErrKeyManagerClosed, func() { closedKeyManager.KeyPair() })
+
+		_, err = wallet.NewKeystore(closedKeyManager, []byte("password"), testKDFParams)
+		require.ErrorIs(t, err, wallet.ErrKeyManagerClosed)
+	})
+}
+
+func TestKeystoreFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "wallet.keystore")
+
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultShimmerPath)
+	require.NoError(t, err)
+	expectedAddress := keyManager.Address(iotago.AddressEd25519)
+
+	require.NoError(t, wallet.CreateKeystoreFile(filePath, keyManager, []byte("password"), testKDFParams))
+
+	// an existing keystore is never overwritten
+	require.Error(t, wallet.CreateKeystoreFile(filePath, keyManager, []byte("password"), testKDFParams))
+
+	fileInfo, err := os.Stat(filePath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), fileInfo.Mode().Perm())
+
+	require.NoError(t, wallet.ChangeKeystoreFilePassword(filePath, []byte("password"), []byte("new password")))
+
+	_, err = wallet.OpenKeystoreFile(filePath, []byte("password"))
+	require.ErrorIs(t, err, wallet.ErrKeystoreInvalidPassword)
+
+	openedKeyManager, err := wallet.OpenKeystoreFile(filePath, []byte("new password"))
+	require.NoError(t, err)
+	require.Equal(t, expectedAddress, openedKeyManager.Address(iotago.AddressEd25519))
+}
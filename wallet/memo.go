@@ -0,0 +1,221 @@
+package wallet
+
+import (
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"io"
+
+	"filippo.io/edwards25519"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+)
+
+// MemoVersion is the version of the envelope format of encrypted memos.
+type MemoVersion byte
+
+const (
+	// MemoVersion1 encrypts the memo with ChaCha20-Poly1305, using a key derived via HKDF-SHA256
+	// from an X25519 key agreement between an ephemeral key and the recipient's Ed25519 key converted to X25519.
+	// The envelope is version (1 byte) || ephemeral X25519 public key (32 bytes) || ciphertext with tag.
+	MemoVersion1 MemoVersion = 1
+
+	// MemoMetadataKey is the default key of encrypted memos in MetadataFeatures.
+	MemoMetadataKey = "memo"
+
+	memoEphemeralKeyLength = 32
+	memoHeaderLength       = 1 + memoEphemeralKeyLength
+	memoKDFInfo            = "IOTA Encrypted Memo v1"
+)
+
+var (
+	// ErrInvalidMemo gets returned when an encrypted memo is malformed or can't be decrypted with the given key.
+	ErrInvalidMemo = ierrors.New("invalid encrypted memo")
+	// ErrUnsupportedMemoVersion gets returned when an encrypted memo uses an unknown envelope version.
+	ErrUnsupportedMemoVersion = ierrors.New("unsupported encrypted memo version")
+)
+
+// EncryptMemo encrypts the memo to the given Ed25519 public key of the recipient.
+// Only the holder of the matching private key can decrypt it, the sender can't decrypt it either.
+func EncryptMemo(recipient ed25519.PublicKey, memo []byte) ([]byte, error) {
+	recipientKey, err := x25519PublicKey(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to generate ephemeral key")
+	}
+
+	sharedSecret, err := ephemeralKey.ECDH(recipientKey)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to derive shared secret")
+	}
+
+	header := make([]byte, 0, memoHeaderLength)
+	header = append(header, byte(MemoVersion1))
+	header = append(header, ephemeralKey.PublicKey().Bytes()...)
+
+	aead, nonce, err := memoCipher(sharedSecret, header, recipientKey.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(header, nonce, memo, header), nil
+}
+
+// DecryptMemo decrypts the encrypted memo with the Ed25519 private key of the recipient.
+func DecryptMemo(privateKey ed25519.PrivateKey, encryptedMemo []byte) ([]byte, error) {
+	if len(encryptedMemo) < memoHeaderLength {
+		return nil, ierrors.WithMessagef(ErrInvalidMemo, "encrypted memo is too short: %d bytes", len(encryptedMemo))
+	}
+
+	if version := MemoVersion(encryptedMemo[0]); version != MemoVersion1 {
+		return nil, ierrors.WithMessagef(ErrUnsupportedMemoVersion, "version %d", version)
+	}
+
+	recipientKey, err := x25519PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralKey, err := ecdh.X25519().NewPublicKey(encryptedMemo[1:memoHeaderLength])
+	if err != nil {
+		return nil, ierrors.Join(ErrInvalidMemo, err)
+	}
+
+	sharedSecret, err := recipientKey.ECDH(ephemeralKey)
+	if err != nil {
+		return nil, ierrors.Join(ErrInvalidMemo, err)
+	}
+
+	header := encryptedMemo[:memoHeaderLength]
+	aead, nonce, err := memoCipher(sharedSecret, header, recipientKey.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	memo, err := aead.Open(nil, nonce, encryptedMemo[memoHeaderLength:], header)
+	if err != nil {
+		return nil, ierrors.WithMessage(ErrInvalidMemo, "decryption failed, the memo is corrupted or encrypted to a different key")
+	}
+
+	return memo, nil
+}
+
+// NewEncryptedMetadataFeature creates a MetadataFeature holding the memo encrypted to the recipient under the given key.
+func NewEncryptedMetadataFeature(key iotago.MetadataFeatureEntriesKey, recipient ed25519.PublicKey, memo []byte) (*iotago.MetadataFeature, error) {
+	encryptedMemo, err := EncryptMemo(recipient, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &iotago.MetadataFeature{
+		Entries: iotago.MetadataFeatureEntries{key: encryptedMemo},
+	}, nil
+}
+
+// NewEncryptedTaggedData creates a TaggedData payload holding the memo encrypted to the recipient.
+// The tag is not encrypted.
+func NewEncryptedTaggedData(tag []byte, recipient ed25519.PublicKey, memo []byte) (*iotago.TaggedData, error) {
+	encryptedMemo, err := EncryptMemo(recipient, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &iotago.TaggedData{
+		Tag:  tag,
+		Data: encryptedMemo,
+	}, nil
+}
+
+// DecryptMemo decrypts the encrypted memo with the key of the given BIP44 path.
+func (k *KeyManager) DecryptMemo(path BIP44Path, encryptedMemo []byte) ([]byte, error) {
+	privateKey, _, err := k.KeyPairForBIP44Path(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecryptMemo(privateKey, encryptedMemo)
+}
+
+// DecryptMetadataFeatureMemo decrypts the encrypted memo stored under the given key of the MetadataFeature
+// with the key of the given BIP44 path.
+func (k *KeyManager) DecryptMetadataFeatureMemo(path BIP44Path, feature *iotago.MetadataFeature, key iotago.MetadataFeatureEntriesKey) ([]byte, error) {
+	encryptedMemo, exists := feature.Entries[key]
+	if !exists {
+		return nil, ierrors.WithMessagef(ErrInvalidMemo, "metadata feature has no entry %q", key)
+	}
+
+	return k.DecryptMemo(path, encryptedMemo)
+}
+
+// DecryptTaggedDataMemo decrypts the encrypted memo of the TaggedData payload with the key of the given BIP44 path.
+func (k *KeyManager) DecryptTaggedDataMemo(path BIP44Path, taggedData *iotago.TaggedData) ([]byte, error) {
+	return k.DecryptMemo(path, taggedData.Data)
+}
+
+// memoCipher derives the ChaCha20-Poly1305 cipher and nonce of a memo from the shared secret,
+// bound to the header and the recipient's X25519 public key.
+func memoCipher(sharedSecret []byte, header []byte, recipientKey []byte) (cipher.AEAD, []byte, error) {
+	salt := make([]byte, 0, len(header)+len(recipientKey))
+	salt = append(salt, header...)
+	salt = append(salt, recipientKey...)
+
+	// every memo uses a fresh ephemeral key, so the key and the nonce are never reused
+	keyMaterial := make([]byte, chacha20poly1305.KeySize+chacha20poly1305.NonceSize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, salt, []byte(memoKDFInfo)), keyMaterial); err != nil {
+		return nil, nil, ierrors.Wrap(err, "failed to derive memo key")
+	}
+
+	aead, err := chacha20poly1305.New(keyMaterial[:chacha20poly1305.KeySize])
+	if err != nil {
+		return nil, nil, ierrors.Wrap(err, "failed to create memo cipher")
+	}
+
+	return aead, keyMaterial[chacha20poly1305.KeySize:], nil
+}
+
+// x25519PublicKey converts the Ed25519 public key to the X25519 public key of the birationally equivalent Montgomery curve.
+func x25519PublicKey(publicKey ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, ierrors.Errorf("invalid ed25519 public key length %d", len(publicKey))
+	}
+
+	point, err := new(edwards25519.Point).SetBytes(publicKey)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "invalid ed25519 public key")
+	}
+
+	x25519Key, err := ecdh.X25519().NewPublicKey(point.BytesMontgomery())
+	if err != nil {
+		return nil, ierrors.Wrap(err, "invalid X25519 public key")
+	}
+
+	return x25519Key, nil
+}
+
+// x25519PrivateKey converts the Ed25519 private key to the X25519 private key, which is the first half of the hashed seed.
+func x25519PrivateKey(privateKey ed25519.PrivateKey) (*ecdh.PrivateKey, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, ierrors.Errorf("invalid ed25519 private key length %d", len(privateKey))
+	}
+
+	digest := sha512.Sum512(privateKey.Seed())
+	defer zeroBytes(digest[:])
+
+	// the scalar is clamped by X25519
+	x25519Key, err := ecdh.X25519().NewPrivateKey(digest[:32])
+	if err != nil {
+		return nil, ierrors.Wrap(err, "invalid X25519 private key")
+	}
+
+	return x25519Key, nil
+}
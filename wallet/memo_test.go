@@ -0,0 +1,146 @@
+package wallet_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotaledger/hive.go/serializer/v2/serix"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestEncryptMemo(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	privateKey, publicKey := keyManager.KeyPair(0)
+	otherPrivateKey, _ := keyManager.KeyPair(1)
+	memo := []byte("invoice 2024-0042")
+
+	encryptedMemo, err := wallet.EncryptMemo(publicKey, memo)
+	require.NoError(t, err)
+	require.Equal(t, byte(wallet.MemoVersion1), encryptedMemo[0])
+	require.NotContains(t, string(encryptedMemo), string(memo))
+
+	decryptedMemo, err := wallet.DecryptMemo(privateKey, encryptedMemo)
+	require.NoError(t, err)
+	require.Equal(t, memo, decryptedMemo)
+
+	// every encryption uses a fresh ephemeral key
+	otherEncryptedMemo, err := wallet.EncryptMemo(publicKey, memo)
+	require.NoError(t, err)
+	require.NotEqual(t, encryptedMemo, otherEncryptedMemo)
+
+	emptyEncryptedMemo, err := wallet.EncryptMemo(publicKey, nil)
+	require.NoError(t, err)
+
+	decryptedEmptyMemo, err := wallet.DecryptMemo(privateKey, emptyEncryptedMemo)
+	require.NoError(t, err)
+	require.Empty(t, decryptedEmptyMemo)
+
+	tamperedMemo := append([]byte(nil), encryptedMemo...)
+	tamperedMemo[len(tamperedMemo)-1] ^= 1
+
+	unsupportedVersionMemo := append([]byte(nil), encryptedMemo...)
+	unsupportedVersionMemo[0] = 2
+
+	tests := []struct {
+		name          string
+		privateKey    ed25519.PrivateKey
+		encryptedMemo []byte
+		err           error
+	}{
+		{
+			name:          "err - different key",
+			privateKey:    otherPrivateKey,
+			encryptedMemo: encryptedMemo,
+			err:           wallet.ErrInvalidMemo,
+		},
+		{
+			name:          "err - tampered",
+			privateKey:    privateKey,
+			encryptedMemo: tamperedMemo,
+			err:           wallet.ErrInvalidMemo,
+		},
+		{
+			name:          "err - too short",
+			privateKey:    privateKey,
+			encryptedMemo: encryptedMemo[:20],
+			err:           wallet.ErrInvalidMemo,
+		},
+		{
+			name:          "err - unsupported version",
+			privateKey:    privateKey,
+			encryptedMemo: unsupportedVersionMemo,
+			err:           wallet.ErrUnsupportedMemoVersion,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := wallet.DecryptMemo(test.privateKey, test.encryptedMemo)
+			require.ErrorIs(t, err, test.err)
+		})
+	}
+
+	t.Run("err - invalid public key", func(t *testing.T) {
+		_, err := wallet.EncryptMemo(tpkg.RandBytes(31), memo)
+		require.Error(t, err)
+	})
+}
+
+func TestEncryptedMemoPayloads(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	path := wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA, AddressIndex: 3}
+	_, publicKey, err := keyManager.KeyPairForBIP44Path(path)
+	require.NoError(t, err)
+
+	memo := []byte("payment reference 1337")
+
+	t.Run("metadata feature", func(t *testing.T) {
+		feature, err := wallet.NewEncryptedMetadataFeature(wallet.MemoMetadataKey, publicKey, memo)
+		require.NoError(t, err)
+
+		// the feature survives the serialization of an output
+		output := &iotago.BasicOutput{
+			Amount: 1_000_000,
+			UnlockConditions: iotago.BasicOutputUnlockConditions{
+				&iotago.AddressUnlockCondition{Address: iotago.Ed25519AddressFromPubKey(publicKey)},
+			},
+			Features: iotago.BasicOutputFeatures{feature},
+		}
+
+		outputBytes, err := tpkg.ZeroCostTestAPI.Encode(output, serix.WithValidation())
+		require.NoError(t, err)
+
+		decodedOutput := &iotago.BasicOutput{}
+		_, err = tpkg.ZeroCostTestAPI.Decode(outputBytes, decodedOutput, serix.WithValidation())
+		require.NoError(t, err)
+
+		decryptedMemo, err := keyManager.DecryptMetadataFeatureMemo(path, decodedOutput.FeatureSet().Metadata(), wallet.MemoMetadataKey)
+		require.NoError(t, err)
+		require.Equal(t, memo, decryptedMemo)
+
+		_, err = keyManager.DecryptMetadataFeatureMemo(path, feature, "other")
+		require.ErrorIs(t, err, wallet.ErrInvalidMemo)
+
+		_, err = keyManager.DecryptMetadataFeatureMemo(path.WithAddressIndex(4), feature, wallet.MemoMetadataKey)
+		require.ErrorIs(t, err, wallet.ErrInvalidMemo)
+	})
+
+	t.Run("tagged data", func(t *testing.T) {
+		taggedData, err := wallet.NewEncryptedTaggedData([]byte("invoice"), publicKey, memo)
+		require.NoError(t, err)
+		require.Equal(t, []byte("invoice"), taggedData.Tag)
+
+		decryptedMemo, err := keyManager.DecryptTaggedDataMemo(path, taggedData)
+		require.NoError(t, err)
+		require.Equal(t, memo, decryptedMemo)
+	})
+}
@@ -0,0 +1,224 @@
+package wallet
+
+import (
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+)
+
+const (
+	// MessageSigningDomain is the domain of messages signed by addresses.
+	MessageSigningDomain = "IOTA Signed Message"
+	// AccountMessageSigningDomain is the domain of messages signed by the block issuer keys of accounts.
+	AccountMessageSigningDomain = "IOTA Signed Account Message"
+)
+
+var (
+	// ErrInvalidMessageSignature gets returned when the signature of a message is invalid for the address or account.
+	ErrInvalidMessageSignature = ierrors.New("invalid message signature")
+	// ErrMessageSigningUnsupportedAddress gets returned when messages can't be signed by the given address type.
+	ErrMessageSigningUnsupportedAddress = ierrors.New("address type does not support message signing")
+	// ErrMessageSigningThresholdNotReached gets returned when the signer doesn't hold the keys to reach the threshold of a multi address.
+	ErrMessageSigningThresholdNotReached = ierrors.New("signer can't reach the threshold of the multi address")
+)
+
+// MessageSigningHash returns the hash of the message that gets signed by addresses.
+// The message is prefixed with MessageSigningDomain, so that a signed message can never be a valid transaction signature.
+func MessageSigningHash(message []byte) []byte {
+	return messageSigningHash(MessageSigningDomain, nil, message)
+}
+
+// AccountMessageSigningHash returns the hash of the message that gets signed by the block issuer keys of the given account.
+func AccountMessageSigningHash(accountID iotago.AccountID, message []byte) []byte {
+	return messageSigningHash(AccountMessageSigningDomain, accountID[:], message)
+}
+
+// SignMessage signs the message for the given address with the keys of the signer.
+// The returned unlock is a SignatureUnlock for Ed25519, ImplicitAccountCreation and restricted addresses.
+// For MultiAddresses, a MultiUnlock is returned, which contains signatures of the addresses the signer holds keys for
+// until the threshold is reached, and EmptyUnlocks for all remaining addresses.
+// It fails with ErrMessageSigningThresholdNotReached if the signer doesn't hold enough keys to reach the threshold.
+func SignMessage(signer iotago.AddressSigner, address iotago.Address, message []byte) (iotago.Unlock, error) {
+	signingHash := MessageSigningHash(message)
+
+	switch addr := builder.ResolveUnderlyingAddress(address).(type) {
+	case *iotago.Ed25519Address, *iotago.ImplicitAccountCreationAddress:
+		signature, err := signer.Sign(addr, signingHash)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to sign message for address %s", addr)
+		}
+
+		return &iotago.SignatureUnlock{Signature: signature}, nil
+
+	case *iotago.MultiAddress:
+		var weight uint16
+		unlocks := make([]iotago.Unlock, 0, len(addr.Addresses))
+		for _, addressWithWeight := range addr.Addresses {
+			if weight >= addr.Threshold || !isMessageSigningAddress(addressWithWeight.Address) {
+				unlocks = append(unlocks, &iotago.EmptyUnlock{})
+				continue
+			}
+
+			if _, err := signer.SignerUIDForAddress(addressWithWeight.Address); err != nil {
+				if ierrors.Is(err, iotago.ErrAddressKeysNotMapped) {
+					unlocks = append(unlocks, &iotago.EmptyUnlock{})
+					continue
+				}
+
+				return nil, ierrors.Wrapf(err, "failed to get signer UID for address %s", addressWithWeight.Address)
+			}
+
+			signature, err := signer.Sign(addressWithWeight.Address, signingHash)
+			if err != nil {
+				return nil, ierrors.Wrapf(err, "failed to sign message for address %s", addressWithWeight.Address)
+			}
+
+			unlocks = append(unlocks, &iotago.SignatureUnlock{Signature: signature})
+			weight += uint16(addressWithWeight.Weight)
+		}
+
+		if weight < addr.Threshold {
+			return nil, ierrors.WithMessagef(ErrMessageSigningThresholdNotReached, "the signer only holds keys for a weight of %d of the multi address threshold %d", weight, addr.Threshold)
+		}
+
+		return &iotago.MultiUnlock{Unlocks: unlocks}, nil
+
+	default:
+		return nil, ierrors.WithMessagef(ErrMessageSigningUnsupportedAddress, "address type %s", address.Type())
+	}
+}
+
+// VerifyMessage verifies the unlock created by SignMessage for the given address and message.
+func VerifyMessage(address iotago.Address, message []byte, unlock iotago.Unlock) error {
+	signingHash := MessageSigningHash(message)
+
+	switch addr := builder.ResolveUnderlyingAddress(address).(type) {
+	case *iotago.Ed25519Address, *iotago.ImplicitAccountCreationAddress:
+		return verifySignatureUnlock(addr, signingHash, unlock)
+
+	case *iotago.MultiAddress:
+		multiUnlock, ok := unlock.(*iotago.MultiUnlock)
+		if !ok {
+			return ierrors.WithMessagef(ErrInvalidMessageSignature, "expected a multi unlock for a multi address, got %T", unlock)
+		}
+
+		if len(multiUnlock.Unlocks) != len(addr.Addresses) {
+			return ierrors.WithMessagef(ErrInvalidMessageSignature, "the multi unlock has %d unlocks for %d addresses", len(multiUnlock.Unlocks), len(addr.Addresses))
+		}
+
+		var weight uint16
+		for i, addressWithWeight := range addr.Addresses {
+			if _, isEmpty := multiUnlock.Unlocks[i].(*iotago.EmptyUnlock); isEmpty {
+				continue
+			}
+
+			if !isMessageSigningAddress(addressWithWeight.Address) {
+				return ierrors.WithMessagef(ErrMessageSigningUnsupportedAddress, "address type %s at index %d of the multi address", addressWithWeight.Address.Type(), i)
+			}
+
+			if err := verifySignatureUnlock(addressWithWeight.Address, signingHash, multiUnlock.Unlocks[i]); err != nil {
+				return ierrors.Wrapf(err, "invalid unlock at index %d", i)
+			}
+
+			weight += uint16(addressWithWeight.Weight)
+		}
+
+		if weight < addr.Threshold {
+			return ierrors.WithMessagef(ErrInvalidMessageSignature, "the signatures only reach a weight of %d of the multi address threshold %d", weight, addr.Threshold)
+		}
+
+		return nil
+
+	default:
+		return ierrors.WithMessagef(ErrMessageSigningUnsupportedAddress, "address type %s", address.Type())
+	}
+}
+
+// SignAccountMessage signs the message for the given account with the key behind the given block issuer key,
+// which proves control over the account to anyone who knows the account's BlockIssuerFeature.
+func SignAccountMessage(signer iotago.AddressSigner, accountID iotago.AccountID, blockIssuerKey *iotago.Ed25519PublicKeyHashBlockIssuerKey, message []byte) (*iotago.Ed25519Signature, error) {
+	// the address of a public key is its public key hash
+	address := iotago.Ed25519Address(blockIssuerKey.PublicKeyHash)
+
+	signature, err := signer.Sign(&address, AccountMessageSigningHash(accountID, message))
+	if err != nil {
+		return nil, ierrors.Wrapf(err, "failed to sign message for account %s", accountID)
+	}
+
+	ed25519Signature, ok := signature.(*iotago.Ed25519Signature)
+	if !ok {
+		return nil, ierrors.WithMessagef(iotago.ErrAddressKeysWrongType, "expected an Ed25519 signature, got %T", signature)
+	}
+
+	return ed25519Signature, nil
+}
+
+// VerifyAccountMessage verifies the signature created by SignAccountMessage for the given account output.
+// The public key of the signature needs to match one of the Ed25519PublicKeyHashBlockIssuerKeys of the account's BlockIssuerFeature.
+// The account ID needs to be given, since it is empty in the output that created the account.
+func VerifyAccountMessage(accountID iotago.AccountID, account *iotago.AccountOutput, message []byte, signature *iotago.Ed25519Signature) error {
+	if !account.AccountID.Empty() && account.AccountID != accountID {
+		return ierrors.WithMessagef(ErrInvalidMessageSignature, "the output belongs to account %s instead of %s", account.AccountID, accountID)
+	}
+
+	blockIssuerFeature := account.FeatureSet().BlockIssuer()
+	if blockIssuerFeature == nil {
+		return ierrors.WithMessagef(ErrInvalidMessageSignature, "account %s has no block issuer feature", accountID)
+	}
+
+	if !blockIssuerFeature.BlockIssuerKeys.Has(iotago.Ed25519PublicKeyHashBlockIssuerKeyFromPublicKey(signature.PublicKey)) {
+		return ierrors.WithMessagef(ErrInvalidMessageSignature, "the public key of the signature is not a block issuer key of account %s", accountID)
+	}
+
+	if err := signature.Valid(AccountMessageSigningHash(accountID, message), iotago.Ed25519AddressFromPubKey(signature.PublicKey[:])); err != nil {
+		return ierrors.Join(ErrInvalidMessageSignature, err)
+	}
+
+	return nil
+}
+
+// verifySignatureUnlock verifies that the unlock is a SignatureUnlock with a valid Ed25519 signature for the given address.
+func verifySignatureUnlock(address iotago.Address, signingHash []byte, unlock iotago.Unlock) error {
+	signatureUnlock, ok := unlock.(*iotago.SignatureUnlock)
+	if !ok {
+		return ierrors.WithMessagef(ErrInvalidMessageSignature, "expected a signature unlock, got %T", unlock)
+	}
+
+	signature, ok := signatureUnlock.Signature.(*iotago.Ed25519Signature)
+	if !ok {
+		return ierrors.WithMessagef(ErrInvalidMessageSignature, "expected an Ed25519 signature, got %T", signatureUnlock.Signature)
+	}
+
+	if !signature.MatchesAddress(address) {
+		return ierrors.WithMessagef(ErrInvalidMessageSignature, "the public key of the signature doesn't match address %s", address)
+	}
+
+	if err := signature.Valid(signingHash, iotago.Ed25519AddressFromPubKey(signature.PublicKey[:])); err != nil {
+		return ierrors.Join(ErrInvalidMessageSignature, err)
+	}
+
+	return nil
+}
+
+// isMessageSigningAddress checks whether the address is directly backed by an Ed25519 key.
+func isMessageSigningAddress(address iotago.Address) bool {
+	switch address.(type) {
+	case *iotago.Ed25519Address, *iotago.ImplicitAccountCreationAddress:
+		return true
+	default:
+		return false
+	}
+}
+
+// messageSigningHash returns the blake2b-256 hash of the length prefixed domain, the context and the message.
+func messageSigningHash(domain string, context []byte, message []byte) []byte {
+	h, _ := blake2b.New256(nil)
+	h.Write([]byte{byte(len(domain))})
+	h.Write([]byte(domain))
+	h.Write(context)
+	h.Write(message)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,179 @@
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	hiveEd25519 "github.com/iotaledger/hive.go/crypto/ed25519"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestSignMessage(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	signer := keyManager.AddressSigner(0, 1)
+	address := keyManager.Address(iotago.AddressEd25519, 0)
+	otherAddress := keyManager.Address(iotago.AddressEd25519, 1)
+	message := []byte("sign in to example.com, nonce 42")
+
+	multiAddress := &iotago.MultiAddress{
+		Addresses: iotago.AddressesWithWeight{
+			{Address: tpkg.RandEd25519Address(), Weight: 1},
+			{Address: address, Weight: 1},
+			{Address: tpkg.RandAccountAddress(), Weight: 1},
+			{Address: otherAddress, Weight: 1},
+		},
+		Threshold: 2,
+	}
+
+	tests := []struct {
+		name    string
+		address iotago.Address
+		signErr error
+	}{
+		{
+			name:    "ok - ed25519",
+			address: address,
+		},
+		{
+			name:    "ok - implicit account creation",
+			address: keyManager.Address(iotago.AddressImplicitAccountCreation, 0),
+		},
+		{
+			name:    "ok - restricted",
+			address: &iotago.RestrictedAddress{Address: address},
+		},
+		{
+			name:    "ok - multi",
+			address: multiAddress,
+		},
+		{
+			name:    "ok - restricted multi",
+			address: &iotago.RestrictedAddress{Address: multiAddress},
+		},
+		{
+			name: "err - multi threshold not reached",
+			address: &iotago.MultiAddress{
+				Addresses: iotago.AddressesWithWeight{
+					{Address: address, Weight: 1},
+					{Address: tpkg.RandEd25519Address(), Weight: 1},
+				},
+				Threshold: 2,
+			},
+			signErr: wallet.ErrMessageSigningThresholdNotReached,
+		},
+		{
+			name:    "err - account",
+			address: tpkg.RandAccountAddress(),
+			signErr: wallet.ErrMessageSigningUnsupportedAddress,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unlock, err := wallet.SignMessage(signer, test.address, message)
+			if test.signErr != nil {
+				require.ErrorIs(t, err, test.signErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, wallet.VerifyMessage(test.address, message, unlock))
+
+			// the signature is only valid for the signed message
+			require.ErrorIs(t, wallet.VerifyMessage(test.address, []byte("another message"), unlock), wallet.ErrInvalidMessageSignature)
+
+			// and only for the signed address
+			require.ErrorIs(t, wallet.VerifyMessage(tpkg.RandEd25519Address(), message, unlock), wallet.ErrInvalidMessageSignature)
+		})
+	}
+
+	t.Run("ok - multi unlock layout", func(t *testing.T) {
+		unlock, err := wallet.SignMessage(signer, multiAddress, message)
+		require.NoError(t, err)
+
+		multiUnlock, ok := unlock.(*iotago.MultiUnlock)
+		require.True(t, ok)
+		require.Len(t, multiUnlock.Unlocks, 4)
+		require.IsType(t, &iotago.EmptyUnlock{}, multiUnlock.Unlocks[0])
+		require.IsType(t, &iotago.SignatureUnlock{}, multiUnlock.Unlocks[1])
+		require.IsType(t, &iotago.EmptyUnlock{}, multiUnlock.Unlocks[2])
+		require.IsType(t, &iotago.SignatureUnlock{}, multiUnlock.Unlocks[3])
+
+		// a single signature doesn't reach the threshold
+		multiUnlock.Unlocks[3] = &iotago.EmptyUnlock{}
+		require.ErrorIs(t, wallet.VerifyMessage(multiAddress, message, multiUnlock), wallet.ErrInvalidMessageSignature)
+	})
+
+	t.Run("err - transaction signature", func(t *testing.T) {
+		// a plain signature of the message, e.g. a transaction signature, is not a valid message signature
+		signature, err := signer.Sign(address, message)
+		require.NoError(t, err)
+		require.ErrorIs(t, wallet.VerifyMessage(address, message, &iotago.SignatureUnlock{Signature: signature}), wallet.ErrInvalidMessageSignature)
+	})
+}
+
+func TestSignAccountMessage(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	_, publicKey := keyManager.KeyPair(0)
+	blockIssuerKey := iotago.Ed25519PublicKeyHashBlockIssuerKeyFromPublicKey(hiveEd25519.PublicKey(publicKey))
+
+	accountID := tpkg.RandAccountID()
+	account := &iotago.AccountOutput{
+		Amount:    1_000_000,
+		AccountID: accountID,
+		UnlockConditions: iotago.AccountOutputUnlockConditions{
+			&iotago.AddressUnlockCondition{Address: tpkg.RandEd25519Address()},
+		},
+		Features: iotago.AccountOutputFeatures{
+			&iotago.BlockIssuerFeature{
+				ExpirySlot:      iotago.MaxSlotIndex,
+				BlockIssuerKeys: iotago.NewBlockIssuerKeys(tpkg.RandBlockIssuerKey(), blockIssuerKey),
+			},
+		},
+	}
+	message := []byte("attestation")
+
+	signature, err := wallet.SignAccountMessage(keyManager.AddressSigner(0), accountID, blockIssuerKey, message)
+	require.NoError(t, err)
+	require.NoError(t, wallet.VerifyAccountMessage(accountID, account, message, signature))
+
+	// the account ID of a newly created account is empty
+	newAccount := account.Clone().(*iotago.AccountOutput) //nolint:forcetypeassert // we know that it is an account output
+	newAccount.AccountID = iotago.EmptyAccountID
+	require.NoError(t, wallet.VerifyAccountMessage(accountID, newAccount, message, signature))
+
+	t.Run("err - different account", func(t *testing.T) {
+		require.ErrorIs(t, wallet.VerifyAccountMessage(tpkg.RandAccountID(), account, message, signature), wallet.ErrInvalidMessageSignature)
+		require.ErrorIs(t, wallet.VerifyAccountMessage(tpkg.RandAccountID(), newAccount, message, signature), wallet.ErrInvalidMessageSignature)
+	})
+
+	t.Run("err - different message", func(t *testing.T) {
+		require.ErrorIs(t, wallet.VerifyAccountMessage(accountID, account, []byte("another message"), signature), wallet.ErrInvalidMessageSignature)
+	})
+
+	t.Run("err - not a block issuer key", func(t *testing.T) {
+		_, otherPublicKey := keyManager.KeyPair(1)
+
+		otherSignature, err := wallet.SignAccountMessage(keyManager.AddressSigner(1), accountID, iotago.Ed25519PublicKeyHashBlockIssuerKeyFromPublicKey(hiveEd25519.PublicKey(otherPublicKey)), message)
+		require.NoError(t, err)
+		require.ErrorIs(t, wallet.VerifyAccountMessage(accountID, account, message, otherSignature), wallet.ErrInvalidMessageSignature)
+	})
+
+	t.Run("err - no block issuer feature", func(t *testing.T) {
+		noBlockIssuerAccount := account.Clone().(*iotago.AccountOutput) //nolint:forcetypeassert // we know that it is an account output
+		noBlockIssuerAccount.Features = nil
+		require.ErrorIs(t, wallet.VerifyAccountMessage(accountID, noBlockIssuerAccount, message, signature), wallet.ErrInvalidMessageSignature)
+	})
+
+	t.Run("err - keys not mapped", func(t *testing.T) {
+		_, err := wallet.SignAccountMessage(keyManager.AddressSigner(1), accountID, blockIssuerKey, message)
+		require.ErrorIs(t, err, iotago.ErrAddressKeysNotMapped)
+	})
+}
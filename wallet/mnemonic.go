@@ -0,0 +1,287 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	"github.com/iotaledger/iota-crypto-demo/pkg/bip39"
+	"github.com/iotaledger/iota-crypto-demo/pkg/bip39/wordlist"
+)
+
+// MnemonicLanguage is the language of the BIP39 word list of a mnemonic.
+type MnemonicLanguage string
+
+const (
+	// MnemonicLanguageEnglish is the english BIP39 word list.
+	MnemonicLanguageEnglish MnemonicLanguage = "english"
+	// MnemonicLanguageJapanese is the japanese BIP39 word list.
+	MnemonicLanguageJapanese MnemonicLanguage = "japanese"
+)
+
+// MnemonicLanguages are the supported mnemonic languages.
+var MnemonicLanguages = []MnemonicLanguage{MnemonicLanguageEnglish, MnemonicLanguageJapanese}
+
+// MnemonicStrength is the entropy of a mnemonic in bits.
+type MnemonicStrength int
+
+const (
+	// MnemonicStrength128 is the strength of a 12 words mnemonic.
+	MnemonicStrength128 MnemonicStrength = 128
+	// MnemonicStrength160 is the strength of a 15 words mnemonic.
+	MnemonicStrength160 MnemonicStrength = 160
+	// MnemonicStrength192 is the strength of a 18 words mnemonic.
+	MnemonicStrength192 MnemonicStrength = 192
+	// MnemonicStrength224 is the strength of a 21 words mnemonic.
+	MnemonicStrength224 MnemonicStrength = 224
+	// MnemonicStrength256 is the strength of a 24 words mnemonic.
+	MnemonicStrength256 MnemonicStrength = 256
+)
+
+// WordCount returns the number of words of a mnemonic with the given strength.
+func (s MnemonicStrength) WordCount() int {
+	// every word encodes 11 bits, one checksum bit is added per 32 bits of entropy
+	return int(s) * 33 / 32 / 11
+}
+
+var (
+	// ErrInvalidMnemonic gets returned when a mnemonic has an unsupported word count, unknown words or an invalid checksum.
+	ErrInvalidMnemonic = ierrors.New("invalid mnemonic")
+	// ErrUnsupportedMnemonicLanguage gets returned when a mnemonic language is not supported.
+	ErrUnsupportedMnemonicLanguage = ierrors.New("unsupported mnemonic language")
+	// ErrUnsupportedMnemonicStrength gets returned when a mnemonic strength is not supported.
+	ErrUnsupportedMnemonicStrength = ierrors.New("unsupported mnemonic strength")
+)
+
+// mnemonicWordList is the BIP39 word list of a language.
+type mnemonicWordList struct {
+	// words are the words of the word list by their index.
+	words []string
+	// indices are the indices of the normalized words of the word list.
+	indices map[string]int
+}
+
+// the word lists of the supported languages.
+// The bip39 package doesn't expose its word lists and only converts mnemonics with a global word list,
+// so the word lists are recovered once when the package gets initialized and the global word list isn't switched afterwards.
+var mnemonicWordLists = recoverMnemonicWordLists()
+
+// recoverMnemonicWordLists recovers the word lists of the supported languages by encoding every index as the first word of a mnemonic.
+func recoverMnemonicWordLists() map[MnemonicLanguage]*mnemonicWordList {
+	//nolint:errcheck // the english word list is always registered
+	defer bip39.SetWordList(string(MnemonicLanguageEnglish))
+
+	wordLists := make(map[MnemonicLanguage]*mnemonicWordList, len(MnemonicLanguages))
+	for _, language := range MnemonicLanguages {
+		if err := bip39.SetWordList(string(language)); err != nil {
+			panic(ierrors.Wrapf(err, "failed to set the %s word list", language))
+		}
+
+		wordList := &mnemonicWordList{
+			words:   make([]string, wordlist.Count),
+			indices: make(map[string]int, wordlist.Count),
+		}
+
+		entropy := make([]byte, MnemonicStrength128/8)
+		for index := range wordlist.Count {
+			// the first word encodes the 11 most significant bits of the entropy
+			entropy[0], entropy[1] = byte(index>>3), byte(index<<5)
+
+			mnemonic, err := bip39.EntropyToMnemonic(entropy)
+			if err != nil {
+				panic(ierrors.Wrapf(err, "failed to recover the %s word list", language))
+			}
+			wordList.words[index] = mnemonic[0]
+			wordList.indices[bip39.ParseMnemonic(mnemonic[0])[0]] = index
+		}
+		wordLists[language] = wordList
+	}
+
+	return wordLists
+}
+
+// mnemonicWordListForLanguage returns the word list of the given language.
+func mnemonicWordListForLanguage(language MnemonicLanguage) (*mnemonicWordList, error) {
+	wordList, exists := mnemonicWordLists[language]
+	if !exists {
+		return nil, ierrors.WithMessagef(ErrUnsupportedMnemonicLanguage, "language %s", language)
+	}
+
+	return wordList, nil
+}
+
+// mnemonicChecksum returns the checksum of the entropy, which are the first bits of its sha256 hash, one bit per 32 bits of entropy.
+func mnemonicChecksum(entropy []byte) (*big.Int, uint) {
+	checksumBits := uint(len(entropy) * 8 / 32)
+	hash := sha256.Sum256(entropy)
+
+	return new(big.Int).Rsh(new(big.Int).SetBytes(hash[:]), sha256.Size*8-checksumBits), checksumBits
+}
+
+// entropyToMnemonic encodes the entropy and its checksum with the word list of the given language.
+// The entropy is encoded here, so the global word list of the bip39 package doesn't need to be switched.
+func entropyToMnemonic(language MnemonicLanguage, entropy []byte) (bip39.Mnemonic, error) {
+	wordList, err := mnemonicWordListForLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+
+	if entropyBits := len(entropy) * 8; entropyBits%32 != 0 || entropyBits < 128 || entropyBits > 512 {
+		return nil, ierrors.WithMessagef(bip39.ErrInvalidEntropySize, "unsupported bit size %d", entropyBits)
+	}
+
+	// every word encodes 11 bits, the last bits are the checksum
+	checksum, checksumBits := mnemonicChecksum(entropy)
+	encoder := new(big.Int).SetBytes(entropy)
+	encoder.Lsh(encoder, checksumBits)
+	encoder.Or(encoder, checksum)
+
+	mnemonic := make(bip39.Mnemonic, (len(entropy)*8+int(checksumBits))/wordlist.IndexBits)
+	wordIndex := new(big.Int)
+	wordIndexMask := big.NewInt(wordlist.Count - 1)
+	for i := len(mnemonic) - 1; i >= 0; i-- {
+		mnemonic[i] = wordList.words[wordIndex.And(encoder, wordIndexMask).Int64()]
+		encoder.Rsh(encoder, wordlist.IndexBits)
+	}
+
+	return mnemonic, nil
+}
+
+// mnemonicToEntropy decodes the entropy of the mnemonic with the word list of the given language and checks its checksum.
+// The entropy is decoded here, since bip39.MnemonicToEntropy rejects mnemonics whose entropy starts with a zero byte.
+func mnemonicToEntropy(language MnemonicLanguage, mnemonic bip39.Mnemonic) ([]byte, error) {
+	wordList, err := mnemonicWordListForLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+
+	// every word encodes 11 bits, the last bits are the checksum
+	decoder := new(big.Int)
+	for _, word := range mnemonic {
+		index, exists := wordList.indices[word]
+		if !exists {
+			return nil, ierrors.WithMessagef(bip39.ErrInvalidMnemonic, "invalid word %s", word)
+		}
+		decoder.Lsh(decoder, wordlist.IndexBits)
+		decoder.Or(decoder, big.NewInt(int64(index)))
+	}
+
+	entropyBits := len(mnemonic) * wordlist.IndexBits * 32 / 33
+	entropyChecksumBits := uint(entropyBits / 32)
+	entropyChecksum := new(big.Int).And(decoder, big.NewInt(1<<entropyChecksumBits-1))
+	entropy := decoder.Rsh(decoder, entropyChecksumBits).FillBytes(make([]byte, entropyBits/8))
+
+	if checksum, _ := mnemonicChecksum(entropy); checksum.Cmp(entropyChecksum) != 0 {
+		zeroBytes(entropy)
+
+		return nil, bip39.ErrInvalidChecksum
+	}
+
+	return entropy, nil
+}
+
+// MnemonicOptions are the options used to derive a seed from a mnemonic.
+type MnemonicOptions struct {
+	passphrase string
+	language   MnemonicLanguage
+}
+
+// WithMnemonicPassphrase sets the optional BIP39 passphrase used to derive the seed.
+func WithMnemonicPassphrase(passphrase string) options.Option[MnemonicOptions] {
+	return func(opts *MnemonicOptions) {
+		opts.passphrase = passphrase
+	}
+}
+
+// WithMnemonicLanguage sets the language of the mnemonic.
+// If no language is set, it is detected from the words of the mnemonic.
+func WithMnemonicLanguage(language MnemonicLanguage) options.Option[MnemonicOptions] {
+	return func(opts *MnemonicOptions) {
+		opts.language = language
+	}
+}
+
+// GenerateMnemonic generates a new random mnemonic of the given strength in the given language.
+func GenerateMnemonic(strength MnemonicStrength, language MnemonicLanguage) (bip39.Mnemonic, error) {
+	switch strength {
+	case MnemonicStrength128, MnemonicStrength160, MnemonicStrength192, MnemonicStrength224, MnemonicStrength256:
+	default:
+		return nil, ierrors.WithMessagef(ErrUnsupportedMnemonicStrength, "strength %d", strength)
+	}
+
+	entropy := make([]byte, strength/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, ierrors.Wrap(err, "failed to generate random entropy")
+	}
+	defer zeroBytes(entropy)
+
+	mnemonic, err := entropyToMnemonic(language, entropy)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to convert entropy to mnemonic")
+	}
+
+	return mnemonic, nil
+}
+
+// ValidateMnemonic parses the given mnemonic and checks its word count, its words and its checksum.
+// If no language is given, the language is detected from the words of the mnemonic.
+func ValidateMnemonic(mnemonic string, language ...MnemonicLanguage) (bip39.Mnemonic, MnemonicLanguage, error) {
+	mnemonicSentence := bip39.ParseMnemonic(mnemonic)
+
+	wordCountValid := false
+	for _, strength := range []MnemonicStrength{MnemonicStrength128, MnemonicStrength160, MnemonicStrength192, MnemonicStrength224, MnemonicStrength256} {
+		if len(mnemonicSentence) == strength.WordCount() {
+			wordCountValid = true
+			break
+		}
+	}
+	if !wordCountValid {
+		return nil, "", ierrors.WithMessagef(ErrInvalidMnemonic, "mnemonic must have 12, 15, 18, 21 or 24 words, got %d", len(mnemonicSentence))
+	}
+
+	languages := MnemonicLanguages
+	if len(language) > 0 {
+		languages = language
+	}
+
+	for _, lang := range languages {
+		entropy, err := mnemonicToEntropy(lang, mnemonicSentence)
+		zeroBytes(entropy)
+
+		switch {
+		case err == nil:
+			return mnemonicSentence, lang, nil
+		case ierrors.Is(err, ErrUnsupportedMnemonicLanguage):
+			return nil, "", err
+		case ierrors.Is(err, bip39.ErrInvalidChecksum):
+			// all words are part of the word list of this language
+			return nil, "", ierrors.WithMessagef(ErrInvalidMnemonic, "invalid checksum for %s mnemonic", lang)
+		}
+	}
+
+	return nil, "", ierrors.WithMessage(ErrInvalidMnemonic, "mnemonic contains words that are not part of the word list")
+}
+
+// MnemonicToSeed validates the given mnemonic and derives the BIP39 seed from it.
+func MnemonicToSeed(mnemonic string, opts ...options.Option[MnemonicOptions]) ([]byte, error) {
+	mnemonicOpts := options.Apply(&MnemonicOptions{}, opts)
+
+	var languages []MnemonicLanguage
+	if mnemonicOpts.language != "" {
+		languages = append(languages, mnemonicOpts.language)
+	}
+
+	mnemonicSentence, _, err := ValidateMnemonic(mnemonic, languages...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the seed is derived as in bip39.MnemonicToSeed, which checks the checksum like bip39.MnemonicToEntropy
+	return pbkdf2.Key([]byte(mnemonicSentence.String()), []byte("mnemonic"+norm.NFKD.String(mnemonicOpts.passphrase)), 2048, bip39.SeedSize, sha512.New), nil
+}
@@ -0,0 +1,188 @@
+package wallet_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestMnemonicToSeed(t *testing.T) {
+	// test vectors of the BIP39 specification, all of them use the passphrase "TREZOR"
+	tests := []struct {
+		name     string
+		mnemonic string
+		seed     string
+	}{
+		{
+			name:     "12 words",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "0xc55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			name:     "12 words with last word checksum",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			seed:     "0xac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			name:     "24 words",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+			seed:     "0xdd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seed, err := wallet.MnemonicToSeed(test.mnemonic, wallet.WithMnemonicPassphrase("TREZOR"))
+			require.NoError(t, err)
+			require.Equal(t, test.seed, hexutil.EncodeHex(seed))
+
+			// a different passphrase results in a different seed
+			seedWithoutPassphrase, err := wallet.MnemonicToSeed(test.mnemonic)
+			require.NoError(t, err)
+			require.NotEqual(t, seed, seedWithoutPassphrase)
+		})
+	}
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	tests := []struct {
+		name     string
+		mnemonic string
+		language []wallet.MnemonicLanguage
+		err      error
+	}{
+		{
+			name:     "ok",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		},
+		{
+			name:     "ok - with language",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			language: []wallet.MnemonicLanguage{wallet.MnemonicLanguageEnglish},
+		},
+		{
+			// the entropy is 0x00f15b7cef718fa8c5ce788ce2799368bab7e8a82a2d43ad
+			name:     "ok - entropy starting with a zero byte",
+			mnemonic: "abstract member tennis ten body stamp blame someone mind because gospel sphere problem violin pass east dry hub",
+		},
+		{
+			name:     "err - invalid checksum",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+			err:      wallet.ErrInvalidMnemonic,
+		},
+		{
+			name:     "err - unknown word",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon iota",
+			err:      wallet.ErrInvalidMnemonic,
+		},
+		{
+			name:     "err - unsupported word count",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon about",
+			err:      wallet.ErrInvalidMnemonic,
+		},
+		{
+			name:     "err - wrong language",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			language: []wallet.MnemonicLanguage{wallet.MnemonicLanguageJapanese},
+			err:      wallet.ErrInvalidMnemonic,
+		},
+		{
+			name:     "err - unsupported language",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			language: []wallet.MnemonicLanguage{"klingon"},
+			err:      wallet.ErrUnsupportedMnemonicLanguage,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := wallet.ValidateMnemonic(test.mnemonic, test.language...)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+
+				return
+			}
+			require.NoError(t, err)
+
+			_, err = wallet.MnemonicToSeed(test.mnemonic)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestGenerateMnemonic(t *testing.T) {
+	for _, language := range wallet.MnemonicLanguages {
+		for _, strength := range []wallet.MnemonicStrength{wallet.MnemonicStrength128, wallet.MnemonicStrength160, wallet.MnemonicStrength192, wallet.MnemonicStrength224, wallet.MnemonicStrength256} {
+			mnemonic, err := wallet.GenerateMnemonic(strength, language)
+			require.NoError(t, err)
+			require.Len(t, mnemonic, strength.WordCount())
+
+			_, detectedLanguage, err := wallet.ValidateMnemonic(mnemonic.String())
+			require.NoError(t, err)
+			require.Equal(t, language, detectedLanguage)
+		}
+	}
+
+	_, err := wallet.GenerateMnemonic(100, wallet.MnemonicLanguageEnglish)
+	require.ErrorIs(t, err, wallet.ErrUnsupportedMnemonicStrength)
+}
+
+func TestKeyManagerMnemonic(t *testing.T) {
+	tests := []struct {
+		name     string
+		entropy  string
+		mnemonic string
+	}{
+		{
+			name:     "12 words",
+			entropy:  "0x00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		},
+		{
+			name:     "18 words starting with a zero byte",
+			entropy:  "0x00f15b7cef718fa8c5ce788ce2799368bab7e8a82a2d43ad",
+			mnemonic: "abstract member tennis ten body stamp blame someone mind because gospel sphere problem violin pass east dry hub",
+		},
+		{
+			name:     "24 words",
+			entropy:  "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entropy, err := hexutil.DecodeHex(test.entropy)
+			require.NoError(t, err)
+
+			keyManager, err := wallet.NewKeyManager(entropy, wallet.DefaultIOTAPath)
+			require.NoError(t, err)
+			require.Equal(t, test.mnemonic, keyManager.Mnemonic().String())
+		})
+	}
+}
+
+func TestNewKeyManagerFromMnemonic(t *testing.T) {
+	mnemonic, err := wallet.GenerateMnemonic(wallet.MnemonicStrength128, wallet.MnemonicLanguageEnglish)
+	require.NoError(t, err)
+
+	keyManager, err := wallet.NewKeyManagerFromMnemonic(mnemonic.String(), wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	keyManagerWithPassphrase, err := wallet.NewKeyManagerFromMnemonic(mnemonic.String(), wallet.DefaultIOTAPath, wallet.WithMnemonicPassphrase("passphrase"))
+	require.NoError(t, err)
+	require.NotEqual(t, keyManager.Address(iotago.AddressEd25519), keyManagerWithPassphrase.Address(iotago.AddressEd25519))
+
+	// whitespace is ignored
+	sameKeyManager, err := wallet.NewKeyManagerFromMnemonic("  "+strings.Join(mnemonic, "\n")+" ", wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+	require.Equal(t, keyManager.Address(iotago.AddressEd25519), sameKeyManager.Address(iotago.AddressEd25519))
+
+	_, err = wallet.NewKeyManagerFromMnemonic(mnemonic[:11].String(), wallet.DefaultIOTAPath)
+	require.ErrorIs(t, err, wallet.ErrInvalidMnemonic)
+}
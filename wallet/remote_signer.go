@@ -0,0 +1,277 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+)
+
+// The remote signer protocol is a small JSON over HTTP protocol, which can be served via TCP or a Unix socket.
+// All requests are POST requests with a RemoteSignerRequest body:
+//
+//	POST /v1/sign             {"address": "0x...", "message": "0x..."} -> RemoteSignerSignatureResponse
+//	POST /v1/signer-uid       {"address": "0x..."}                     -> RemoteSignerUIDResponse
+//	POST /v1/empty-signature  {"address": "0x..."}                     -> RemoteSignerSignatureResponse
+//
+// Addresses are hex encoded in their serialized form (see iotago.Address.ID).
+// Errors are returned as RemoteSignerErrorResponse, with status 404 if no keys are mapped for the address
+// and status 400 if the request is malformed or the address type is not supported.
+const (
+	// RemoteSignerRouteSign is the route to sign a message for an address.
+	RemoteSignerRouteSign = "/v1/sign"
+	// RemoteSignerRouteSignerUID is the route to get the signer unique identifier of an address.
+	RemoteSignerRouteSignerUID = "/v1/signer-uid"
+	// RemoteSignerRouteEmptySignature is the route to get an empty signature for an address.
+	RemoteSignerRouteEmptySignature = "/v1/empty-signature"
+
+	// DefaultRemoteSignerTimeout is the default timeout of requests to a remote signer.
+	DefaultRemoteSignerTimeout = 10 * time.Second
+
+	// remoteSignerUnixSocketBaseURL is the base URL used for requests over a Unix socket, the host is ignored.
+	remoteSignerUnixSocketBaseURL = "http://unix"
+	// remoteSignerMaxBodySize is the maximum size of request and response bodies of the remote signer protocol.
+	remoteSignerMaxBodySize = 1 << 20
+)
+
+var (
+	// ErrRemoteSignerRequestFailed gets returned when a request to a remote signer failed.
+	ErrRemoteSignerRequestFailed = ierrors.New("remote signer request failed")
+	// ErrRemoteSignerInvalidSignature gets returned when a remote signer returned an invalid signature.
+	ErrRemoteSignerInvalidSignature = ierrors.New("remote signer returned an invalid signature")
+	// ErrRemoteSignerSocketPathInUse gets returned when the path of the Unix socket of a remote signer server is already in use.
+	ErrRemoteSignerSocketPathInUse = ierrors.New("remote signer socket path is already in use")
+)
+
+// RemoteSignerRequest is the request body of the remote signer protocol.
+type RemoteSignerRequest struct {
+	// The hex encoded serialized address.
+	Address string `json:"address"`
+	// The hex encoded message to sign, only used for sign requests.
+	Message string `json:"message,omitempty"`
+}
+
+// RemoteSignerSignature is an Ed25519 signature in the remote signer protocol.
+type RemoteSignerSignature struct {
+	// The type of the signature, only iotago.SignatureEd25519 is supported.
+	Type iotago.SignatureType `json:"type"`
+	// The hex encoded public key.
+	PublicKey string `json:"publicKey"`
+	// The hex encoded signature.
+	Signature string `json:"signature"`
+}
+
+// RemoteSignerSignatureResponse is the response body of sign and empty signature requests.
+type RemoteSignerSignatureResponse struct {
+	Signature *RemoteSignerSignature `json:"signature"`
+}
+
+// RemoteSignerUIDResponse is the response body of signer UID requests.
+type RemoteSignerUIDResponse struct {
+	// The hex encoded signer unique identifier.
+	SignerUID string `json:"signerUid"`
+}
+
+// RemoteSignerErrorResponse is the response body of failed requests.
+type RemoteSignerErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// RemoteSigner implements iotago.AddressSigner by requesting the signatures from a remote signer,
+// so that the private keys never need to be held in the memory of the process.
+type RemoteSigner struct {
+	baseURL    string
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+var _ iotago.AddressSigner = &RemoteSigner{}
+
+// NewRemoteSigner creates a new RemoteSigner that sends its requests to the given HTTP base URL.
+func NewRemoteSigner(baseURL string, opts ...options.Option[RemoteSigner]) *RemoteSigner {
+	return options.Apply(&RemoteSigner{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		timeout:    DefaultRemoteSignerTimeout,
+	}, opts)
+}
+
+// NewUnixSocketRemoteSigner creates a new RemoteSigner that sends its requests to the Unix socket at the given path.
+func NewUnixSocketRemoteSigner(socketPath string, opts ...options.Option[RemoteSigner]) *RemoteSigner {
+	return options.Apply(&RemoteSigner{
+		baseURL: remoteSignerUnixSocketBaseURL,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+		timeout: DefaultRemoteSignerTimeout,
+	}, opts)
+}
+
+// WithRemoteSignerHTTPClient sets the HTTP client used to send the requests.
+func WithRemoteSignerHTTPClient(httpClient *http.Client) options.Option[RemoteSigner] {
+	return func(signer *RemoteSigner) {
+		signer.httpClient = httpClient
+	}
+}
+
+// WithRemoteSignerTimeout sets the timeout of every request to the remote signer.
+func WithRemoteSignerTimeout(timeout time.Duration) options.Option[RemoteSigner] {
+	return func(signer *RemoteSigner) {
+		signer.timeout = timeout
+	}
+}
+
+// SignerUIDForAddress returns the signer unique identifier for a given address.
+func (s *RemoteSigner) SignerUIDForAddress(addr iotago.Address) (iotago.Identifier, error) {
+	res := new(RemoteSignerUIDResponse)
+	if err := s.do(RemoteSignerRouteSignerUID, &RemoteSignerRequest{Address: hexutil.EncodeHex(addr.ID())}, res); err != nil {
+		return iotago.EmptyIdentifier, ierrors.Wrapf(err, "can't get signer UID for address %s", addr)
+	}
+
+	signerUID, err := iotago.IdentifierFromHexString(res.SignerUID)
+	if err != nil {
+		return iotago.EmptyIdentifier, ierrors.Join(ErrRemoteSignerRequestFailed, ierrors.Wrap(err, "invalid signer UID"))
+	}
+
+	return signerUID, nil
+}
+
+// Sign produces the signature for the given message by sending it to the remote signer.
+// The returned signature is verified against the message and the address before it is returned.
+func (s *RemoteSigner) Sign(addr iotago.Address, msg []byte) (iotago.Signature, error) {
+	res := new(RemoteSignerSignatureResponse)
+	if err := s.do(RemoteSignerRouteSign, &RemoteSignerRequest{Address: hexutil.EncodeHex(addr.ID()), Message: hexutil.EncodeHex(msg)}, res); err != nil {
+		return nil, ierrors.Wrapf(err, "can't sign message for address %s", addr)
+	}
+
+	signature, err := res.Signature.ed25519Signature()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ed25519.Verify(signature.PublicKey[:], msg, signature.Signature[:]) {
+		return nil, ierrors.WithMessagef(ErrRemoteSignerInvalidSignature, "signature for address %s does not match the message", addr)
+	}
+
+	// a valid signature of another key would only be rejected by the node
+	if !signature.MatchesAddress(builder.ResolveUnderlyingAddress(addr)) {
+		return nil, ierrors.WithMessagef(ErrRemoteSignerInvalidSignature, "public key of the signature does not match address %s", addr)
+	}
+
+	return signature, nil
+}
+
+// EmptySignatureForAddress returns an empty signature for the given address.
+func (s *RemoteSigner) EmptySignatureForAddress(addr iotago.Address) (iotago.Signature, error) {
+	res := new(RemoteSignerSignatureResponse)
+	if err := s.do(RemoteSignerRouteEmptySignature, &RemoteSignerRequest{Address: hexutil.EncodeHex(addr.ID())}, res); err != nil {
+		return nil, ierrors.Wrapf(err, "can't get empty signature for address %s", addr)
+	}
+
+	return res.Signature.ed25519Signature()
+}
+
+// do sends the request to the given route of the remote signer and decodes the response into res.
+func (s *RemoteSigner) do(route string, req *RemoteSignerRequest, res interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to encode request")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+route, bytes.NewReader(reqBody))
+	if err != nil {
+		return ierrors.Wrap(err, "failed to create request")
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRes, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return ierrors.Join(ErrRemoteSignerRequestFailed, err)
+	}
+	defer httpRes.Body.Close()
+
+	resBody, err := io.ReadAll(io.LimitReader(httpRes.Body, remoteSignerMaxBodySize))
+	if err != nil {
+		return ierrors.Join(ErrRemoteSignerRequestFailed, ierrors.Wrap(err, "failed to read response"))
+	}
+
+	if httpRes.StatusCode != http.StatusOK {
+		errRes := new(RemoteSignerErrorResponse)
+		if err := json.Unmarshal(resBody, errRes); err != nil {
+			errRes.Error = string(resBody)
+		}
+
+		if httpRes.StatusCode == http.StatusNotFound {
+			return ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "remote signer: %s", errRes.Error)
+		}
+
+		return ierrors.WithMessagef(ErrRemoteSignerRequestFailed, "status %d: %s", httpRes.StatusCode, errRes.Error)
+	}
+
+	if err := json.Unmarshal(resBody, res); err != nil {
+		return ierrors.Join(ErrRemoteSignerRequestFailed, ierrors.Wrap(err, "failed to decode response"))
+	}
+
+	return nil
+}
+
+// newRemoteSignerSignature converts the given signature to its representation in the remote signer protocol.
+func newRemoteSignerSignature(signature iotago.Signature) (*RemoteSignerSignature, error) {
+	ed25519Signature, ok := signature.(*iotago.Ed25519Signature)
+	if !ok {
+		return nil, ierrors.Errorf("unsupported signature type %T", signature)
+	}
+
+	return &RemoteSignerSignature{
+		Type:      iotago.SignatureEd25519,
+		PublicKey: hexutil.EncodeHex(ed25519Signature.PublicKey[:]),
+		Signature: hexutil.EncodeHex(ed25519Signature.Signature[:]),
+	}, nil
+}
+
+// ed25519Signature converts the signature of the remote signer protocol to an Ed25519Signature.
+func (s *RemoteSignerSignature) ed25519Signature() (*iotago.Ed25519Signature, error) {
+	if s == nil {
+		return nil, ierrors.WithMessage(ErrRemoteSignerInvalidSignature, "signature is missing")
+	}
+
+	if s.Type != iotago.SignatureEd25519 {
+		return nil, ierrors.WithMessagef(ErrRemoteSignerInvalidSignature, "unsupported signature type %d", s.Type)
+	}
+
+	publicKey, err := hexutil.DecodeHex(s.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, ierrors.WithMessage(ErrRemoteSignerInvalidSignature, "invalid public key")
+	}
+
+	signatureBytes, err := hexutil.DecodeHex(s.Signature)
+	if err != nil || len(signatureBytes) != ed25519.SignatureSize {
+		return nil, ierrors.WithMessage(ErrRemoteSignerInvalidSignature, "invalid signature")
+	}
+
+	signature := &iotago.Ed25519Signature{}
+	copy(signature.PublicKey[:], publicKey)
+	copy(signature.Signature[:], signatureBytes)
+
+	return signature, nil
+}
@@ -0,0 +1,274 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+)
+
+// remoteSignerReadHeaderTimeout is the timeout to read the request headers of the remote signer server.
+const remoteSignerReadHeaderTimeout = 5 * time.Second
+
+// errRemoteSignerBadRequest is used to answer requests with status 400.
+var errRemoteSignerBadRequest = ierrors.New("bad request")
+
+// RemoteSignerServer is a reference implementation of the remote signer protocol, see RemoteSigner.
+// It serves the signatures of an iotago.AddressSigner, e.g. the keys of a KeyManager, and can be run
+// in a separate process, so that the private keys are not held in the memory of the process using the RemoteSigner.
+type RemoteSignerServer struct {
+	signer iotago.AddressSigner
+	mux    *http.ServeMux
+}
+
+var _ http.Handler = &RemoteSignerServer{}
+
+// NewRemoteSignerServer creates a new RemoteSignerServer that serves the signatures of the given signer.
+func NewRemoteSignerServer(signer iotago.AddressSigner) *RemoteSignerServer {
+	server := &RemoteSignerServer{
+		signer: signer,
+		mux:    http.NewServeMux(),
+	}
+
+	server.mux.HandleFunc(RemoteSignerRouteSign, server.handle(server.sign))
+	server.mux.HandleFunc(RemoteSignerRouteSignerUID, server.handle(server.signerUID))
+	server.mux.HandleFunc(RemoteSignerRouteEmptySignature, server.handle(server.emptySignature))
+
+	return server
+}
+
+// NewRemoteSignerServerFromKeyManager creates a new RemoteSignerServer that serves the signatures
+// of the Ed25519 and ImplicitAccountCreation addresses of the given BIP44 paths of the key manager.
+func NewRemoteSignerServerFromKeyManager(keyManager *KeyManager, paths ...BIP44Path) (*RemoteSignerServer, error) {
+	signer, err := keyManager.AddressSignerForBIP44Paths(paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRemoteSignerServer(signer), nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *RemoteSignerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve serves the remote signer protocol on the given listener until the context is canceled.
+//
+// **The remote signer protocol has no authentication**, everyone who can connect to the listener can sign messages
+// with the keys of the server. Only serve it on listeners that are not reachable by untrusted parties, e.g. via ServeUnixSocket.
+func (s *RemoteSignerServer) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: remoteSignerReadHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		//nolint:errcheck // we are not interested in the error of closing the server
+		httpServer.Close()
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !ierrors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// ServeUnixSocket serves the remote signer protocol on a Unix socket at the given path until the context is canceled.
+// The socket is only accessible by the user running the server.
+// A stale socket at the given path that no server is listening on anymore is replaced,
+// ErrRemoteSignerSocketPathInUse is returned if the path is taken by any other file.
+func (s *RemoteSignerServer) ServeUnixSocket(ctx context.Context, socketPath string) error {
+	if err := removeStaleUnixSocket(socketPath); err != nil {
+		return err
+	}
+
+	// the socket is created in a private directory and only moved to its path once its permissions are restricted,
+	// so other users can't connect to it in the meantime
+	privateDir, err := os.MkdirTemp(filepath.Dir(socketPath), ".signer")
+	if err != nil {
+		return ierrors.Wrapf(err, "failed to create private directory for unix socket %s", socketPath)
+	}
+	defer os.RemoveAll(privateDir)
+
+	privateSocketPath := filepath.Join(privateDir, "s")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: privateSocketPath, Net: "unix"})
+	if err != nil {
+		return ierrors.Wrapf(err, "failed to listen on unix socket %s", socketPath)
+	}
+	// the socket is removed from its final path instead
+	listener.SetUnlinkOnClose(false)
+
+	if err := os.Chmod(privateSocketPath, 0o600); err != nil {
+		listener.Close()
+
+		return ierrors.Wrapf(err, "failed to set permissions of unix socket %s", socketPath)
+	}
+
+	// the socket is linked instead of renamed, since a rename would replace a file created at the path in the meantime
+	if err := os.Link(privateSocketPath, socketPath); err != nil {
+		listener.Close()
+
+		if os.IsExist(err) {
+			return ierrors.WithMessagef(ErrRemoteSignerSocketPathInUse, "path %s", socketPath)
+		}
+
+		return ierrors.Wrapf(err, "failed to move unix socket to %s", socketPath)
+	}
+	defer os.Remove(socketPath)
+
+	return s.Serve(ctx, listener)
+}
+
+// removeStaleUnixSocket removes the Unix socket at the given path if no server is listening on it anymore.
+func removeStaleUnixSocket(socketPath string) error {
+	fileInfo, err := os.Lstat(socketPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return ierrors.Wrapf(err, "failed to check unix socket path %s", socketPath)
+	}
+
+	if fileInfo.Mode().Type() != os.ModeSocket {
+		return ierrors.WithMessagef(ErrRemoteSignerSocketPathInUse, "path %s is not a unix socket", socketPath)
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err == nil {
+		conn.Close()
+
+		return ierrors.WithMessagef(ErrRemoteSignerSocketPathInUse, "another server is listening on unix socket %s", socketPath)
+	}
+
+	if !ierrors.Is(err, syscall.ECONNREFUSED) {
+		return ierrors.Wrapf(err, "failed to check whether unix socket %s is stale", socketPath)
+	}
+
+	if err := os.Remove(socketPath); err != nil {
+		return ierrors.Wrapf(err, "failed to remove stale unix socket %s", socketPath)
+	}
+
+	return nil
+}
+
+// handle decodes the request, calls the given handler and encodes its response or error.
+func (s *RemoteSignerServer) handle(handler func(address iotago.Address, req *RemoteSignerRequest) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeRemoteSignerResponse(w, http.StatusMethodNotAllowed, &RemoteSignerErrorResponse{Error: "method not allowed"})
+
+			return
+		}
+
+		res, err := func() (interface{}, error) {
+			req := new(RemoteSignerRequest)
+			if err := json.NewDecoder(io.LimitReader(r.Body, remoteSignerMaxBodySize)).Decode(req); err != nil {
+				return nil, ierrors.WithMessagef(errRemoteSignerBadRequest, "invalid request: %s", err)
+			}
+
+			addressBytes, err := hexutil.DecodeHex(req.Address)
+			if err != nil {
+				return nil, ierrors.WithMessagef(errRemoteSignerBadRequest, "invalid address: %s", err)
+			}
+
+			address, n, err := iotago.AddressFromBytes(addressBytes)
+			if err != nil || n != len(addressBytes) {
+				return nil, ierrors.WithMessage(errRemoteSignerBadRequest, "invalid address")
+			}
+
+			// signers only hold the keys of Ed25519 based addresses
+			switch builder.ResolveUnderlyingAddress(address).(type) {
+			case *iotago.Ed25519Address, *iotago.ImplicitAccountCreationAddress:
+			default:
+				return nil, ierrors.WithMessagef(errRemoteSignerBadRequest, "unsupported address %s", address)
+			}
+
+			return handler(address, req)
+		}()
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case ierrors.Is(err, iotago.ErrAddressKeysNotMapped):
+				status = http.StatusNotFound
+			case ierrors.Is(err, errRemoteSignerBadRequest), ierrors.Is(err, iotago.ErrAddressKeysWrongType):
+				status = http.StatusBadRequest
+			}
+
+			writeRemoteSignerResponse(w, status, &RemoteSignerErrorResponse{Error: err.Error()})
+
+			return
+		}
+
+		writeRemoteSignerResponse(w, http.StatusOK, res)
+	}
+}
+
+func (s *RemoteSignerServer) sign(address iotago.Address, req *RemoteSignerRequest) (interface{}, error) {
+	msg, err := hexutil.DecodeHex(req.Message)
+	if err != nil {
+		return nil, ierrors.WithMessagef(errRemoteSignerBadRequest, "invalid message: %s", err)
+	}
+
+	signature, err := s.signer.Sign(address, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteSignature, err := newRemoteSignerSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteSignerSignatureResponse{Signature: remoteSignature}, nil
+}
+
+func (s *RemoteSignerServer) signerUID(address iotago.Address, _ *RemoteSignerRequest) (interface{}, error) {
+	signerUID, err := s.signer.SignerUIDForAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteSignerUIDResponse{SignerUID: signerUID.ToHex()}, nil
+}
+
+func (s *RemoteSignerServer) emptySignature(address iotago.Address, _ *RemoteSignerRequest) (interface{}, error) {
+	signature, err := s.signer.EmptySignatureForAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteSignature, err := newRemoteSignerSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteSignerSignatureResponse{Signature: remoteSignature}, nil
+}
+
+func writeRemoteSignerResponse(w http.ResponseWriter, status int, res interface{}) {
+	resBody, err := json.Marshal(res)
+	if err != nil {
+		status = http.StatusInternalServerError
+		resBody = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	//nolint:errcheck // the client might have disconnected already
+	w.Write(resBody)
+}
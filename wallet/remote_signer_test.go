@@ -0,0 +1,202 @@
+package wallet_test
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	path := wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA}
+	server, err := wallet.NewRemoteSignerServerFromKeyManager(keyManager, path)
+	require.NoError(t, err)
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	remoteSigner := wallet.NewRemoteSigner(httpServer.URL)
+	localSigner, err := keyManager.AddressSignerForBIP44Paths(path)
+	require.NoError(t, err)
+
+	//nolint:forcetypeassert // we know that the address is an Ed25519 address
+	ed25519Address := keyManager.Address(iotago.AddressEd25519, 0).(*iotago.Ed25519Address)
+	msg := tpkg.RandBytes(32)
+
+	tests := []struct {
+		name    string
+		address iotago.Address
+		err     error
+	}{
+		{
+			name:    "ok - Ed25519 address",
+			address: ed25519Address,
+		},
+		{
+			name:    "ok - ImplicitAccountCreation address",
+			address: keyManager.Address(iotago.AddressImplicitAccountCreation, 0),
+		},
+		{
+			name:    "ok - restricted Ed25519 address",
+			address: &iotago.RestrictedAddress{Address: ed25519Address, AllowedCapabilities: iotago.AddressCapabilitiesBitMask{}},
+		},
+		{
+			name:    "err - keys not mapped",
+			address: keyManager.Address(iotago.AddressEd25519, 1),
+			err:     iotago.ErrAddressKeysNotMapped,
+		},
+		{
+			name:    "err - unsupported address",
+			address: tpkg.RandAccountAddress(),
+			err:     wallet.ErrRemoteSignerRequestFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signature, err := remoteSigner.Sign(test.address, msg)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+
+				_, err = remoteSigner.SignerUIDForAddress(test.address)
+				require.ErrorIs(t, err, test.err)
+
+				return
+			}
+			require.NoError(t, err)
+
+			// Ed25519 signatures are deterministic
+			expectedSignature, err := localSigner.Sign(test.address, msg)
+			require.NoError(t, err)
+			require.Equal(t, expectedSignature, signature)
+
+			signerUID, err := remoteSigner.SignerUIDForAddress(test.address)
+			require.NoError(t, err)
+			require.Equal(t, signature.SignerUID(), signerUID)
+
+			emptySignature, err := remoteSigner.EmptySignatureForAddress(test.address)
+			require.NoError(t, err)
+			require.Equal(t, &iotago.Ed25519Signature{}, emptySignature)
+		})
+	}
+
+	t.Run("ok - transaction builder", func(t *testing.T) {
+		signedTransaction, err := builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, remoteSigner).
+			AddInput(&builder.TxInput{UnlockTarget: ed25519Address, InputID: tpkg.RandOutputID(0), Input: tpkg.RandOutputOnAddressWithAmount(iotago.OutputBasic, ed25519Address, 1_000_000)}).
+			AddOutput(tpkg.RandOutputOnAddressWithAmount(iotago.OutputBasic, tpkg.RandEd25519Address(), 1_000_000)).
+			Build()
+		require.NoError(t, err)
+
+		signingMessage, err := signedTransaction.Transaction.SigningMessage()
+		require.NoError(t, err)
+
+		//nolint:forcetypeassert // we know that the unlock is a signature unlock
+		signature := signedTransaction.Unlocks[0].(*iotago.SignatureUnlock).Signature.(*iotago.Ed25519Signature)
+		require.NoError(t, signature.Valid(signingMessage, ed25519Address))
+	})
+}
+
+// otherKeySigner signs every message with the key of another address.
+type otherKeySigner struct {
+	iotago.AddressSigner
+	address iotago.Address
+}
+
+func (s *otherKeySigner) Sign(_ iotago.Address, msg []byte) (iotago.Signature, error) {
+	return s.AddressSigner.Sign(s.address, msg)
+}
+
+func TestRemoteSignerOtherKey(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	path := wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA}
+	localSigner, err := keyManager.AddressSignerForBIP44Paths(path, wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA, AddressIndex: 1})
+	require.NoError(t, err)
+
+	// the server returns valid signatures, but of the key of another address
+	httpServer := httptest.NewServer(wallet.NewRemoteSignerServer(&otherKeySigner{AddressSigner: localSigner, address: keyManager.Address(iotago.AddressEd25519, 1)}))
+	defer httpServer.Close()
+
+	_, err = wallet.NewRemoteSigner(httpServer.URL).Sign(keyManager.Address(iotago.AddressEd25519, 0), tpkg.RandBytes(32))
+	require.ErrorIs(t, err, wallet.ErrRemoteSignerInvalidSignature)
+}
+
+func TestRemoteSignerUnixSocket(t *testing.T) {
+	// unix socket paths are limited in length, so the default test directory might be too long
+	dir, err := os.MkdirTemp("", "signer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "signer.sock")
+
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	server, err := wallet.NewRemoteSignerServerFromKeyManager(keyManager, wallet.BIP44Path{CoinType: wallet.CoinTypeIOTA})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ServeUnixSocket(ctx, socketPath)
+	}()
+
+	// the socket is only accessible by the user running the server
+	require.Eventually(t, func() bool {
+		socketInfo, err := os.Stat(socketPath)
+
+		return err == nil && socketInfo.Mode().Perm() == 0o600
+	}, 5*time.Second, 10*time.Millisecond)
+
+	address := keyManager.Address(iotago.AddressEd25519, 0)
+	msg := tpkg.RandBytes(32)
+
+	signature, err := wallet.NewUnixSocketRemoteSigner(socketPath).Sign(address, msg)
+	require.NoError(t, err)
+	//nolint:forcetypeassert // we know that the signature is an Ed25519 signature
+	require.NoError(t, signature.(*iotago.Ed25519Signature).Valid(msg, address.(*iotago.Ed25519Address)))
+
+	// the socket of a running server is not replaced
+	require.ErrorIs(t, server.ServeUnixSocket(ctx, socketPath), wallet.ErrRemoteSignerSocketPathInUse)
+
+	cancel()
+	require.NoError(t, <-serveErr)
+
+	// other files are not replaced
+	filePath := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(filePath, nil, 0o600))
+	require.ErrorIs(t, server.ServeUnixSocket(context.Background(), filePath), wallet.ErrRemoteSignerSocketPathInUse)
+
+	// a stale socket is replaced
+	staleListener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	require.NoError(t, err)
+	staleListener.SetUnlinkOnClose(false)
+	require.NoError(t, staleListener.Close())
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		serveErr <- server.ServeUnixSocket(ctx, socketPath)
+	}()
+
+	require.Eventually(t, func() bool {
+		_, err := wallet.NewUnixSocketRemoteSigner(socketPath).Sign(address, msg)
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-serveErr)
+}
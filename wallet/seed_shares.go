@@ -0,0 +1,556 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+)
+
+const (
+	// DefaultSeedShareIterationExponent is the default iteration exponent of the seed share encryption.
+	DefaultSeedShareIterationExponent uint8 = 1
+	// MaxSeedShareCount is the maximum number of groups and of members per group.
+	MaxSeedShareCount = 16
+
+	seedShareRadixBits             = 10
+	seedShareRadix                 = 1 << seedShareRadixBits
+	seedShareIdentifierBits        = 15
+	seedShareIterationExponentBits = 4
+	seedShareMaxIterationExponent  = 1<<seedShareIterationExponentBits - 1
+	seedShareChecksumWords         = 3
+	// the identifier, extendable flag and iteration exponent as well as the group and member parameters take two words each.
+	seedShareMetadataWords   = 2 + 2 + seedShareChecksumWords
+	seedShareMinSecretLength = 16
+	seedShareMinWords        = seedShareMetadataWords + (seedShareMinSecretLength*8+seedShareRadixBits-1)/seedShareRadixBits
+	seedShareDigestLength    = 4
+	seedShareDigestIndex     = 254
+	seedShareSecretIndex     = 255
+	seedShareBaseIterations  = 10000
+	seedShareRoundCount      = 4
+
+	seedShareCustomization           = "shamir"
+	seedShareCustomizationExtendable = "shamir_extendable"
+)
+
+var (
+	// ErrInvalidSeedShare gets returned when a seed share mnemonic is malformed or doesn't match the other shares.
+	ErrInvalidSeedShare = ierrors.New("invalid seed share")
+	// ErrInsufficientSeedShares gets returned when the given seed shares don't satisfy the thresholds.
+	ErrInsufficientSeedShares = ierrors.New("insufficient seed shares")
+	// ErrInvalidSeedShareParameters gets returned when the parameters used to split a seed are invalid.
+	ErrInvalidSeedShareParameters = ierrors.New("invalid seed share parameters")
+)
+
+// seedShareWordIndexes maps the words of the SLIP-39 word list to their index.
+var seedShareWordIndexes = func() map[string]int {
+	indexes := make(map[string]int, len(seedShareWordList))
+	for i, word := range seedShareWordList {
+		indexes[word] = i
+	}
+
+	return indexes
+}()
+
+// SeedShareGroup defines the members of a group of seed shares.
+type SeedShareGroup struct {
+	// The number of member shares needed to recover the group share.
+	MemberThreshold uint8
+	// The number of member shares of the group.
+	MemberCount uint8
+}
+
+// SeedShareOptions are the options used to split and combine seed shares.
+type SeedShareOptions struct {
+	passphrase        string
+	iterationExponent uint8
+}
+
+// WithSeedSharePassphrase sets the passphrase used to encrypt the seed before splitting it.
+// Combining shares with a different passphrase results in a different, but valid seed.
+func WithSeedSharePassphrase(passphrase string) options.Option[SeedShareOptions] {
+	return func(opts *SeedShareOptions) {
+		opts.passphrase = passphrase
+	}
+}
+
+// WithSeedShareIterationExponent sets the exponent of the number of PBKDF2 iterations used to encrypt the seed.
+func WithSeedShareIterationExponent(iterationExponent uint8) options.Option[SeedShareOptions] {
+	return func(opts *SeedShareOptions) {
+		opts.iterationExponent = iterationExponent
+	}
+}
+
+// GenerateSeedShares splits the seed into SLIP-39 share mnemonics, which are returned per group.
+// The seed can be recovered from the member threshold of shares of groupThreshold groups.
+func GenerateSeedShares(seed []byte, groupThreshold uint8, groups []SeedShareGroup, opts ...options.Option[SeedShareOptions]) ([][]string, error) {
+	seedShareOpts := options.Apply(&SeedShareOptions{
+		iterationExponent: DefaultSeedShareIterationExponent,
+	}, opts)
+
+	if len(seed) < seedShareMinSecretLength || len(seed)%2 != 0 {
+		return nil, ierrors.WithMessagef(ErrInvalidSeedShareParameters, "seed length must be even and at least %d bytes, got %d", seedShareMinSecretLength, len(seed))
+	}
+	if seedShareOpts.iterationExponent > seedShareMaxIterationExponent {
+		return nil, ierrors.WithMessagef(ErrInvalidSeedShareParameters, "iteration exponent must not exceed %d", seedShareMaxIterationExponent)
+	}
+	if err := validateSeedSharePassphrase(seedShareOpts.passphrase); err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 || len(groups) > MaxSeedShareCount || groupThreshold == 0 || int(groupThreshold) > len(groups) {
+		return nil, ierrors.WithMessagef(ErrInvalidSeedShareParameters, "group threshold %d must be between 1 and the group count %d, which must not exceed %d", groupThreshold, len(groups), MaxSeedShareCount)
+	}
+	for i, group := range groups {
+		if group.MemberCount == 0 || group.MemberCount > MaxSeedShareCount || group.MemberThreshold == 0 || group.MemberThreshold > group.MemberCount {
+			return nil, ierrors.WithMessagef(ErrInvalidSeedShareParameters, "member threshold %d of group %d must be between 1 and the member count %d, which must not exceed %d", group.MemberThreshold, i, group.MemberCount, MaxSeedShareCount)
+		}
+		if group.MemberThreshold == 1 && group.MemberCount > 1 {
+			return nil, ierrors.WithMessagef(ErrInvalidSeedShareParameters, "group %d must use a single member share for a member threshold of 1", i)
+		}
+	}
+
+	var identifierBytes [2]byte
+	if _, err := rand.Read(identifierBytes[:]); err != nil {
+		return nil, ierrors.Wrap(err, "failed to generate identifier")
+	}
+	identifier := binary.BigEndian.Uint16(identifierBytes[:]) & (1<<seedShareIdentifierBits - 1)
+
+	encryptedSeed := seedShareFeistel(seed, seedShareOpts.passphrase, seedShareOpts.iterationExponent, identifier, true, true)
+
+	groupShares, err := splitSecret(groupThreshold, uint8(len(groups)), encryptedSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	mnemonics := make([][]string, len(groups))
+	for i, groupShare := range groupShares {
+		memberShares, err := splitSecret(groups[i].MemberThreshold, groups[i].MemberCount, groupShare.value)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, memberShare := range memberShares {
+			mnemonics[i] = append(mnemonics[i], (&seedShare{
+				identifier:        identifier,
+				extendable:        true,
+				iterationExponent: seedShareOpts.iterationExponent,
+				groupIndex:        groupShare.x,
+				groupThreshold:    groupThreshold,
+				groupCount:        uint8(len(groups)),
+				memberIndex:       memberShare.x,
+				memberThreshold:   groups[i].MemberThreshold,
+				value:             memberShare.value,
+			}).mnemonic())
+		}
+	}
+
+	return mnemonics, nil
+}
+
+// CombineSeedShares recovers the seed from SLIP-39 share mnemonics.
+// Exactly the group threshold of groups with exactly their member threshold of shares need to be given.
+func CombineSeedShares(mnemonics []string, opts ...options.Option[SeedShareOptions]) ([]byte, error) {
+	seedShareOpts := options.Apply(&SeedShareOptions{}, opts)
+
+	if len(mnemonics) == 0 {
+		return nil, ierrors.WithMessage(ErrInsufficientSeedShares, "no shares given")
+	}
+	if err := validateSeedSharePassphrase(seedShareOpts.passphrase); err != nil {
+		return nil, err
+	}
+
+	shares := make([]*seedShare, 0, len(mnemonics))
+	for _, mnemonic := range mnemonics {
+		share, err := parseSeedShare(mnemonic)
+		if err != nil {
+			return nil, err
+		}
+
+		shares = append(shares, share)
+	}
+
+	first := shares[0]
+	groups := make(map[uint8][]*seedShare)
+	groupIndexes := make([]uint8, 0)
+	for _, share := range shares {
+		if share.identifier != first.identifier || share.extendable != first.extendable || share.iterationExponent != first.iterationExponent ||
+			share.groupThreshold != first.groupThreshold || share.groupCount != first.groupCount || len(share.value) != len(first.value) {
+			return nil, ierrors.WithMessage(ErrInvalidSeedShare, "the shares don't belong to the same seed")
+		}
+
+		group, exists := groups[share.groupIndex]
+		if !exists {
+			groupIndexes = append(groupIndexes, share.groupIndex)
+		}
+		for _, member := range group {
+			if member.memberThreshold != share.memberThreshold {
+				return nil, ierrors.WithMessagef(ErrInvalidSeedShare, "the shares of group %d have different member thresholds", share.groupIndex)
+			}
+			if member.memberIndex == share.memberIndex {
+				return nil, ierrors.WithMessagef(ErrInvalidSeedShare, "duplicate member index %d in group %d", share.memberIndex, share.groupIndex)
+			}
+		}
+		groups[share.groupIndex] = append(group, share)
+	}
+
+	if len(groups) != int(first.groupThreshold) {
+		return nil, ierrors.WithMessagef(ErrInsufficientSeedShares, "shares of exactly %d groups are needed, got %d", first.groupThreshold, len(groups))
+	}
+
+	groupShares := make([]*shamirShare, 0, len(groups))
+	for _, groupIndex := range groupIndexes {
+		members := groups[groupIndex]
+		if len(members) != int(members[0].memberThreshold) {
+			return nil, ierrors.WithMessagef(ErrInsufficientSeedShares, "exactly %d shares of group %d are needed, got %d", members[0].memberThreshold, groupIndex, len(members))
+		}
+
+		memberShares := make([]*shamirShare, 0, len(members))
+		for _, member := range members {
+			memberShares = append(memberShares, &shamirShare{x: member.memberIndex, value: member.value})
+		}
+
+		groupSecret, err := recoverSecret(members[0].memberThreshold, memberShares)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to recover share of group %d", groupIndex)
+		}
+
+		groupShares = append(groupShares, &shamirShare{x: groupIndex, value: groupSecret})
+	}
+
+	encryptedSeed, err := recoverSecret(first.groupThreshold, groupShares)
+	if err != nil {
+		return nil, err
+	}
+
+	return seedShareFeistel(encryptedSeed, seedShareOpts.passphrase, first.iterationExponent, first.identifier, first.extendable, false), nil
+}
+
+// SeedShares splits the seed of the key manager into SLIP-39 share mnemonics, see GenerateSeedShares.
+func (k *KeyManager) SeedShares(groupThreshold uint8, groups []SeedShareGroup, opts ...options.Option[SeedShareOptions]) ([][]string, error) {
+	k.keyCacheMutex.Lock()
+	defer k.keyCacheMutex.Unlock()
+
+	if k.closed {
+		return nil, ErrKeyManagerClosed
+	}
+
+	return GenerateSeedShares(k.seed, groupThreshold, groups, opts...)
+}
+
+// NewKeyManagerFromSeedShares creates a new key manager from the seed recovered from SLIP-39 share mnemonics, see CombineSeedShares.
+func NewKeyManagerFromSeedShares(mnemonics []string, path string, opts ...options.Option[SeedShareOptions]) (*KeyManager, error) {
+	seed, err := CombineSeedShares(mnemonics, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyManager(seed, path)
+}
+
+// seedShare is a single SLIP-39 share.
+type seedShare struct {
+	identifier        uint16
+	extendable        bool
+	iterationExponent uint8
+	groupIndex        uint8
+	groupThreshold    uint8
+	groupCount        uint8
+	memberIndex       uint8
+	memberThreshold   uint8
+	value             []byte
+}
+
+// parseSeedShare parses a SLIP-39 share mnemonic.
+func parseSeedShare(mnemonic string) (*seedShare, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) < seedShareMinWords {
+		return nil, ierrors.WithMessagef(ErrInvalidSeedShare, "a share mnemonic needs at least %d words, got %d", seedShareMinWords, len(words))
+	}
+
+	indexes := make([]int, len(words))
+	for i, word := range words {
+		index, exists := seedShareWordIndexes[word]
+		if !exists {
+			return nil, ierrors.WithMessagef(ErrInvalidSeedShare, "unknown word %q", word)
+		}
+		indexes[i] = index
+	}
+
+	// the padding of the value needs to fit in a single byte
+	valueWords := len(indexes) - seedShareMetadataWords
+	paddingBits := (seedShareRadixBits * valueWords) % 16
+	if paddingBits > 8 {
+		return nil, ierrors.WithMessagef(ErrInvalidSeedShare, "invalid share mnemonic length of %d words", len(words))
+	}
+
+	identifierAndExponent := indexes[0]<<seedShareRadixBits | indexes[1]
+	share := &seedShare{
+		identifier:        uint16(identifierAndExponent >> (1 + seedShareIterationExponentBits)),
+		extendable:        identifierAndExponent>>seedShareIterationExponentBits&1 == 1,
+		iterationExponent: uint8(identifierAndExponent & seedShareMaxIterationExponent),
+	}
+
+	if seedShareChecksum(share.customization(), indexes) != 1 {
+		return nil, ierrors.WithMessage(ErrInvalidSeedShare, "invalid checksum")
+	}
+
+	parameters := indexes[2]<<seedShareRadixBits | indexes[3]
+	share.groupIndex = uint8(parameters >> 16 & 0xF)
+	share.groupThreshold = uint8(parameters>>12&0xF) + 1
+	share.groupCount = uint8(parameters>>8&0xF) + 1
+	share.memberIndex = uint8(parameters >> 4 & 0xF)
+	share.memberThreshold = uint8(parameters&0xF) + 1
+
+	if share.groupThreshold > share.groupCount {
+		return nil, ierrors.WithMessagef(ErrInvalidSeedShare, "group threshold %d exceeds the group count %d", share.groupThreshold, share.groupCount)
+	}
+
+	value := new(big.Int)
+	for _, index := range indexes[4 : len(indexes)-seedShareChecksumWords] {
+		value.Lsh(value, seedShareRadixBits)
+		value.Or(value, big.NewInt(int64(index)))
+	}
+
+	valueLength := (seedShareRadixBits*valueWords - paddingBits) / 8
+	if value.BitLen() > valueLength*8 {
+		return nil, ierrors.WithMessage(ErrInvalidSeedShare, "invalid padding")
+	}
+	share.value = value.FillBytes(make([]byte, valueLength))
+
+	return share, nil
+}
+
+// mnemonic encodes the share as SLIP-39 share mnemonic.
+func (s *seedShare) mnemonic() string {
+	extendable := 0
+	if s.extendable {
+		extendable = 1
+	}
+
+	identifierAndExponent := int(s.identifier)<<(1+seedShareIterationExponentBits) | extendable<<seedShareIterationExponentBits | int(s.iterationExponent)
+	parameters := int(s.groupIndex)<<16 | int(s.groupThreshold-1)<<12 | int(s.groupCount-1)<<8 | int(s.memberIndex)<<4 | int(s.memberThreshold-1)
+
+	valueWords := (len(s.value)*8 + seedShareRadixBits - 1) / seedShareRadixBits
+	indexes := make([]int, 0, seedShareMetadataWords+valueWords)
+	indexes = append(indexes,
+		identifierAndExponent>>seedShareRadixBits, identifierAndExponent%seedShareRadix,
+		parameters>>seedShareRadixBits, parameters%seedShareRadix,
+	)
+
+	value := new(big.Int).SetBytes(s.value)
+	mask := big.NewInt(seedShareRadix - 1)
+	for i := valueWords - 1; i >= 0; i-- {
+		indexes = append(indexes, int(new(big.Int).And(new(big.Int).Rsh(value, uint(i*seedShareRadixBits)), mask).Int64()))
+	}
+
+	checksum := seedShareChecksum(s.customization(), append(indexes, 0, 0, 0)) ^ 1
+	for i := seedShareChecksumWords - 1; i >= 0; i-- {
+		indexes = append(indexes, checksum>>(i*seedShareRadixBits)%seedShareRadix)
+	}
+
+	words := make([]string, len(indexes))
+	for i, index := range indexes {
+		words[i] = seedShareWordList[index]
+	}
+
+	return strings.Join(words, " ")
+}
+
+// customization returns the customization string of the checksum.
+func (s *seedShare) customization() string {
+	if s.extendable {
+		return seedShareCustomizationExtendable
+	}
+
+	return seedShareCustomization
+}
+
+// seedShareChecksum computes the RS1024 checksum polynomial of the customization string and the given word indexes.
+func seedShareChecksum(customization string, indexes []int) int {
+	generator := [10]int{0xE0E040, 0x1C1C080, 0x3838100, 0x7070200, 0xE0E0009, 0x1C0C2412, 0x38086C24, 0x3090FC48, 0x21B1F890, 0x3F3F120}
+
+	checksum := 1
+	update := func(value int) {
+		b := checksum >> 20
+		checksum = (checksum&0xFFFFF)<<seedShareRadixBits ^ value
+		for i := range generator {
+			if (b>>i)&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+
+	for _, c := range []byte(customization) {
+		update(int(c))
+	}
+	for _, index := range indexes {
+		update(index)
+	}
+
+	return checksum
+}
+
+// seedShareFeistel encrypts or decrypts the secret with a four round Feistel network using PBKDF2 as round function.
+func seedShareFeistel(secret []byte, passphrase string, iterationExponent uint8, identifier uint16, extendable bool, encrypt bool) []byte {
+	half := len(secret) / 2
+	left := append([]byte(nil), secret[:half]...)
+	right := append([]byte(nil), secret[half:]...)
+
+	var salt []byte
+	if !extendable {
+		salt = binary.BigEndian.AppendUint16([]byte(seedShareCustomization), identifier)
+	}
+	iterations := (seedShareBaseIterations << iterationExponent) / seedShareRoundCount
+
+	for round := 0; round < seedShareRoundCount; round++ {
+		i := round
+		if !encrypt {
+			i = seedShareRoundCount - 1 - round
+		}
+
+		roundKey := pbkdf2.Key(append([]byte{byte(i)}, passphrase...), append(append([]byte(nil), salt...), right...), iterations, half, sha256.New)
+		for j := range left {
+			left[j] ^= roundKey[j]
+		}
+		left, right = right, left
+	}
+
+	return append(right, left...)
+}
+
+// validateSeedSharePassphrase checks that the passphrase only consists of printable ASCII characters.
+func validateSeedSharePassphrase(passphrase string) error {
+	for _, c := range []byte(passphrase) {
+		if c < 32 || c > 126 {
+			return ierrors.WithMessage(ErrInvalidSeedShareParameters, "the passphrase must only contain printable ASCII characters")
+		}
+	}
+
+	return nil
+}
+
+// shamirShare is a share of Shamir's secret sharing over GF(256).
+type shamirShare struct {
+	x     uint8
+	value []byte
+}
+
+// gf256Exp and gf256Log are the exponent and logarithm tables of GF(256) with the Rijndael polynomial and generator 3.
+var gf256Exp, gf256Log = func() (exp [255]byte, log [256]byte) {
+	poly := 1
+	for i := range exp {
+		exp[i] = byte(poly)
+		log[poly] = byte(i)
+
+		poly = poly<<1 ^ poly
+		if poly&0x100 != 0 {
+			poly ^= 0x11B
+		}
+	}
+
+	return exp, log
+}()
+
+// splitSecret splits the secret into count shares of which threshold are needed to recover it.
+// A digest of the secret is embedded into the shares, so that recovering with invalid shares is detected.
+func splitSecret(threshold uint8, count uint8, secret []byte) ([]*shamirShare, error) {
+	if threshold == 1 {
+		shares := make([]*shamirShare, count)
+		for i := range shares {
+			shares[i] = &shamirShare{x: uint8(i), value: append([]byte(nil), secret...)}
+		}
+
+		return shares, nil
+	}
+
+	shares := make([]*shamirShare, 0, count)
+	for i := uint8(0); i < threshold-2; i++ {
+		value := make([]byte, len(secret))
+		if _, err := rand.Read(value); err != nil {
+			return nil, ierrors.Wrap(err, "failed to generate random share")
+		}
+
+		shares = append(shares, &shamirShare{x: i, value: value})
+	}
+
+	digestShare := make([]byte, len(secret))
+	if _, err := rand.Read(digestShare[seedShareDigestLength:]); err != nil {
+		return nil, ierrors.Wrap(err, "failed to generate random share")
+	}
+	copy(digestShare, secretDigest(digestShare[seedShareDigestLength:], secret))
+
+	baseShares := append(append([]*shamirShare(nil), shares...),
+		&shamirShare{x: seedShareDigestIndex, value: digestShare},
+		&shamirShare{x: seedShareSecretIndex, value: secret},
+	)
+
+	for i := threshold - 2; i < count; i++ {
+		shares = append(shares, &shamirShare{x: i, value: interpolate(baseShares, i)})
+	}
+
+	return shares, nil
+}
+
+// recoverSecret recovers the secret from threshold shares and verifies its digest.
+func recoverSecret(threshold uint8, shares []*shamirShare) ([]byte, error) {
+	if threshold == 1 {
+		return shares[0].value, nil
+	}
+
+	secret := interpolate(shares, seedShareSecretIndex)
+	digestShare := interpolate(shares, seedShareDigestIndex)
+	if !hmac.Equal(digestShare[:seedShareDigestLength], secretDigest(digestShare[seedShareDigestLength:], secret)) {
+		return nil, ierrors.WithMessage(ErrInvalidSeedShare, "invalid digest of the recovered secret")
+	}
+
+	return secret, nil
+}
+
+// secretDigest returns the digest of the secret embedded into the shares.
+func secretDigest(randomPart []byte, secret []byte) []byte {
+	mac := hmac.New(sha256.New, randomPart)
+	mac.Write(secret)
+
+	return mac.Sum(nil)[:seedShareDigestLength]
+}
+
+// interpolate evaluates the polynomial defined by the shares at x using Lagrange interpolation in GF(256).
+// All shares need to have distinct x coordinates and values of the same length.
+func interpolate(shares []*shamirShare, x uint8) []byte {
+	for _, share := range shares {
+		if share.x == x {
+			return append([]byte(nil), share.value...)
+		}
+	}
+
+	logProduct := 0
+	for _, share := range shares {
+		logProduct += int(gf256Log[share.x^x])
+	}
+
+	result := make([]byte, len(shares[0].value))
+	for i, share := range shares {
+		logBasis := logProduct - int(gf256Log[share.x^x])
+		for j, other := range shares {
+			if i != j {
+				logBasis -= int(gf256Log[share.x^other.x])
+			}
+		}
+		logBasis = (logBasis%255 + 255) % 255
+
+		for k, b := range share.value {
+			if b != 0 {
+				result[k] ^= gf256Exp[(int(gf256Log[b])+logBasis)%255]
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,222 @@
+package wallet_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/hexutil"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestCombineSeedShares(t *testing.T) {
+	// test vectors of the SLIP-39 specification, all of them use the passphrase "TREZOR"
+	tests := []struct {
+		name      string
+		mnemonics []string
+		seed      string
+		err       error
+	}{
+		{
+			name: "ok - 1-of-1",
+			mnemonics: []string{
+				"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard",
+			},
+			seed: "0xbb54aac4b89dc868ba37d9cc21b2cece",
+		},
+		{
+			name: "ok - 2-of-3",
+			mnemonics: []string{
+				"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+				"shadow pistol academic acid actress prayer class unknown daughter sweater depict flip twice unkind craft early superior advocate guest smoking",
+			},
+			seed: "0xb43ceb7e57a0ea8766221624d01b0864",
+		},
+		{
+			name: "err - invalid checksum",
+			mnemonics: []string{
+				"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision kidney",
+			},
+			err: wallet.ErrInvalidSeedShare,
+		},
+		{
+			name: "err - unknown word",
+			mnemonics: []string{
+				"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision iota",
+			},
+			err: wallet.ErrInvalidSeedShare,
+		},
+		{
+			name: "err - too short",
+			mnemonics: []string{
+				"duckling enlarge academic academic agency result length solution fridge kidney",
+			},
+			err: wallet.ErrInvalidSeedShare,
+		},
+		{
+			name: "err - not enough shares",
+			mnemonics: []string{
+				"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+			},
+			err: wallet.ErrInsufficientSeedShares,
+		},
+		{
+			name: "err - duplicate share",
+			mnemonics: []string{
+				"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+				"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+			},
+			err: wallet.ErrInvalidSeedShare,
+		},
+		{
+			name: "err - shares of different seeds",
+			mnemonics: []string{
+				"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+				"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard",
+			},
+			err: wallet.ErrInvalidSeedShare,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seed, err := wallet.CombineSeedShares(test.mnemonics, wallet.WithSeedSharePassphrase("TREZOR"))
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.seed, hexutil.EncodeHex(seed))
+		})
+	}
+}
+
+func TestGenerateSeedShares(t *testing.T) {
+	seed := tpkg.RandBytes(32)
+
+	tests := []struct {
+		name           string
+		groupThreshold uint8
+		groups         []wallet.SeedShareGroup
+		// the indexes of the shares to combine per group
+		combine map[int][]int
+		err     error
+	}{
+		{
+			name:           "ok - single share",
+			groupThreshold: 1,
+			groups:         []wallet.SeedShareGroup{{MemberThreshold: 1, MemberCount: 1}},
+			combine:        map[int][]int{0: {0}},
+		},
+		{
+			name:           "ok - 3-of-5",
+			groupThreshold: 1,
+			groups:         []wallet.SeedShareGroup{{MemberThreshold: 3, MemberCount: 5}},
+			combine:        map[int][]int{0: {4, 1, 2}},
+		},
+		{
+			name:           "ok - 2-of-3 groups",
+			groupThreshold: 2,
+			groups: []wallet.SeedShareGroup{
+				{MemberThreshold: 1, MemberCount: 1},
+				{MemberThreshold: 2, MemberCount: 3},
+				{MemberThreshold: 3, MemberCount: 5},
+			},
+			combine: map[int][]int{0: {0}, 2: {0, 3, 4}},
+		},
+		{
+			name:           "err - group threshold exceeds group count",
+			groupThreshold: 2,
+			groups:         []wallet.SeedShareGroup{{MemberThreshold: 2, MemberCount: 3}},
+			err:            wallet.ErrInvalidSeedShareParameters,
+		},
+		{
+			name:           "err - member threshold exceeds member count",
+			groupThreshold: 1,
+			groups:         []wallet.SeedShareGroup{{MemberThreshold: 4, MemberCount: 3}},
+			err:            wallet.ErrInvalidSeedShareParameters,
+		},
+		{
+			name:           "err - member threshold 1 with multiple members",
+			groupThreshold: 1,
+			groups:         []wallet.SeedShareGroup{{MemberThreshold: 1, MemberCount: 2}},
+			err:            wallet.ErrInvalidSeedShareParameters,
+		},
+		{
+			name:           "err - too many members",
+			groupThreshold: 1,
+			groups:         []wallet.SeedShareGroup{{MemberThreshold: 2, MemberCount: 17}},
+			err:            wallet.ErrInvalidSeedShareParameters,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mnemonics, err := wallet.GenerateSeedShares(seed, test.groupThreshold, test.groups, wallet.WithSeedSharePassphrase("passphrase"))
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, mnemonics, len(test.groups))
+
+			var combine []string
+			for groupIndex, group := range test.groups {
+				require.Len(t, mnemonics[groupIndex], int(group.MemberCount))
+				for _, memberIndex := range test.combine[groupIndex] {
+					combine = append(combine, mnemonics[groupIndex][memberIndex])
+				}
+			}
+
+			recoveredSeed, err := wallet.CombineSeedShares(combine, wallet.WithSeedSharePassphrase("passphrase"))
+			require.NoError(t, err)
+			require.Equal(t, seed, recoveredSeed)
+
+			// a different passphrase results in a different seed
+			otherSeed, err := wallet.CombineSeedShares(combine)
+			require.NoError(t, err)
+			require.NotEqual(t, seed, otherSeed)
+
+			if len(combine) > 1 {
+				_, err = wallet.CombineSeedShares(combine[1:], wallet.WithSeedSharePassphrase("passphrase"))
+				require.ErrorIs(t, err, wallet.ErrInsufficientSeedShares)
+			}
+		})
+	}
+
+	t.Run("err - invalid seed length", func(t *testing.T) {
+		_, err := wallet.GenerateSeedShares(tpkg.RandBytes(15), 1, []wallet.SeedShareGroup{{MemberThreshold: 1, MemberCount: 1}})
+		require.ErrorIs(t, err, wallet.ErrInvalidSeedShareParameters)
+	})
+
+	t.Run("err - invalid passphrase", func(t *testing.T) {
+		_, err := wallet.GenerateSeedShares(seed, 1, []wallet.SeedShareGroup{{MemberThreshold: 1, MemberCount: 1}}, wallet.WithSeedSharePassphrase("pässphrase"))
+		require.ErrorIs(t, err, wallet.ErrInvalidSeedShareParameters)
+	})
+}
+
+func TestKeyManagerSeedShares(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	mnemonics, err := keyManager.SeedShares(1, []wallet.SeedShareGroup{{MemberThreshold: 2, MemberCount: 3}})
+	require.NoError(t, err)
+
+	for _, mnemonic := range mnemonics[0] {
+		require.Len(t, strings.Fields(mnemonic), 33)
+	}
+
+	recoveredKeyManager, err := wallet.NewKeyManagerFromSeedShares([]string{mnemonics[0][2], mnemonics[0][0]}, wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	for index := uint32(0); index < 3; index++ {
+		require.Equal(t, keyManager.Address(iotago.AddressEd25519, index), recoveredKeyManager.Address(iotago.AddressEd25519, index))
+	}
+
+	keyManager.Close()
+	_, err = keyManager.SeedShares(1, []wallet.SeedShareGroup{{MemberThreshold: 2, MemberCount: 3}})
+	require.ErrorIs(t, err, wallet.ErrKeyManagerClosed)
+}
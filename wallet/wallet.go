@@ -0,0 +1,643 @@
+package wallet
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/iotaledger/hive.go/core/safemath"
+	"github.com/iotaledger/hive.go/ierrors"
+	"github.com/iotaledger/hive.go/runtime/options"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/nodeclient"
+)
+
+// Balance is the balance of the outputs of a wallet at a given slot.
+type Balance struct {
+	// The base tokens of the outputs the wallet can unlock, without storage deposits that need to be returned.
+	BaseTokens iotago.BaseToken
+	// The base tokens of the outputs the wallet can only unlock once their timelocks or expirations are reached.
+	LockedBaseTokens iotago.BaseToken
+	// The decayed stored mana of the outputs the wallet can unlock.
+	StoredMana iotago.Mana
+	// The potential mana generated by the outputs the wallet can unlock.
+	PotentialMana iotago.Mana
+	// The decayed stored and potential mana of the locked outputs.
+	LockedMana iotago.Mana
+	// The native tokens of the outputs the wallet can unlock.
+	NativeTokens iotago.NativeTokenSum
+	// The native tokens of the locked outputs.
+	LockedNativeTokens iotago.NativeTokenSum
+}
+
+// Mana returns the sum of the stored and potential mana of the outputs the wallet can unlock.
+func (b *Balance) Mana() (iotago.Mana, error) {
+	return safemath.SafeAdd(b.StoredMana, b.PotentialMana)
+}
+
+// Wallet keeps track of the outputs owned by a set of addresses and keeps them in sync with the ledger of a node.
+// Outputs owned by the chain addresses of owned account, anchor and NFT outputs are tracked as well.
+type Wallet struct {
+	client     *nodeclient.Client
+	keyManager *KeyManager
+
+	// addresses are the explicitly tracked addresses by their key.
+	addresses map[string]iotago.Address
+	// chainAddresses are the addresses of the owned chain outputs by their key.
+	chainAddresses map[string]iotago.OutputID
+	outputs        iotago.OutputSet
+	syncedSlot     iotago.SlotIndex
+	mutex          sync.RWMutex
+}
+
+// NewWallet creates a new wallet that tracks the outputs of the addresses of the given key manager.
+// Addresses need to be added via Discover or TrackAddresses.
+func NewWallet(client *nodeclient.Client, keyManager *KeyManager) *Wallet {
+	return &Wallet{
+		client:         client,
+		keyManager:     keyManager,
+		addresses:      make(map[string]iotago.Address),
+		chainAddresses: make(map[string]iotago.OutputID),
+		outputs:        make(iotago.OutputSet),
+	}
+}
+
+// KeyManager returns the key manager of the wallet.
+func (w *Wallet) KeyManager() *KeyManager {
+	return w.keyManager
+}
+
+// TrackAddresses adds the given addresses to the set of addresses whose outputs are tracked.
+// Outputs that were created before the synced slot of the wallet are not added, use Discover to fetch them.
+func (w *Wallet) TrackAddresses(addresses ...iotago.Address) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, address := range addresses {
+		w.addresses[address.Key()] = address
+	}
+}
+
+// Addresses returns the explicitly tracked addresses and the addresses of the owned chain outputs.
+func (w *Wallet) Addresses() []iotago.Address {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	addresses := make([]iotago.Address, 0, len(w.addresses)+len(w.chainAddresses))
+	for _, address := range w.addresses {
+		addresses = append(addresses, address)
+	}
+	for _, outputID := range w.chainAddresses {
+		addresses = append(addresses, chainAddress(outputID, w.outputs[outputID]))
+	}
+
+	return addresses
+}
+
+// Outputs returns a copy of the outputs related to the tracked addresses and to the addresses of the owned chain outputs.
+func (w *Wallet) Outputs() iotago.OutputSet {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.outputs.Filter(func(_ iotago.OutputID, output iotago.Output) bool {
+		return w.isRelated(output)
+	})
+}
+
+// SyncedSlot returns the slot up to which the wallet applied the UTXO changes of the ledger.
+func (w *Wallet) SyncedSlot() iotago.SlotIndex {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.syncedSlot
+}
+
+// Discover discovers the used addresses of the key manager and fetches the outputs of the discovered addresses
+// and of the owned chain outputs from the indexer. The wallet is synced to the latest commitment of the node afterwards.
+func (w *Wallet) Discover(ctx context.Context, opts ...options.Option[DiscoveryOptions]) error {
+	// the indexer might already contain changes of later slots, those are applied again while syncing
+	info, err := w.client.Info(ctx)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to get node info")
+	}
+
+	indexer, err := w.client.Indexer(ctx)
+	if err != nil {
+		return err
+	}
+
+	hrp := w.client.CommittedAPI().ProtocolParameters().Bech32HRP()
+
+	discoveredAddresses, err := DiscoverAddresses(ctx, indexer, w.keyManager, hrp, opts...)
+	if err != nil {
+		return err
+	}
+
+	pendingOutputs := make(iotago.OutputSet)
+	for _, discoveredAddress := range discoveredAddresses {
+		w.TrackAddresses(discoveredAddress.Address)
+
+		for outputID, output := range discoveredAddress.Outputs {
+			pendingOutputs[outputID] = output
+		}
+	}
+
+	if err := w.loadOutputs(ctx, indexer, pendingOutputs, info.Status.LatestCommitmentID.Slot()); err != nil {
+		return err
+	}
+
+	return w.Sync(ctx)
+}
+
+// Sync applies the UTXO changes of all slots between the synced slot of the wallet and the latest commitment of the node.
+// If the wallet wasn't synced before, the outputs of the tracked addresses are fetched from the indexer instead
+// of applying the UTXO changes of all slots since genesis.
+func (w *Wallet) Sync(ctx context.Context) error {
+	info, err := w.client.Info(ctx)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to get node info")
+	}
+
+	latestCommittedSlot := info.Status.LatestCommitmentID.Slot()
+	if w.SyncedSlot() == 0 {
+		if err := w.loadAddressOutputs(ctx, latestCommittedSlot); err != nil {
+			return err
+		}
+	}
+
+	for slot := w.SyncedSlot() + 1; slot <= latestCommittedSlot; slot++ {
+		utxoChanges, err := w.client.CommitmentUTXOChangesFullBySlot(ctx, slot)
+		if err != nil {
+			return ierrors.Wrapf(err, "failed to get UTXO changes of slot %d", slot)
+		}
+
+		w.ApplyUTXOChanges(utxoChanges)
+	}
+
+	return nil
+}
+
+// loadAddressOutputs fetches the outputs of the tracked addresses from the indexer, see loadOutputs.
+func (w *Wallet) loadAddressOutputs(ctx context.Context, syncedSlot iotago.SlotIndex) error {
+	indexer, err := w.client.Indexer(ctx)
+	if err != nil {
+		return err
+	}
+
+	hrp := w.client.CommittedAPI().ProtocolParameters().Bech32HRP()
+
+	pendingOutputs := make(iotago.OutputSet)
+	for _, address := range w.Addresses() {
+		outputs, err := addressOutputs(ctx, indexer, address.Bech32(hrp))
+		if err != nil {
+			return ierrors.Wrapf(err, "failed to query outputs of address %s", address.Bech32(hrp))
+		}
+
+		for outputID, output := range outputs {
+			pendingOutputs[outputID] = output
+		}
+	}
+
+	return w.loadOutputs(ctx, indexer, pendingOutputs, syncedSlot)
+}
+
+// loadOutputs adds the given outputs and fetches the outputs of the chain outputs found along the way from the indexer.
+// The wallet is marked as synced up to the given slot afterwards, the indexer might already contain changes of later slots,
+// those are applied again while syncing.
+func (w *Wallet) loadOutputs(ctx context.Context, indexer nodeclient.IndexerClient, pendingOutputs iotago.OutputSet, syncedSlot iotago.SlotIndex) error {
+	hrp := w.client.CommittedAPI().ProtocolParameters().Bech32HRP()
+
+	// chain outputs found along the way might own further outputs
+	for len(pendingOutputs) > 0 {
+		w.mutex.Lock()
+		newChainAddresses := w.addOutputs(pendingOutputs)
+		w.mutex.Unlock()
+
+		pendingOutputs = make(iotago.OutputSet)
+		for _, address := range newChainAddresses {
+			outputs, err := addressOutputs(ctx, indexer, address.Bech32(hrp))
+			if err != nil {
+				return ierrors.Wrapf(err, "failed to query outputs of chain address %s", address.Bech32(hrp))
+			}
+
+			for outputID, output := range outputs {
+				pendingOutputs[outputID] = output
+			}
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if syncedSlot > w.syncedSlot {
+		w.syncedSlot = syncedSlot
+	}
+
+	return nil
+}
+
+// ApplyUTXOChanges adds the created outputs owned by the wallet and removes the consumed outputs.
+func (w *Wallet) ApplyUTXOChanges(utxoChanges *api.UTXOChangesFullResponse) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// the created outputs are added first, so that chains transitioned within the wallet keep their address
+	createdOutputs := make(iotago.OutputSet, len(utxoChanges.CreatedOutputs))
+	for _, createdOutput := range utxoChanges.CreatedOutputs {
+		createdOutputs[createdOutput.OutputID] = createdOutput.Output
+	}
+	w.addOutputs(createdOutputs)
+
+	for _, consumedOutput := range utxoChanges.ConsumedOutputs {
+		w.removeOutput(consumedOutput.OutputID)
+	}
+
+	if slot := utxoChanges.CommitmentID.Slot(); slot > w.syncedSlot {
+		w.syncedSlot = slot
+	}
+}
+
+// ApplyOutputWithMetadata adds the given output if it is owned by the wallet or removes it if it was spent.
+// It returns whether the output was added to the wallet.
+func (w *Wallet) ApplyOutputWithMetadata(outputWithMetadata *api.OutputWithMetadataResponse) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	outputID := outputWithMetadata.Metadata.OutputID
+	if outputWithMetadata.Metadata.Spent != nil {
+		w.removeOutput(outputID)
+
+		return false
+	}
+
+	if _, exists := w.outputs[outputID]; exists {
+		return false
+	}
+
+	w.addOutputs(iotago.OutputSet{outputID: outputWithMetadata.Output})
+	_, added := w.outputs[outputID]
+
+	return added
+}
+
+// Listen subscribes to the outputs of the tracked addresses and to the spending of the owned outputs
+// and applies the received events until the context is canceled.
+// The subscription to an owned output is closed once the output is spent.
+// Events are sent for accepted blocks, so Sync should be called periodically to apply the committed state.
+func (w *Wallet) Listen(ctx context.Context, eventAPI *nodeclient.EventAPIClient) error {
+	// all subscriptions are closed once the wallet stops listening
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan *api.OutputWithMetadataResponse)
+	subscribe := func(subscriptionCtx context.Context, eventChan <-chan *api.OutputWithMetadataResponse, subscription *nodeclient.EventAPIClientSubscription) error {
+		if err := subscription.Error(); err != nil {
+			return err
+		}
+
+		go func() {
+			//nolint:errcheck // we are not interested in the error of closing the subscription
+			defer subscription.Close()
+
+			for {
+				select {
+				case <-subscriptionCtx.Done():
+					return
+				case event := <-eventChan:
+					select {
+					case events <- event:
+					case <-subscriptionCtx.Done():
+						return
+					}
+				}
+			}
+		}()
+
+		return nil
+	}
+
+	subscribedAddresses := make(map[string]struct{})
+	subscribeAddress := func(address iotago.Address) error {
+		if _, exists := subscribedAddresses[address.Key()]; exists {
+			return nil
+		}
+		subscribedAddresses[address.Key()] = struct{}{}
+
+		eventChan, subscription := eventAPI.OutputsWithMetadataByUnlockConditionAndAddress(api.EventAPIUnlockConditionAny, address)
+
+		return subscribe(ctx, eventChan, subscription)
+	}
+
+	// the subscriptions to the owned outputs by output ID, they are canceled once the output is spent
+	outputSubscriptions := make(map[iotago.OutputID]context.CancelFunc)
+	subscribeOutput := func(outputID iotago.OutputID) error {
+		if _, exists := outputSubscriptions[outputID]; exists {
+			return nil
+		}
+
+		outputCtx, outputCancel := context.WithCancel(ctx)
+		eventChan, subscription := eventAPI.OutputWithMetadataByOutputID(outputID)
+		if err := subscribe(outputCtx, eventChan, subscription); err != nil {
+			outputCancel()
+
+			return err
+		}
+		outputSubscriptions[outputID] = outputCancel
+
+		return nil
+	}
+
+	for _, address := range w.Addresses() {
+		if err := subscribeAddress(address); err != nil {
+			return ierrors.Wrapf(err, "failed to subscribe to outputs of address %s", address)
+		}
+	}
+
+	for outputID := range w.Outputs() {
+		if err := subscribeOutput(outputID); err != nil {
+			return ierrors.Wrapf(err, "failed to subscribe to output %s", outputID)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-events:
+			outputID := event.Metadata.OutputID
+			if !w.ApplyOutputWithMetadata(event) {
+				if outputCancel, exists := outputSubscriptions[outputID]; exists && event.Metadata.Spent != nil {
+					outputCancel()
+					delete(outputSubscriptions, outputID)
+				}
+
+				continue
+			}
+
+			if err := subscribeOutput(outputID); err != nil {
+				return ierrors.Wrapf(err, "failed to subscribe to output %s", outputID)
+			}
+
+			// the added output might be a chain output whose address owns outputs
+			for _, address := range w.Addresses() {
+				if err := subscribeAddress(address); err != nil {
+					return ierrors.Wrapf(err, "failed to subscribe to outputs of address %s", address)
+				}
+			}
+		}
+	}
+}
+
+// Balance calculates the balance of the owned outputs at the given slot.
+func (w *Wallet) Balance(slot iotago.SlotIndex) (*Balance, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	apiForSlot := w.client.APIForSlot(slot)
+	manaDecayProvider := apiForSlot.ManaDecayProvider()
+	storageScoreStructure := apiForSlot.StorageScoreStructure()
+
+	balance := &Balance{
+		NativeTokens:       make(iotago.NativeTokenSum),
+		LockedNativeTokens: make(iotago.NativeTokenSum),
+	}
+
+	for outputID, output := range w.outputs {
+		unlockable, locked := w.outputUnlockable(output, slot)
+		if !unlockable && !locked {
+			continue
+		}
+
+		baseTokens := output.BaseTokenAmount()
+		if storageDepositReturn := output.UnlockConditionSet().StorageDepositReturn(); storageDepositReturn != nil && !w.isTracked(storageDepositReturn.ReturnAddress) {
+			baseTokens -= storageDepositReturn.Amount
+		}
+
+		// outputs created after the given slot don't hold any mana yet
+		var storedMana, potentialMana iotago.Mana
+		if outputID.Slot() <= slot {
+			var err error
+			storedMana, err = manaDecayProvider.DecayManaBySlots(output.StoredMana(), outputID.Slot(), slot)
+			if err != nil {
+				return nil, ierrors.Wrapf(err, "failed to calculate stored mana of output %s", outputID)
+			}
+
+			potentialMana, err = iotago.PotentialMana(manaDecayProvider, storageScoreStructure, output, outputID.Slot(), slot)
+			if err != nil {
+				return nil, ierrors.Wrapf(err, "failed to calculate potential mana of output %s", outputID)
+			}
+		}
+
+		var err error
+		if unlockable {
+			if balance.BaseTokens, err = safemath.SafeAdd(balance.BaseTokens, baseTokens); err != nil {
+				return nil, ierrors.Wrap(err, "base token overflow")
+			}
+			if balance.StoredMana, err = safemath.SafeAdd(balance.StoredMana, storedMana); err != nil {
+				return nil, ierrors.Wrap(err, "stored mana overflow")
+			}
+			if balance.PotentialMana, err = safemath.SafeAdd(balance.PotentialMana, potentialMana); err != nil {
+				return nil, ierrors.Wrap(err, "potential mana overflow")
+			}
+			addNativeToken(balance.NativeTokens, output)
+
+			continue
+		}
+
+		if balance.LockedBaseTokens, err = safemath.SafeAdd(balance.LockedBaseTokens, baseTokens); err != nil {
+			return nil, ierrors.Wrap(err, "locked base token overflow")
+		}
+		lockedMana, err := safemath.SafeAdd(storedMana, potentialMana)
+		if err != nil {
+			return nil, ierrors.Wrap(err, "locked mana overflow")
+		}
+		if balance.LockedMana, err = safemath.SafeAdd(balance.LockedMana, lockedMana); err != nil {
+			return nil, ierrors.Wrap(err, "locked mana overflow")
+		}
+		addNativeToken(balance.LockedNativeTokens, output)
+	}
+
+	return balance, nil
+}
+
+// outputUnlockable returns whether the wallet can unlock the output at the given slot,
+// or whether the output is locked but the wallet will be able to unlock it at a later slot.
+func (w *Wallet) outputUnlockable(output iotago.Output, slot iotago.SlotIndex) (unlockable bool, locked bool) {
+	unlockConditions := output.UnlockConditionSet()
+
+	owner := ownerAddress(output)
+	if expiration := unlockConditions.Expiration(); expiration != nil {
+		if slot >= expiration.Slot {
+			owner = expiration.ReturnAddress
+		} else if !w.isTracked(owner) && w.isTracked(expiration.ReturnAddress) {
+			// the output can be claimed by the wallet once it expires
+			return false, true
+		}
+	}
+
+	if owner == nil || !w.isTracked(owner) {
+		return false, false
+	}
+
+	if unlockConditions.HasTimelockUntil(slot) {
+		return false, true
+	}
+
+	return true, false
+}
+
+// addOutputs adds the outputs related to the tracked addresses and returns the addresses of newly owned chain outputs.
+// Outputs owned by the chain outputs within the given set are added as well.
+func (w *Wallet) addOutputs(outputs iotago.OutputSet) []iotago.Address {
+	var newChainAddresses []iotago.Address
+
+	pendingOutputs := outputs.Clone()
+	for added := true; added; {
+		added = false
+
+		for outputID, output := range pendingOutputs {
+			if _, exists := w.outputs[outputID]; exists {
+				delete(pendingOutputs, outputID)
+				continue
+			}
+
+			if !w.isRelated(output) {
+				continue
+			}
+
+			w.outputs[outputID] = output
+			delete(pendingOutputs, outputID)
+			added = true
+
+			if address := chainAddress(outputID, output); address != nil {
+				w.chainAddresses[address.Key()] = outputID
+				newChainAddresses = append(newChainAddresses, address)
+			}
+		}
+	}
+
+	return newChainAddresses
+}
+
+// removeOutput removes the output and the chain address of the output, if the chain is not owned by another output.
+// Outputs owned by the chain address are kept, since the chain might be transitioned back into the wallet,
+// but they are not considered to be related to the wallet anymore.
+func (w *Wallet) removeOutput(outputID iotago.OutputID) {
+	output, exists := w.outputs[outputID]
+	if !exists {
+		return
+	}
+	delete(w.outputs, outputID)
+
+	address := chainAddress(outputID, output)
+	if address == nil || w.chainAddresses[address.Key()] != outputID {
+		return
+	}
+
+	delete(w.chainAddresses, address.Key())
+	for otherOutputID, otherOutput := range w.outputs {
+		if otherAddress := chainAddress(otherOutputID, otherOutput); otherAddress != nil && otherAddress.Equal(address) {
+			w.chainAddresses[address.Key()] = otherOutputID
+
+			return
+		}
+	}
+}
+
+// isTracked returns whether the given address is tracked or belongs to an owned chain output.
+func (w *Wallet) isTracked(address iotago.Address) bool {
+	if _, exists := w.addresses[address.Key()]; exists {
+		return true
+	}
+
+	_, exists := w.chainAddresses[address.Key()]
+
+	return exists
+}
+
+// isRelated returns whether any of the addresses in the unlock conditions of the output is tracked.
+func (w *Wallet) isRelated(output iotago.Output) bool {
+	unlockConditions := output.UnlockConditionSet()
+
+	var addresses []iotago.Address
+	if addressUnlockCondition := unlockConditions.Address(); addressUnlockCondition != nil {
+		addresses = append(addresses, addressUnlockCondition.Address)
+	}
+	if stateController := unlockConditions.StateControllerAddress(); stateController != nil {
+		addresses = append(addresses, stateController.Address)
+	}
+	if governor := unlockConditions.GovernorAddress(); governor != nil {
+		addresses = append(addresses, governor.Address)
+	}
+	if immutableAccount := unlockConditions.ImmutableAccount(); immutableAccount != nil {
+		addresses = append(addresses, immutableAccount.Address)
+	}
+	if expiration := unlockConditions.Expiration(); expiration != nil {
+		addresses = append(addresses, expiration.ReturnAddress)
+	}
+
+	for _, address := range addresses {
+		if w.isTracked(address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownerAddress returns the address that can unlock the output, ignoring expirations.
+// For anchor outputs, the state controller is returned.
+func ownerAddress(output iotago.Output) iotago.Address {
+	switch output := output.(type) {
+	case iotago.OwnerTransitionIndependentOutput:
+		return output.Owner()
+	case *iotago.AnchorOutput:
+		return output.StateController()
+	default:
+		return nil
+	}
+}
+
+// chainAddress returns the address of the given chain output or nil if it doesn't have an address.
+// Implicit accounts are basic outputs, their account address is derived from the output ID.
+func chainAddress(outputID iotago.OutputID, output iotago.Output) iotago.Address {
+	switch output := output.(type) {
+	case *iotago.BasicOutput:
+		if output.UnlockConditionSet().Address().Address.Type() == iotago.AddressImplicitAccountCreation {
+			return iotago.AccountAddressFromOutputID(outputID)
+		}
+
+		return nil
+	case iotago.ChainOutput:
+		chainID := output.ChainID()
+		if chainID.Empty() {
+			utxoIDChainID, ok := chainID.(iotago.UTXOIDChainID)
+			if !ok {
+				return nil
+			}
+			chainID = utxoIDChainID.FromOutputID(outputID)
+		}
+
+		if !chainID.Addressable() {
+			return nil
+		}
+
+		return chainID.ToAddress()
+	default:
+		return nil
+	}
+}
+
+// addNativeToken adds the native token of the output to the given sum.
+func addNativeToken(nativeTokenSum iotago.NativeTokenSum, output iotago.Output) {
+	nativeToken := output.FeatureSet().NativeToken()
+	if nativeToken == nil {
+		return
+	}
+
+	if _, exists := nativeTokenSum[nativeToken.ID]; !exists {
+		nativeTokenSum[nativeToken.ID] = new(big.Int)
+	}
+	nativeTokenSum[nativeToken.ID].Add(nativeTokenSum[nativeToken.ID], nativeToken.Amount)
+}
@@ -0,0 +1,264 @@
+package wallet_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/iotaledger/hive.go/lo"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/api"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func basicOutput(address iotago.Address, amount iotago.BaseToken, unlockConditions ...iotago.BasicOutputUnlockCondition) *iotago.BasicOutput {
+	return &iotago.BasicOutput{
+		Amount:           amount,
+		UnlockConditions: append(iotago.BasicOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: address}}, unlockConditions...),
+	}
+}
+
+func accountOutput(accountID iotago.AccountID, address iotago.Address) *iotago.AccountOutput {
+	return &iotago.AccountOutput{
+		Amount:           1_000_000,
+		AccountID:        accountID,
+		UnlockConditions: iotago.AccountOutputUnlockConditions{&iotago.AddressUnlockCondition{Address: address}},
+	}
+}
+
+func utxoChanges(slot iotago.SlotIndex, createdOutputs iotago.OutputSet, consumedOutputs iotago.OutputSet) *api.UTXOChangesFullResponse {
+	utxoChanges := &api.UTXOChangesFullResponse{
+		CommitmentID:    iotago.NewCommitmentID(slot, tpkg.Rand32ByteArray()),
+		CreatedOutputs:  make([]*api.OutputWithID, 0, len(createdOutputs)),
+		ConsumedOutputs: make([]*api.OutputWithID, 0, len(consumedOutputs)),
+	}
+
+	for outputID, output := range createdOutputs {
+		utxoChanges.CreatedOutputs = append(utxoChanges.CreatedOutputs, &api.OutputWithID{OutputID: outputID, Output: output})
+	}
+	for outputID, output := range consumedOutputs {
+		utxoChanges.ConsumedOutputs = append(utxoChanges.ConsumedOutputs, &api.OutputWithID{OutputID: outputID, Output: output})
+	}
+
+	return utxoChanges
+}
+
+func TestWalletApplyUTXOChanges(t *testing.T) {
+	defer gock.Off()
+
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	w := wallet.NewWallet(nodeClient(t), keyManager)
+
+	address := keyManager.Address(iotago.AddressEd25519, 0)
+	otherAddress := tpkg.RandEd25519Address()
+	w.TrackAddresses(address)
+
+	var (
+		accountOutputID = tpkg.RandOutputIDWithCreationSlot(1, 0)
+		account         = accountOutput(iotago.EmptyAccountID, address)
+		accountAddress  = iotago.AccountAddressFromOutputID(accountOutputID)
+
+		ownedByAccountOutputID = tpkg.RandOutputIDWithCreationSlot(1, 1)
+		ownedByAccountOutput   = basicOutput(accountAddress, 2_000_000)
+		plainOutputID          = tpkg.RandOutputIDWithCreationSlot(1, 2)
+		plainOutput            = basicOutput(address, 3_000_000)
+	)
+
+	// the output owned by the account is added, even though the account is created in the same slot
+	w.ApplyUTXOChanges(utxoChanges(1, iotago.OutputSet{
+		accountOutputID:                         account,
+		ownedByAccountOutputID:                  ownedByAccountOutput,
+		plainOutputID:                           plainOutput,
+		tpkg.RandOutputIDWithCreationSlot(1, 3): basicOutput(otherAddress, 4_000_000),
+	}, nil))
+	require.ElementsMatch(t, []iotago.OutputID{accountOutputID, ownedByAccountOutputID, plainOutputID}, lo.Keys(w.Outputs()))
+	require.ElementsMatch(t, []iotago.Address{address, accountAddress}, w.Addresses())
+	require.EqualValues(t, 1, w.SyncedSlot())
+
+	// transitioning the account within the wallet keeps the outputs owned by the account
+	transitionedAccountOutputID := tpkg.RandOutputIDWithCreationSlot(2, 0)
+	transitionedAccount := accountOutput(accountAddress.AccountID(), address)
+	w.ApplyUTXOChanges(utxoChanges(2, iotago.OutputSet{
+		transitionedAccountOutputID: transitionedAccount,
+	}, iotago.OutputSet{
+		accountOutputID: account,
+		plainOutputID:   plainOutput,
+	}))
+	require.ElementsMatch(t, []iotago.OutputID{transitionedAccountOutputID, ownedByAccountOutputID}, lo.Keys(w.Outputs()))
+
+	balance, err := w.Balance(2)
+	require.NoError(t, err)
+	require.EqualValues(t, 3_000_000, balance.BaseTokens)
+
+	// transferring the account to another address drops the outputs owned by the account
+	w.ApplyUTXOChanges(utxoChanges(3, iotago.OutputSet{
+		tpkg.RandOutputIDWithCreationSlot(3, 0): accountOutput(accountAddress.AccountID(), otherAddress),
+	}, iotago.OutputSet{transitionedAccountOutputID: transitionedAccount}))
+	require.Empty(t, w.Outputs())
+	require.Equal(t, []iotago.Address{address}, w.Addresses())
+	require.EqualValues(t, 3, w.SyncedSlot())
+
+	balance, err = w.Balance(3)
+	require.NoError(t, err)
+	require.Zero(t, balance.BaseTokens)
+
+	// outputs received via events are added and removed again once they are spent
+	eventOutputID := tpkg.RandOutputIDWithCreationSlot(4, 0)
+	eventOutput := &api.OutputWithMetadataResponse{
+		Output:   basicOutput(address, 5_000_000),
+		Metadata: &api.OutputMetadata{OutputID: eventOutputID},
+	}
+	require.True(t, w.ApplyOutputWithMetadata(eventOutput))
+	require.False(t, w.ApplyOutputWithMetadata(eventOutput))
+	require.Contains(t, w.Outputs(), eventOutputID)
+
+	eventOutput.Metadata.Spent = &api.OutputConsumptionMetadata{Slot: 5}
+	require.False(t, w.ApplyOutputWithMetadata(eventOutput))
+	require.Empty(t, w.Outputs())
+}
+
+func TestWalletBalance(t *testing.T) {
+	defer gock.Off()
+
+	const (
+		creationSlot iotago.SlotIndex = 10
+		balanceSlot  iotago.SlotIndex = 100
+		futureSlot   iotago.SlotIndex = 200
+	)
+
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	w := wallet.NewWallet(nodeClient(t), keyManager)
+
+	address := keyManager.Address(iotago.AddressEd25519, 0)
+	otherAddress := tpkg.RandEd25519Address()
+	w.TrackAddresses(address)
+
+	nativeTokenFeature := tpkg.RandNativeTokenFeature()
+	plainOutput := basicOutput(address, 1_000_000)
+	plainOutput.Mana = 1_000
+	plainOutput.Features = iotago.BasicOutputFeatures{nativeTokenFeature}
+
+	lockedNativeTokenFeature := tpkg.RandNativeTokenFeature()
+	timelockedOutput := basicOutput(address, 2_000_000, &iotago.TimelockUnlockCondition{Slot: futureSlot})
+	timelockedOutput.Mana = 2_000
+	timelockedOutput.Features = iotago.BasicOutputFeatures{lockedNativeTokenFeature}
+
+	unlockableOutputs := iotago.OutputSet{
+		tpkg.RandOutputIDWithCreationSlot(creationSlot, 0): plainOutput,
+		// the storage deposit needs to be returned to the other address
+		tpkg.RandOutputIDWithCreationSlot(creationSlot, 1): basicOutput(address, 3_000_000, &iotago.StorageDepositReturnUnlockCondition{ReturnAddress: otherAddress, Amount: 500_000}),
+		// the output expired and returned to the wallet
+		tpkg.RandOutputIDWithCreationSlot(creationSlot, 2): basicOutput(otherAddress, 4_000_000, &iotago.ExpirationUnlockCondition{ReturnAddress: address, Slot: 50}),
+		// the output can be claimed until it expires
+		tpkg.RandOutputIDWithCreationSlot(creationSlot, 3): basicOutput(address, 5_000_000, &iotago.ExpirationUnlockCondition{ReturnAddress: otherAddress, Slot: futureSlot}),
+	}
+	lockedOutputs := iotago.OutputSet{
+		tpkg.RandOutputIDWithCreationSlot(creationSlot, 4): timelockedOutput,
+		// the output returns to the wallet if it is not claimed until it expires
+		tpkg.RandOutputIDWithCreationSlot(creationSlot, 5): basicOutput(otherAddress, 6_000_000, &iotago.ExpirationUnlockCondition{ReturnAddress: address, Slot: futureSlot}),
+	}
+
+	createdOutputs := iotago.OutputSet{
+		// the output expired and returned to the other address
+		tpkg.RandOutputIDWithCreationSlot(creationSlot, 6): basicOutput(address, 7_000_000, &iotago.ExpirationUnlockCondition{ReturnAddress: otherAddress, Slot: 50}),
+	}
+	for outputID, output := range unlockableOutputs {
+		createdOutputs[outputID] = output
+	}
+	for outputID, output := range lockedOutputs {
+		createdOutputs[outputID] = output
+	}
+	w.ApplyUTXOChanges(utxoChanges(creationSlot, createdOutputs, nil))
+
+	balance, err := w.Balance(balanceSlot)
+	require.NoError(t, err)
+	require.EqualValues(t, 12_500_000, balance.BaseTokens)
+	require.EqualValues(t, 8_000_000, balance.LockedBaseTokens)
+	require.Equal(t, iotago.NativeTokenSum{nativeTokenFeature.ID: nativeTokenFeature.Amount}, balance.NativeTokens)
+	require.Equal(t, iotago.NativeTokenSum{lockedNativeTokenFeature.ID: lockedNativeTokenFeature.Amount}, balance.LockedNativeTokens)
+
+	manaDecayProvider := mockAPI.ManaDecayProvider()
+	expectedStoredMana, err := manaDecayProvider.DecayManaBySlots(plainOutput.Mana, creationSlot, balanceSlot)
+	require.NoError(t, err)
+	require.Equal(t, expectedStoredMana, balance.StoredMana)
+
+	potentialMana := func(outputs iotago.OutputSet) iotago.Mana {
+		var sum iotago.Mana
+		for _, output := range outputs {
+			mana, err := iotago.PotentialMana(manaDecayProvider, mockAPI.StorageScoreStructure(), output, creationSlot, balanceSlot)
+			require.NoError(t, err)
+			sum += mana
+		}
+
+		return sum
+	}
+	require.Equal(t, potentialMana(unlockableOutputs), balance.PotentialMana)
+
+	expectedLockedStoredMana, err := manaDecayProvider.DecayManaBySlots(timelockedOutput.Mana, creationSlot, balanceSlot)
+	require.NoError(t, err)
+	require.Equal(t, expectedLockedStoredMana+potentialMana(lockedOutputs), balance.LockedMana)
+
+	// outputs created after the given slot don't hold any mana yet
+	balance, err = w.Balance(creationSlot - 1)
+	require.NoError(t, err)
+	require.Zero(t, balance.StoredMana)
+	require.Zero(t, balance.PotentialMana)
+
+	// all outputs are unlockable once the timelocks and expirations are reached
+	balance, err = w.Balance(futureSlot)
+	require.NoError(t, err)
+	require.EqualValues(t, 1_000_000+2_500_000+4_000_000+2_000_000+6_000_000, balance.BaseTokens)
+	require.Zero(t, balance.LockedBaseTokens)
+	require.Equal(t, iotago.NativeTokenSum{
+		nativeTokenFeature.ID:       nativeTokenFeature.Amount,
+		lockedNativeTokenFeature.ID: lockedNativeTokenFeature.Amount,
+	}, balance.NativeTokens)
+}
+
+func TestWalletSync(t *testing.T) {
+	defer gock.Off()
+
+	hrp := mockAPI.ProtocolParameters().Bech32HRP()
+
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	w := wallet.NewWallet(nodeClient(t, api.IndexerPluginName), keyManager)
+
+	address := keyManager.Address(iotago.AddressEd25519, 0)
+	w.TrackAddresses(address)
+
+	var (
+		firstOutput    = basicOutput(address, 1_000_000)
+		firstOutputID  = mockOutput(firstOutput)
+		secondOutputID = tpkg.RandOutputIDWithCreationSlot(2, 0)
+	)
+
+	// the first sync fetches the outputs from the indexer instead of applying the UTXO changes since genesis
+	mockInfo(iotago.NewCommitmentID(1, tpkg.Rand32ByteArray()))
+	mockIndexerOutputs(api.IndexerRouteOutputs, "unlockableByAddress", address.Bech32(hrp), firstOutputID)
+	mockIndexerOutputs(api.IndexerRouteOutputsBasic, "address", address.Bech32(hrp), firstOutputID)
+
+	require.NoError(t, w.Sync(context.Background()))
+	require.EqualValues(t, 1, w.SyncedSlot())
+	require.Equal(t, []iotago.OutputID{firstOutputID}, lo.Keys(w.Outputs()))
+
+	mockInfo(iotago.NewCommitmentID(2, tpkg.Rand32ByteArray()))
+	mockGetJSON(api.EndpointWithNamedParameterValue(api.CoreRouteCommitmentBySlotUTXOChangesFull, api.ParameterSlot, "2"),
+		utxoChanges(2, iotago.OutputSet{secondOutputID: basicOutput(address, 2_000_000)}, iotago.OutputSet{firstOutputID: firstOutput}))
+
+	require.NoError(t, w.Sync(context.Background()))
+	require.EqualValues(t, 2, w.SyncedSlot())
+	require.Equal(t, []iotago.OutputID{secondOutputID}, lo.Keys(w.Outputs()))
+
+	balance, err := w.Balance(2)
+	require.NoError(t, err)
+	require.EqualValues(t, 2_000_000, balance.BaseTokens)
+}
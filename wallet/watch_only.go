@@ -0,0 +1,229 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"sync"
+
+	"github.com/iotaledger/iota-crypto-demo/pkg/slip10"
+
+	"github.com/iotaledger/hive.go/ierrors"
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+)
+
+// ErrWatchOnly gets returned when a watch-only key source is asked to produce a signature.
+var ErrWatchOnly = ierrors.New("watch-only key source can't sign")
+
+// DerivedPublicKey is a public key together with the BIP44 path it was derived from.
+type DerivedPublicKey struct {
+	Path      BIP44Path
+	PublicKey ed25519.PublicKey
+}
+
+// PublicKeyRange calculates count public keys, starting at the given address index,
+// for the given account and change of the coin type of the key manager's path.
+// SLIP-10 only supports hardened derivation for ed25519, so there is no extended public key
+// watch-only parties could derive further keys from, the public keys need to be exported instead.
+func (k *KeyManager) PublicKeyRange(account uint32, change uint32, startIndex uint32, count uint32) ([]*DerivedPublicKey, error) {
+	basePath, err := k.BIP44Path()
+	if err != nil {
+		return nil, ierrors.Wrap(err, "the coin type can't be determined from the key manager's path")
+	}
+
+	if uint64(startIndex)+uint64(count) > uint64(slip10.Hardened) {
+		return nil, ierrors.WithMessagef(ErrInvalidBIP44Path, "public key range %d+%d exceeds the maximum address index", startIndex, count)
+	}
+
+	publicKeys := make([]*DerivedPublicKey, 0, count)
+	for i := uint32(0); i < count; i++ {
+		path := BIP44Path{
+			CoinType:     basePath.CoinType,
+			Account:      account,
+			Change:       change,
+			AddressIndex: startIndex + i,
+		}
+
+		_, pubKey, err := k.KeyPairForBIP44Path(path)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKeys = append(publicKeys, &DerivedPublicKey{Path: path, PublicKey: pubKey})
+	}
+
+	return publicKeys, nil
+}
+
+// WatchOnlyKeySource holds public keys whose private keys live elsewhere, e.g. on a hardware wallet or a remote signer.
+// It derives the addresses of the public keys, which can be tracked with Wallet.TrackAddresses, and implements
+// iotago.AddressSigner, so that a TransactionBuilder can compute the work score and the unlock layout of a transaction.
+// Signing is refused with ErrWatchOnly.
+type WatchOnlyKeySource struct {
+	// publicKeys are the public keys by the key of their Ed25519Address.
+	publicKeys map[string]ed25519.PublicKey
+	// paths are the BIP44 paths of the public keys by the key of their Ed25519Address, if known.
+	paths map[string]BIP44Path
+	// order is the order in which the public keys were added.
+	order []string
+	mutex sync.RWMutex
+}
+
+var _ iotago.AddressSigner = &WatchOnlyKeySource{}
+
+// NewWatchOnlyKeySource creates a new watch-only key source for the given public keys.
+func NewWatchOnlyKeySource(publicKeys ...ed25519.PublicKey) (*WatchOnlyKeySource, error) {
+	source := &WatchOnlyKeySource{
+		publicKeys: make(map[string]ed25519.PublicKey),
+		paths:      make(map[string]BIP44Path),
+	}
+
+	if err := source.AddPublicKeys(publicKeys...); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// NewWatchOnlyKeySourceFromDerivedPublicKeys creates a new watch-only key source for the public keys exported via KeyManager.PublicKeyRange.
+func NewWatchOnlyKeySourceFromDerivedPublicKeys(publicKeys ...*DerivedPublicKey) (*WatchOnlyKeySource, error) {
+	source, err := NewWatchOnlyKeySource()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := source.AddDerivedPublicKeys(publicKeys...); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// AddPublicKeys adds the given public keys to the key source.
+func (s *WatchOnlyKeySource) AddPublicKeys(publicKeys ...ed25519.PublicKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, publicKey := range publicKeys {
+		if _, err := s.addPublicKey(publicKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddDerivedPublicKeys adds the given public keys together with their BIP44 paths to the key source.
+func (s *WatchOnlyKeySource) AddDerivedPublicKeys(publicKeys ...*DerivedPublicKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, derivedPublicKey := range publicKeys {
+		key, err := s.addPublicKey(derivedPublicKey.PublicKey)
+		if err != nil {
+			return ierrors.Wrapf(err, "invalid public key at path %s", derivedPublicKey.Path)
+		}
+
+		s.paths[key] = derivedPublicKey.Path
+	}
+
+	return nil
+}
+
+// Addresses returns the addresses of the specified type of all public keys, in the order they were added.
+func (s *WatchOnlyKeySource) Addresses(addressType iotago.AddressType) []iotago.DirectUnlockableAddress {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	addresses := make([]iotago.DirectUnlockableAddress, 0, len(s.order))
+	for _, key := range s.order {
+		addresses = append(addresses, addressFromPublicKey(addressType, s.publicKeys[key]))
+	}
+
+	return addresses
+}
+
+// DerivedAddresses returns the addresses of the specified type of all public keys whose BIP44 path is known,
+// in the order they were added.
+func (s *WatchOnlyKeySource) DerivedAddresses(addressType iotago.AddressType) []*DerivedAddress {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	addresses := make([]*DerivedAddress, 0, len(s.paths))
+	for _, key := range s.order {
+		if path, exists := s.paths[key]; exists {
+			addresses = append(addresses, &DerivedAddress{Path: path, Address: addressFromPublicKey(addressType, s.publicKeys[key])})
+		}
+	}
+
+	return addresses
+}
+
+// PublicKey returns the public key backing the given address.
+func (s *WatchOnlyKeySource) PublicKey(addr iotago.Address) (ed25519.PublicKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	addr = builder.ResolveUnderlyingAddress(addr)
+
+	var ed25519Address *iotago.Ed25519Address
+	switch address := addr.(type) {
+	case *iotago.Ed25519Address:
+		ed25519Address = address
+	case *iotago.ImplicitAccountCreationAddress:
+		ed25519Address = (*iotago.Ed25519Address)(address)
+	default:
+		return nil, ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "address type %T is not backed by a public key", addr)
+	}
+
+	publicKey, exists := s.publicKeys[ed25519Address.Key()]
+	if !exists {
+		return nil, ierrors.WithMessagef(iotago.ErrAddressKeysNotMapped, "address %s", addr)
+	}
+
+	return publicKey, nil
+}
+
+// SignerUIDForAddress returns the signer unique identifier for a given address.
+func (s *WatchOnlyKeySource) SignerUIDForAddress(addr iotago.Address) (iotago.Identifier, error) {
+	publicKey, err := s.PublicKey(addr)
+	if err != nil {
+		return iotago.EmptyIdentifier, err
+	}
+
+	// the UID is the blake2b 256 hash of the public key
+	return iotago.IdentifierFromData(publicKey), nil
+}
+
+// Sign always fails with ErrWatchOnly, since the key source doesn't hold any private keys.
+func (s *WatchOnlyKeySource) Sign(addr iotago.Address, _ []byte) (iotago.Signature, error) {
+	return nil, ierrors.WithMessagef(ErrWatchOnly, "address %s", addr)
+}
+
+// EmptySignatureForAddress returns an empty signature for the given address.
+// The public key is already set, so that the signature only needs to be filled in by the holder of the private key.
+func (s *WatchOnlyKeySource) EmptySignatureForAddress(addr iotago.Address) (iotago.Signature, error) {
+	publicKey, err := s.PublicKey(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := &iotago.Ed25519Signature{}
+	copy(signature.PublicKey[:], publicKey)
+
+	return signature, nil
+}
+
+// addPublicKey adds the given public key and returns the key of its Ed25519Address.
+func (s *WatchOnlyKeySource) addPublicKey(publicKey ed25519.PublicKey) (string, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return "", ierrors.Errorf("invalid ed25519 public key length %d", len(publicKey))
+	}
+
+	key := iotago.Ed25519AddressFromPubKey(publicKey).Key()
+	if _, exists := s.publicKeys[key]; !exists {
+		s.order = append(s.order, key)
+	}
+	s.publicKeys[key] = append(ed25519.PublicKey(nil), publicKey...)
+
+	return key, nil
+}
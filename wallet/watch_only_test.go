@@ -0,0 +1,149 @@
+package wallet_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iotago "github.com/iotaledger/iota.go/v4"
+	"github.com/iotaledger/iota.go/v4/builder"
+	"github.com/iotaledger/iota.go/v4/tpkg"
+	"github.com/iotaledger/iota.go/v4/wallet"
+)
+
+func TestWatchOnlyKeySource(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	publicKeys, err := keyManager.PublicKeyRange(0, wallet.BIP44ChangeExternal, 0, 3)
+	require.NoError(t, err)
+
+	watchOnly, err := wallet.NewWatchOnlyKeySourceFromDerivedPublicKeys(publicKeys...)
+	require.NoError(t, err)
+
+	// the watch-only key source derives the same addresses as the key manager
+	addresses, err := keyManager.AddressRange(iotago.AddressEd25519, 0, wallet.BIP44ChangeExternal, 0, 3)
+	require.NoError(t, err)
+	require.Equal(t, addresses, watchOnly.DerivedAddresses(iotago.AddressEd25519))
+
+	implicitAddresses, err := keyManager.AddressRange(iotago.AddressImplicitAccountCreation, 0, wallet.BIP44ChangeExternal, 0, 3)
+	require.NoError(t, err)
+	require.Equal(t, implicitAddresses, watchOnly.DerivedAddresses(iotago.AddressImplicitAccountCreation))
+
+	// public keys without a path are only part of the plain addresses
+	otherPublicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	require.NoError(t, watchOnly.AddPublicKeys(otherPublicKey))
+	require.Len(t, watchOnly.DerivedAddresses(iotago.AddressEd25519), 3)
+	require.Len(t, watchOnly.Addresses(iotago.AddressEd25519), 4)
+	require.Equal(t, iotago.Ed25519AddressFromPubKey(otherPublicKey), watchOnly.Addresses(iotago.AddressEd25519)[3])
+
+	_, err = wallet.NewWatchOnlyKeySource(ed25519.PublicKey(tpkg.RandBytes(31)))
+	require.Error(t, err)
+
+	//nolint:forcetypeassert // we know that the address is an Ed25519Address
+	ed25519Address := addresses[1].Address.(*iotago.Ed25519Address)
+	signer := keyManager.AddressSigner()
+
+	tests := []struct {
+		name    string
+		address iotago.Address
+		err     error
+	}{
+		{
+			name:    "ok - ed25519",
+			address: ed25519Address,
+		},
+		{
+			name:    "ok - implicit account creation",
+			address: implicitAddresses[1].Address,
+		},
+		{
+			name:    "ok - restricted",
+			address: &iotago.RestrictedAddress{Address: ed25519Address},
+		},
+		{
+			name:    "err - not mapped",
+			address: tpkg.RandEd25519Address(),
+			err:     iotago.ErrAddressKeysNotMapped,
+		},
+		{
+			name:    "err - account",
+			address: tpkg.RandAccountAddress(),
+			err:     iotago.ErrAddressKeysNotMapped,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signerUID, err := watchOnly.SignerUIDForAddress(test.address)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+
+				_, err = watchOnly.EmptySignatureForAddress(test.address)
+				require.ErrorIs(t, err, test.err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, iotago.IdentifierFromData(publicKeys[1].PublicKey), signerUID)
+
+			signature, err := watchOnly.EmptySignatureForAddress(test.address)
+			require.NoError(t, err)
+
+			// the empty signature has the same size as the one of the key holder
+			expectedSignature, err := signer.EmptySignatureForAddress(test.address)
+			require.NoError(t, err)
+			require.Equal(t, expectedSignature.Size(), signature.Size())
+
+			_, err = watchOnly.Sign(test.address, tpkg.RandBytes(32))
+			require.ErrorIs(t, err, wallet.ErrWatchOnly)
+		})
+	}
+}
+
+func TestWatchOnlyKeySourceTransactionBuilder(t *testing.T) {
+	keyManager, err := wallet.NewKeyManagerFromRandom(wallet.DefaultIOTAPath)
+	require.NoError(t, err)
+
+	publicKeys, err := keyManager.PublicKeyRange(0, wallet.BIP44ChangeExternal, 0, 2)
+	require.NoError(t, err)
+
+	watchOnly, err := wallet.NewWatchOnlyKeySourceFromDerivedPublicKeys(publicKeys...)
+	require.NoError(t, err)
+
+	addresses := watchOnly.Addresses(iotago.AddressEd25519)
+	newTransactionBuilder := func(signer iotago.AddressSigner) *builder.TransactionBuilder {
+		return builder.NewTransactionBuilder(tpkg.ZeroCostTestAPI, signer).
+			AddInput(&builder.TxInput{UnlockTarget: addresses[0], InputID: tpkg.RandOutputID(0), Input: tpkg.RandOutputOnAddressWithAmount(iotago.OutputBasic, addresses[0], 1_000_000)}).
+			AddInput(&builder.TxInput{UnlockTarget: addresses[1], InputID: tpkg.RandOutputID(1), Input: tpkg.RandOutputOnAddressWithAmount(iotago.OutputBasic, addresses[1], 1_000_000)}).
+			AddInput(&builder.TxInput{UnlockTarget: addresses[0], InputID: tpkg.RandOutputID(2), Input: tpkg.RandOutputOnAddressWithAmount(iotago.OutputBasic, addresses[0], 1_000_000)}).
+			AddOutput(tpkg.RandOutputOnAddressWithAmount(iotago.OutputBasic, tpkg.RandEd25519Address(), 3_000_000))
+	}
+
+	_, err = newTransactionBuilder(watchOnly).Build()
+	require.ErrorIs(t, err, wallet.ErrWatchOnly)
+
+	unsignedTransaction, err := newTransactionBuilder(watchOnly).BuildUnsigned()
+	require.NoError(t, err)
+
+	// the unlock layout matches the one of the signed transaction
+	signer, err := keyManager.AddressSignerForBIP44Paths(publicKeys[0].Path, publicKeys[1].Path)
+	require.NoError(t, err)
+
+	signedTransaction, err := newTransactionBuilder(signer).Build()
+	require.NoError(t, err)
+	require.Len(t, unsignedTransaction.Unlocks, len(signedTransaction.Unlocks))
+
+	for i, unlock := range unsignedTransaction.Unlocks {
+		require.Equal(t, signedTransaction.Unlocks[i].Type(), unlock.Type())
+	}
+
+	unsignedWorkScore, err := unsignedTransaction.WorkScore(tpkg.ZeroCostTestAPI.ProtocolParameters().WorkScoreParameters())
+	require.NoError(t, err)
+
+	signedWorkScore, err := signedTransaction.WorkScore(tpkg.ZeroCostTestAPI.ProtocolParameters().WorkScoreParameters())
+	require.NoError(t, err)
+	require.Equal(t, signedWorkScore, unsignedWorkScore)
+}